@@ -0,0 +1,199 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+)
+
+// devicesBucket is the store bucket of the access rules.
+const devicesBucket = "device_access"
+
+// The DBus names of the trusted and blocked properties of the devices of the Bluetooth service.
+const (
+	trustedProperty = "org.bluez.Device1.Trusted"
+	blockedProperty = "org.bluez.Device1.Blocked"
+)
+
+// ErrRuleNotFound is returned when a device is neither trusted nor blocked.
+var ErrRuleNotFound = errors.New("device is neither trusted nor blocked")
+
+// Rule describes whether a device is trusted or blocked.
+type Rule struct {
+	UpdatedAt time.Time            `doc:"The time at which the rule was last updated." json:"updated_at"`
+	Address   bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Trusted   bool                 `doc:"Whether the service authorization requests of the device are accepted automatically." json:"trusted"`
+	Blocked   bool                 `doc:"Whether all authorization requests of the device are rejected automatically." json:"blocked"`
+}
+
+// Policy holds the trusted and blocked devices.
+type Policy struct {
+	store       *store.Store
+	session     bluetooth.Session
+	setProperty func(adapter bluetooth.AdapterData, address bluetooth.MacAddress, property string, value bool) error
+
+	rules map[bluetooth.MacAddress]Rule
+	mu    sync.RWMutex
+}
+
+// New loads and returns the access policy from the store.
+func New(st *store.Store) (*Policy, error) {
+	rules, err := store.List[Rule](st, devicesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load access rules: %w", err)
+	}
+
+	p := &Policy{store: st, setProperty: setDeviceProperty, rules: make(map[bluetooth.MacAddress]Rule, len(rules))}
+	for _, rule := range rules {
+		p.rules[rule.Address] = rule
+	}
+
+	return p, nil
+}
+
+// Start sets the session, whose devices are trusted and blocked in the Bluetooth service along with their rules.
+func (p *Policy) Start(session bluetooth.Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.session = session
+}
+
+// Rules returns the rules of all trusted and blocked devices, sorted by address.
+func (p *Policy) Rules() []Rule {
+	rules := []Rule{}
+	if p == nil {
+		return rules
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, rule := range p.rules {
+		rules = append(rules, rule)
+	}
+
+	slices.SortFunc(rules, func(a, b Rule) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return rules
+}
+
+// Rule returns the rule of a device. If the device is neither trusted nor blocked, ErrRuleNotFound is returned.
+func (p *Policy) Rule(address bluetooth.MacAddress) (Rule, error) {
+	if p == nil {
+		return Rule{}, ErrRuleNotFound
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rule, ok := p.rules[address]
+	if !ok {
+		return Rule{}, ErrRuleNotFound
+	}
+
+	return rule, nil
+}
+
+// Trusted returns whether the device is trusted.
+func (p *Policy) Trusted(address bluetooth.MacAddress) bool {
+	rule, _ := p.Rule(address)
+
+	return rule.Trusted
+}
+
+// Blocked returns whether the device is blocked.
+func (p *Policy) Blocked(address bluetooth.MacAddress) bool {
+	rule, _ := p.Rule(address)
+
+	return rule.Blocked
+}
+
+// SetTrusted sets whether the device is trusted, in the Bluetooth service and in its rule, and returns its updated rule.
+func (p *Policy) SetTrusted(address bluetooth.MacAddress, trusted bool) (Rule, error) {
+	if err := p.setDevice(address, trustedProperty, trusted); err != nil {
+		return Rule{}, err
+	}
+
+	return p.update(address, func(rule *Rule) {
+		rule.Trusted = trusted
+	})
+}
+
+// SetBlocked sets whether the device is blocked, in the Bluetooth service and in its rule, and returns its updated rule.
+func (p *Policy) SetBlocked(address bluetooth.MacAddress, blocked bool) (Rule, error) {
+	if err := p.setDevice(address, blockedProperty, blocked); err != nil {
+		return Rule{}, err
+	}
+
+	return p.update(address, func(rule *Rule) {
+		rule.Blocked = blocked
+	})
+}
+
+// setDevice sets a property of a device in the Bluetooth service. Devices which are not known to the
+// Bluetooth service are skipped, so that they can be trusted or blocked by the daemon before they are discovered.
+func (p *Policy) setDevice(address bluetooth.MacAddress, property string, value bool) error {
+	p.mu.RLock()
+	session := p.session
+	p.mu.RUnlock()
+
+	if session == nil {
+		return nil
+	}
+
+	device, err := session.Device(address).Properties()
+	if err != nil {
+		if errors.Is(err, errorkinds.ErrDeviceNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, adapter := range session.Adapters() {
+		if adapter.Address == device.AssociatedAdapter {
+			return p.setProperty(adapter, address, property, value)
+		}
+	}
+
+	return nil
+}
+
+// update updates the rule of a device, and stores it. If the device is neither
+// trusted nor blocked after the update, its rule is removed.
+func (p *Policy) update(address bluetooth.MacAddress, set func(rule *Rule)) (Rule, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rule := p.rules[address]
+	rule.Address = address
+	rule.UpdatedAt = time.Now()
+	set(&rule)
+
+	if !rule.Trusted && !rule.Blocked {
+		if err := p.store.Delete(devicesBucket, address.String()); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return Rule{}, err
+		}
+
+		delete(p.rules, address)
+
+		return rule, nil
+	}
+
+	if err := p.store.Put(devicesBucket, address.String(), rule); err != nil {
+		return Rule{}, err
+	}
+
+	p.rules[address] = rule
+
+	return rule, nil
+}
@@ -0,0 +1,173 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+)
+
+// fakeSession is a session with a single adapter and the devices known to it.
+// Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	adapter bluetooth.AdapterData
+	known   []bluetooth.MacAddress
+}
+
+// fakeDevice is a device of a fakeSession.
+type fakeDevice struct {
+	bluetooth.Device
+
+	session *fakeSession
+	address bluetooth.MacAddress
+}
+
+func (s *fakeSession) Adapters() []bluetooth.AdapterData {
+	return []bluetooth.AdapterData{s.adapter}
+}
+
+func (s *fakeSession) Device(address bluetooth.MacAddress) bluetooth.Device {
+	return fakeDevice{session: s, address: address}
+}
+
+func (d fakeDevice) Properties() (bluetooth.DeviceData, error) {
+	if !slices.Contains(d.session.known, d.address) {
+		return bluetooth.DeviceData{}, fmt.Errorf("get %q: %w", d.address.String(), errorkinds.ErrDeviceNotFound)
+	}
+
+	return bluetooth.DeviceData{
+		DeviceEventData: bluetooth.DeviceEventData{Address: d.address, AssociatedAdapter: d.session.adapter.Address},
+	}, nil
+}
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// newTestPolicy returns a policy with a temporary store, whose device properties are recorded instead of being set.
+func newTestPolicy(t *testing.T, path string) (*Policy, *[]string) {
+	t.Helper()
+
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { st.Close() })
+
+	p, err := New(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var set []string
+
+	p.setProperty = func(adapter bluetooth.AdapterData, address bluetooth.MacAddress, property string, value bool) error {
+		if property == blockedProperty && value && address == mac("00:1B:66:0F:0F:0F") {
+			return errors.New("blocking failed")
+		}
+
+		set = append(set, fmt.Sprintf("%s %s %s=%v", adapter.UniqueName, address.String(), property, value))
+
+		return nil
+	}
+
+	return p, &set
+}
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	known, unknown, failing := mac("00:1B:66:01:02:03"), mac("00:1B:66:04:05:06"), mac("00:1B:66:0F:0F:0F")
+
+	p, set := newTestPolicy(t, path)
+	p.Start(&fakeSession{
+		adapter: bluetooth.AdapterData{UniqueName: "hci0", AdapterEventData: bluetooth.AdapterEventData{Address: mac("00:1A:7D:DA:71:13")}},
+		known:   []bluetooth.MacAddress{known, failing},
+	})
+
+	if _, err := p.SetTrusted(known, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.SetBlocked(known, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Devices which are not known to the Bluetooth service are only blocked by the daemon.
+	if _, err := p.SetBlocked(unknown, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rules are not changed if the device cannot be blocked in the Bluetooth service.
+	if _, err := p.SetBlocked(failing, true); err == nil {
+		t.Fatal("SetBlocked() succeeded, want an error")
+	}
+
+	want := []string{
+		"hci0 00:1B:66:01:02:03 org.bluez.Device1.Trusted=true",
+		"hci0 00:1B:66:01:02:03 org.bluez.Device1.Blocked=true",
+	}
+	if !slices.Equal(*set, want) {
+		t.Fatalf("set properties = %v, want %v", *set, want)
+	}
+
+	if !p.Trusted(known) || !p.Blocked(known) || p.Trusted(unknown) || !p.Blocked(unknown) || p.Blocked(failing) {
+		t.Fatalf("rules = %+v", p.Rules())
+	}
+
+	if rules := p.Rules(); len(rules) != 2 || rules[0].Address != known || rules[1].Address != unknown {
+		t.Fatalf("Rules() = %+v, want the rules sorted by address", rules)
+	}
+
+	// The rule is removed once the device is neither trusted nor blocked.
+	if _, err := p.SetBlocked(unknown, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Rule(unknown); !errors.Is(err, ErrRuleNotFound) {
+		t.Fatalf("Rule() = %v, want ErrRuleNotFound", err)
+	}
+
+	if _, err := p.SetTrusted(known, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rules are loaded from the store again.
+	p.store.Close()
+
+	reloaded, _ := newTestPolicy(t, path)
+	if rule, err := reloaded.Rule(known); err != nil || rule.Trusted || !rule.Blocked {
+		t.Fatalf("Rule() after reloading = %+v, %v", rule, err)
+	}
+}
+
+func TestPolicyWithoutSession(t *testing.T) {
+	p, set := newTestPolicy(t, filepath.Join(t.TempDir(), "store.db"))
+	address := mac("00:1B:66:01:02:03")
+
+	if _, err := p.SetTrusted(address, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Trusted(address) || len(*set) != 0 {
+		t.Fatalf("trusted = %v, set properties = %v", p.Trusted(address), *set)
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+
+	if p.Trusted(mac("00:1B:66:01:02:03")) || p.Blocked(mac("00:1B:66:01:02:03")) || len(p.Rules()) != 0 {
+		t.Fatal("a nil policy has rules")
+	}
+}
@@ -0,0 +1,23 @@
+package access
+
+import (
+	"strings"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/godbus/dbus/v5"
+)
+
+// bluezService is the DBus name of the Bluetooth service.
+const bluezService = "org.bluez"
+
+// setDeviceProperty sets a property of a device of an adapter through its object on the system bus.
+func setDeviceProperty(adapter bluetooth.AdapterData, address bluetooth.MacAddress, property string, value bool) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+
+	path := dbus.ObjectPath("/org/bluez/" + adapter.UniqueName + "/dev_" + strings.ReplaceAll(address.String(), ":", "_"))
+
+	return conn.Object(bluezService, path).SetProperty(property, dbus.MakeVariant(value))
+}
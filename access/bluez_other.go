@@ -0,0 +1,11 @@
+//go:build !linux
+
+package access
+
+import "github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+
+// setDeviceProperty does nothing, since the properties of a device can only be set on Linux.
+// On other systems, the trusted and blocked devices are only enforced by the daemon.
+func setDeviceProperty(bluetooth.AdapterData, bluetooth.MacAddress, string, bool) error {
+	return nil
+}
@@ -0,0 +1,7 @@
+/*
+Package access provides the daemon's persistent lists of trusted and blocked devices, which are enforced
+by the authorization agent: the service authorization requests of trusted devices are accepted automatically,
+and all authorization requests of blocked devices are rejected automatically.
+On Linux, the devices which are known to BlueZ are also trusted and blocked in BlueZ.
+*/
+package access
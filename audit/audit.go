@@ -0,0 +1,289 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The default rotation settings.
+const (
+	DefaultMaxSize    = 10 << 20
+	DefaultMaxBackups = 5
+)
+
+// The default and maximum number of entries returned by a query.
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// The outcomes of an operation.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Config describes the audit log's configuration.
+type Config struct {
+	// Path holds the path to the audit log file.
+	Path string
+
+	// MaxSize holds the size in bytes, after which the audit log file is rotated.
+	MaxSize int64
+
+	// MaxBackups holds the number of rotated files to keep.
+	// Rotated files are named after the audit log file, with a numeric suffix (for example, "audit.jsonl.1").
+	MaxBackups int
+}
+
+// Entry describes an audit log entry.
+type Entry struct {
+	Timestamp time.Time      `doc:"The time at which the operation completed." json:"timestamp"`
+	Caller    string         `doc:"The identity of the caller." json:"caller"`
+	RequestID string         `doc:"The ID of the request." json:"request_id,omitempty"`
+	Operation string         `doc:"The ID of the operation." json:"operation"`
+	Adapter   string         `doc:"The address of the target adapter." json:"adapter,omitempty"`
+	Device    string         `doc:"The address of the target device." json:"device,omitempty"`
+	Params    map[string]any `doc:"The parameters of the operation." json:"params,omitempty"`
+	Outcome   string         `doc:"The outcome of the operation." enum:"success,failure" json:"outcome"`
+	Status    int            `doc:"The HTTP status code of the response to the operation. It is omitted for MQTT commands." json:"status,omitempty"`
+}
+
+// Filter describes the filters of an audit log query.
+type Filter struct {
+	// Since and Until hold the time range of the returned entries. If either is zero, the range is open.
+	Since, Until time.Time
+
+	// Device holds the address of the target adapter or device of the returned entries.
+	Device string
+
+	// Operation holds the operation ID of the returned entries.
+	Operation string
+
+	// Limit holds the maximum number of returned entries.
+	Limit int
+}
+
+// Log is an append-only audit log, which is stored as JSON lines.
+type Log struct {
+	cfg Config
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+
+	// rotation is held for reading by queries, so that the files are not rotated while they are read.
+	// Appends do not take it, so they are not blocked by queries.
+	rotation sync.RWMutex
+}
+
+// Open opens the audit log file, and creates it if it does not exist.
+func Open(cfg Config) (*Log, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+
+	if cfg.MaxBackups < 0 {
+		cfg.MaxBackups = DefaultMaxBackups
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create audit log directory: %w", err)
+	}
+
+	l := &Log{cfg: cfg}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Close closes the audit log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// Record appends an entry to the audit log, and rotates the audit log file if required.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(b)) > l.cfg.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(b)
+	l.size += int64(n)
+
+	return err
+}
+
+// Query returns the entries of the audit log and its rotated files which match the filter,
+// with the most recent entries first.
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultQueryLimit
+	}
+
+	filter.Limit = min(filter.Limit, MaxQueryLimit)
+
+	l.rotation.RLock()
+	defer l.rotation.RUnlock()
+
+	var entries []Entry
+
+	for i := l.cfg.MaxBackups; i >= 0; i-- {
+		matched, err := l.read(l.name(i), filter)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, matched...)
+	}
+
+	slices.Reverse(entries)
+
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+
+	return entries, nil
+}
+
+// open opens the current audit log file for appending.
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+
+	l.file, l.size = file, info.Size()
+
+	return nil
+}
+
+// rotate renames the current audit log file and each rotated file to the next suffix,
+// removes the oldest rotated file, and opens a new audit log file.
+// The log's lock must be held while calling this function.
+func (l *Log) rotate() error {
+	l.rotation.Lock()
+	defer l.rotation.Unlock()
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("cannot rotate audit log: %w", err)
+	}
+
+	if l.cfg.MaxBackups == 0 {
+		if err := os.Remove(l.cfg.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot rotate audit log: %w", err)
+		}
+
+		return l.open()
+	}
+
+	for i := l.cfg.MaxBackups - 1; i >= 0; i-- {
+		if err := os.Rename(l.name(i), l.name(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot rotate audit log: %w", err)
+		}
+	}
+
+	return l.open()
+}
+
+// read returns the entries of an audit log file which match the filter.
+// A missing file has no entries, and a partially written last line is skipped.
+func (l *Log) read(name string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("cannot read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// name returns the name of the audit log file with the provided rotation suffix.
+func (l *Log) name(suffix int) string {
+	if suffix == 0 {
+		return l.cfg.Path
+	}
+
+	return l.cfg.Path + "." + strconv.Itoa(suffix)
+}
+
+// matches returns whether the entry matches the filter.
+func (f Filter) matches(entry Entry) bool {
+	switch {
+	case !f.Since.IsZero() && entry.Timestamp.Before(f.Since):
+		return false
+
+	case !f.Until.IsZero() && entry.Timestamp.After(f.Until):
+		return false
+
+	case f.Operation != "" && entry.Operation != f.Operation:
+		return false
+
+	case f.Device != "" && !strings.EqualFold(entry.Device, f.Device) && !strings.EqualFold(entry.Adapter, f.Device):
+		return false
+	}
+
+	return true
+}
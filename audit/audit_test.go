@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// openTestLog opens an audit log in a temporary directory, which is closed when the test finishes.
+func openTestLog(t *testing.T, cfg Config) *Log {
+	t.Helper()
+
+	cfg.Path = filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	return l
+}
+
+// operations returns the operations of the entries.
+func operations(entries []Entry) []string {
+	ops := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ops = append(ops, entry.Operation)
+	}
+
+	return ops
+}
+
+func TestQuery(t *testing.T) {
+	l := openTestLog(t, Config{})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []Entry{
+		{Operation: "adapter.power", Adapter: "00:1A:7D:DA:71:13"},
+		{Operation: "device.connect", Adapter: "00:1A:7D:DA:71:13", Device: "00:1B:66:01:02:03"},
+		{Operation: "device.disconnect", Adapter: "00:1A:7D:DA:71:13", Device: "00:1B:66:01:02:03"},
+		{Operation: "device.connect", Adapter: "00:1A:7D:DA:71:13", Device: "00:1B:66:04:05:06"},
+	}
+
+	for i, entry := range records {
+		entry.Timestamp, entry.Outcome = start.Add(time.Duration(i)*time.Minute), OutcomeSuccess
+		if err := l.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "all", want: []string{"device.connect", "device.disconnect", "device.connect", "adapter.power"}},
+		{name: "limit", filter: Filter{Limit: 2}, want: []string{"device.connect", "device.disconnect"}},
+		{name: "operation", filter: Filter{Operation: "device.disconnect"}, want: []string{"device.disconnect"}},
+		{name: "device", filter: Filter{Device: "00:1b:66:01:02:03"}, want: []string{"device.disconnect", "device.connect"}},
+		{name: "adapter", filter: Filter{Device: "00:1A:7D:DA:71:13", Limit: 1}, want: []string{"device.connect"}},
+		{
+			name:   "time range",
+			filter: Filter{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)},
+			want:   []string{"device.disconnect", "device.connect"},
+		},
+		{name: "no match", filter: Filter{Operation: "adapter.discovery"}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := l.Query(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := operations(entries); !slices.Equal(got, test.want) {
+				t.Fatalf("Query() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuerySkipsPartialLines(t *testing.T) {
+	l := openTestLog(t, Config{})
+
+	if err := l.Record(Entry{Operation: "adapter.power", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.file.WriteString(`{"operation":"device.con`); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := operations(entries); !slices.Equal(got, []string{"adapter.power"}) {
+		t.Fatalf("Query() = %v, want [adapter.power]", got)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBackups int
+		files      int
+		entries    int
+	}{
+		{name: "backups", maxBackups: 2, files: 3, entries: 3},
+		{name: "no backups", maxBackups: 0, files: 1, entries: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Each entry is larger than the maximum size, so each record rotates the log.
+			l := openTestLog(t, Config{MaxSize: 1, MaxBackups: test.maxBackups})
+
+			for i := range 5 {
+				if err := l.Record(Entry{Operation: "op" + strconv.Itoa(i), Outcome: OutcomeSuccess}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files, err := os.ReadDir(filepath.Dir(l.cfg.Path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(files) != test.files {
+				t.Fatalf("%d files, want %d", len(files), test.files)
+			}
+
+			entries, err := l.Query(Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"op4", "op3", "op2"}[:test.entries]
+			if got := operations(entries); !slices.Equal(got, want) {
+				t.Fatalf("Query() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	l := openTestLog(t, Config{})
+
+	if err := l.Record(Entry{Operation: "adapter.power", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Close()
+
+	reopened, err := Open(l.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if reopened.size != l.size {
+		t.Fatalf("size = %d, want %d", reopened.size, l.size)
+	}
+
+	if err := reopened.Record(Entry{Operation: "device.connect", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := reopened.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := operations(entries); !slices.Equal(got, []string{"device.connect", "adapter.power"}) {
+		t.Fatalf("Query() = %v", got)
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	tests := map[string]string{
+		"127.0.0.1:50000":     "127.0.0.1",
+		"[::1]:50000":         "::1",
+		"unix:uid=1000,pid=1": "unix:uid=1000",
+		"unix":                "unix",
+		"mqtt":                "mqtt",
+	}
+
+	for caller, want := range tests {
+		if got := Principal(caller); got != want {
+			t.Errorf("Principal(%q) = %q, want %q", caller, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// callerKey is the context key of the caller's identity.
+type callerKey struct{}
+
+// ConnContext attaches the identity of the peer of a connection to the context.
+// It is meant to be used as the ConnContext function of an HTTP server.
+// For UNIX socket connections, the identity includes the peer's user and process IDs, if available.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	caller := conn.RemoteAddr().String()
+
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		caller = "unix"
+		if creds := peerCredentials(unixConn); creds != "" {
+			caller += ":" + creds
+		}
+	}
+
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// Caller returns the identity of the caller attached to the context, if any.
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+
+	return caller
+}
+
+// Principal returns the part of a caller's identity which is the same for all of its connections:
+// the host of a TCP peer, or the user ID of a UNIX socket peer. Other identities are returned as is.
+func Principal(caller string) string {
+	if rest, ok := strings.CutPrefix(caller, "unix:"); ok {
+		uid, _, _ := strings.Cut(rest, ",")
+
+		return "unix:" + uid
+	}
+
+	if host, _, err := net.SplitHostPort(caller); err == nil {
+		return host
+	}
+
+	return caller
+}
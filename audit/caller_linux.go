@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the user and process IDs of the peer of a UNIX socket connection.
+func peerCredentials(conn *net.UnixConn) string {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return ""
+	}
+
+	var (
+		cred *unix.Ucred
+		cerr error
+	)
+
+	if err := raw.Control(func(fd uintptr) {
+		cred, cerr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil || cerr != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("uid=%d,pid=%d", cred.Uid, cred.Pid)
+}
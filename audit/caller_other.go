@@ -0,0 +1,10 @@
+//go:build !linux
+
+package audit
+
+import "net"
+
+// peerCredentials returns an empty identity, since peer credentials are only supported on Linux.
+func peerCredentials(*net.UnixConn) string {
+	return ""
+}
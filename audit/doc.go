@@ -0,0 +1,4 @@
+/*
+Package audit provides an append-only log of the state-changing operations performed through the API.
+*/
+package audit
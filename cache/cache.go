@@ -0,0 +1,683 @@
+package cache
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// Cache holds the last known state of the adapters, devices, media players and file transfers
+// of a session. Each entry holds the time at which it was last updated.
+type Cache struct {
+	session    bluetooth.Session
+	hub        *events.Hub
+	subscriber *events.Subscriber
+	done       chan struct{}
+
+	adapters        map[bluetooth.MacAddress]*adapterEntry
+	adaptersUpdated time.Time
+	devices         map[bluetooth.MacAddress]entry[bluetooth.DeviceData]
+	players         map[bluetooth.MacAddress]entry[bluetooth.MediaData]
+	transfers       map[bluetooth.MacAddress]entry[bluetooth.FileTransferEventData]
+	lastSeen        map[bluetooth.MacAddress]time.Time
+	seeded          bool
+	seq             uint64
+
+	changed chan struct{}
+
+	mu sync.RWMutex
+}
+
+// Snapshot holds the state of the cache after the event with the sequence number Seq was applied.
+type Snapshot struct {
+	Seq          uint64
+	Adapters     []bluetooth.AdapterData
+	Devices      []bluetooth.DeviceData
+	MediaPlayers []bluetooth.MediaEventData
+	Transfers    []bluetooth.FileTransferEventData
+}
+
+// entry holds a cached object and the time at which it was last updated.
+type entry[T any] struct {
+	data      T
+	updatedAt time.Time
+}
+
+// adapterEntry holds a cached adapter, and the time at which its list of devices was last updated.
+type adapterEntry struct {
+	entry[bluetooth.AdapterData]
+
+	devicesUpdated time.Time
+}
+
+// New returns a new cache of the session. Use (*Cache).Start() to seed the cache and keep it up to date.
+func New(session bluetooth.Session, hub *events.Hub) *Cache {
+	c := &Cache{
+		session:  session,
+		hub:      hub,
+		done:     make(chan struct{}),
+		changed:  make(chan struct{}),
+		lastSeen: make(map[bluetooth.MacAddress]time.Time),
+	}
+	c.reset()
+
+	return c
+}
+
+// Start seeds the cache from the session, and updates it from the session events until the cache is stopped.
+// The cache is seeded again each time the session is re-established.
+func (c *Cache) Start() {
+	c.seq = c.hub.Seq()
+	c.subscriber, _ = c.hub.SubscribeSince(c.seq)
+	c.seed()
+
+	go c.watch()
+}
+
+// Stop stops updating the cache.
+func (c *Cache) Stop() {
+	if c == nil || c.subscriber == nil {
+		return
+	}
+
+	c.subscriber.Unsubscribe()
+	<-c.done
+}
+
+// Changed returns a channel which is closed on the next update of the cache.
+func (c *Cache) Changed() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.changed
+}
+
+// Seq returns the sequence number of the last event that was applied to the cache.
+func (c *Cache) Seq() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.seq
+}
+
+// Snapshot returns the complete state of the cache.
+func (c *Cache) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Adapters:     []bluetooth.AdapterData{},
+		Devices:      []bluetooth.DeviceData{},
+		MediaPlayers: []bluetooth.MediaEventData{},
+		Transfers:    []bluetooth.FileTransferEventData{},
+	}
+	if c == nil {
+		return snapshot
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot.Seq = c.seq
+
+	for _, adapter := range c.adapters {
+		snapshot.Adapters = append(snapshot.Adapters, adapter.data)
+	}
+
+	for _, device := range c.devices {
+		snapshot.Devices = append(snapshot.Devices, device.data)
+	}
+
+	for address, player := range c.players {
+		snapshot.MediaPlayers = append(snapshot.MediaPlayers, bluetooth.MediaEventData{Address: address, MediaData: player.data})
+	}
+
+	for _, transfer := range c.transfers {
+		snapshot.Transfers = append(snapshot.Transfers, transfer.data)
+	}
+
+	slices.SortFunc(snapshot.Adapters, func(a, b bluetooth.AdapterData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+	slices.SortFunc(snapshot.Devices, func(a, b bluetooth.DeviceData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+	slices.SortFunc(snapshot.MediaPlayers, func(a, b bluetooth.MediaEventData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+	slices.SortFunc(snapshot.Transfers, func(a, b bluetooth.FileTransferEventData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return snapshot
+}
+
+// Adapters returns the cached adapters and the time at which they were last updated.
+// If the cache has not been seeded, false is returned.
+func (c *Cache) Adapters() ([]bluetooth.AdapterData, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.seeded {
+		return nil, time.Time{}, false
+	}
+
+	adapters := make([]bluetooth.AdapterData, 0, len(c.adapters))
+	for _, adapter := range c.adapters {
+		adapters = append(adapters, adapter.data)
+	}
+
+	slices.SortFunc(adapters, func(a, b bluetooth.AdapterData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return adapters, c.adaptersUpdated, true
+}
+
+// Adapter returns the cached properties of an adapter and the time at which they were last updated.
+func (c *Cache) Adapter(address bluetooth.MacAddress) (bluetooth.AdapterData, time.Time, bool) {
+	if c == nil {
+		return bluetooth.AdapterData{}, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	adapter, ok := c.adapters[address]
+	if !ok {
+		return bluetooth.AdapterData{}, time.Time{}, false
+	}
+
+	return adapter.data, adapter.updatedAt, true
+}
+
+// Devices returns the cached devices of an adapter and the time at which any of them was last updated.
+func (c *Cache) Devices(adapterAddress bluetooth.MacAddress) ([]bluetooth.DeviceData, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	adapter, ok := c.adapters[adapterAddress]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	devices := []bluetooth.DeviceData{}
+	for _, device := range c.devices {
+		if device.data.AssociatedAdapter == adapterAddress {
+			devices = append(devices, device.data)
+		}
+	}
+
+	slices.SortFunc(devices, func(a, b bluetooth.DeviceData) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return devices, adapter.devicesUpdated, true
+}
+
+// Device returns the cached properties of a device and the time at which they were last updated.
+func (c *Cache) Device(address bluetooth.MacAddress) (bluetooth.DeviceData, time.Time, bool) {
+	if c == nil {
+		return bluetooth.DeviceData{}, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	device, ok := c.devices[address]
+
+	return device.data, device.updatedAt, ok
+}
+
+// LastSeen returns the time at which a device was last seen, that is, when it was added or reported
+// a new signal strength. Unlike the update time of a device, it is not changed when the cache is re-seeded
+// or the devices are fetched from the session. False is returned if the device has not been seen yet.
+func (c *Cache) LastSeen(address bluetooth.MacAddress) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen, ok := c.lastSeen[address]
+
+	return seen, ok
+}
+
+// MediaPlayer returns the cached properties of a device's media player and the time at which they were last updated.
+func (c *Cache) MediaPlayer(address bluetooth.MacAddress) (bluetooth.MediaData, time.Time, bool) {
+	if c == nil {
+		return bluetooth.MediaData{}, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	player, ok := c.players[address]
+
+	return player.data, player.updatedAt, ok
+}
+
+// Transfer returns the progress of a device's ongoing file transfer and the time at which it was last updated.
+func (c *Cache) Transfer(address bluetooth.MacAddress) (bluetooth.FileTransferEventData, time.Time, bool) {
+	if c == nil {
+		return bluetooth.FileTransferEventData{}, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	transfer, ok := c.transfers[address]
+
+	return transfer.data, transfer.updatedAt, ok
+}
+
+// StoreAdapters replaces the cached adapters with the adapters that were fetched from the session,
+// and returns the time of the update.
+func (c *Cache) StoreAdapters(adapters []bluetooth.AdapterData) time.Time {
+	now := time.Now()
+	if c == nil {
+		return now
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	current := make(map[bluetooth.MacAddress]struct{}, len(adapters))
+	for _, adapter := range adapters {
+		current[adapter.Address] = struct{}{}
+		c.putAdapter(adapter, now)
+	}
+
+	for address := range c.adapters {
+		if _, ok := current[address]; !ok {
+			c.removeAdapter(address, now)
+		}
+	}
+
+	c.adaptersUpdated = now
+
+	return now
+}
+
+// StoreAdapter updates the cached properties of an adapter, and returns the time of the update.
+func (c *Cache) StoreAdapter(adapter bluetooth.AdapterData) time.Time {
+	now := time.Now()
+	if c == nil {
+		return now
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.putAdapter(adapter, now)
+	c.adaptersUpdated = now
+
+	return now
+}
+
+// StoreDevices replaces the cached devices of an adapter with the devices that were fetched from the session,
+// and returns the time of the update.
+func (c *Cache) StoreDevices(adapterAddress bluetooth.MacAddress, devices []bluetooth.DeviceData) time.Time {
+	now := time.Now()
+	if c == nil {
+		return now
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	if _, ok := c.adapters[adapterAddress]; !ok {
+		return now
+	}
+
+	for address, device := range c.devices {
+		if device.data.AssociatedAdapter == adapterAddress {
+			delete(c.devices, address)
+		}
+	}
+
+	for _, device := range devices {
+		c.devices[device.Address] = entry[bluetooth.DeviceData]{device, now}
+	}
+
+	c.adapters[adapterAddress].devicesUpdated = now
+
+	return now
+}
+
+// StoreDevice updates the cached properties of a device, and returns the time of the update.
+func (c *Cache) StoreDevice(device bluetooth.DeviceData) time.Time {
+	now := time.Now()
+	if c == nil {
+		return now
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.putDevice(device, now)
+
+	return now
+}
+
+// StoreMediaPlayer updates the cached properties of a device's media player, and returns the time of the update.
+func (c *Cache) StoreMediaPlayer(address bluetooth.MacAddress, player bluetooth.MediaData) time.Time {
+	now := time.Now()
+	if c == nil {
+		return now
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.players[address] = entry[bluetooth.MediaData]{player, now}
+
+	return now
+}
+
+// watch applies each event to the cache, until the cache is stopped. If any events were dropped
+// by the event hub, the cache is seeded again.
+func (c *Cache) watch() {
+	defer close(c.done)
+
+	for ev := range c.subscriber.C {
+		c.mu.RLock()
+		missed := ev.Seq > c.seq+1
+		c.mu.RUnlock()
+
+		if missed {
+			slog.Warn("State cache missed events, seeding again", "seq", ev.Seq)
+			c.seed()
+		}
+
+		c.apply(ev)
+
+		c.lock()
+		c.seq = ev.Seq
+		c.unlock()
+	}
+}
+
+// apply updates the cache with the data of a single event.
+func (c *Cache) apply(ev events.Event) {
+	now := time.Now()
+
+	switch data := ev.Data.(type) {
+	case supervisor.Status:
+		if data.State == supervisor.StateUp {
+			c.seed()
+		}
+
+	case bluetooth.Event[bluetooth.AdapterEventData]:
+		c.applyAdapter(data, now)
+
+	case bluetooth.Event[bluetooth.DeviceEventData]:
+		c.applyDevice(data, now)
+
+	case bluetooth.Event[bluetooth.MediaEventData]:
+		c.lock()
+		defer c.unlock()
+
+		if data.Action == bluetooth.EventActionRemoved {
+			delete(c.players, data.Data.Address)
+
+			return
+		}
+
+		player, ok := c.players[data.Data.Address]
+		if !ok {
+			return
+		}
+
+		c.players[data.Data.Address] = entry[bluetooth.MediaData]{mergeMedia(player.data, data.Data.MediaData), now}
+
+	case bluetooth.Event[bluetooth.FileTransferEventData]:
+		c.lock()
+		defer c.unlock()
+
+		transfer := data.Data
+		if data.Action == bluetooth.EventActionRemoved || (transfer.Size > 0 && transfer.Transferred >= transfer.Size) {
+			delete(c.transfers, transfer.Address)
+
+			return
+		}
+
+		c.transfers[transfer.Address] = entry[bluetooth.FileTransferEventData]{transfer, now}
+	}
+}
+
+// applyAdapter updates the cache with an adapter event. The properties of newly added adapters,
+// along with their devices, are fetched from the session.
+func (c *Cache) applyAdapter(ev bluetooth.Event[bluetooth.AdapterEventData], now time.Time) {
+	address := ev.Data.Address
+
+	if ev.Action == bluetooth.EventActionRemoved {
+		c.lock()
+		c.removeAdapter(address, now)
+		c.adaptersUpdated = now
+		c.unlock()
+
+		return
+	}
+
+	c.mu.RLock()
+	adapter, ok := c.adapters[address]
+	c.mu.RUnlock()
+
+	if ok {
+		c.lock()
+		adapter.data.AdapterEventData = ev.Data
+		adapter.updatedAt = now
+		c.adaptersUpdated = now
+		c.unlock()
+
+		return
+	}
+
+	properties, err := c.session.Adapter(address).Properties()
+	if err != nil {
+		properties = bluetooth.AdapterData{AdapterEventData: ev.Data}
+	}
+
+	devices, _ := c.session.Adapter(address).Devices()
+
+	c.lock()
+	defer c.unlock()
+
+	c.putAdapter(properties, now)
+	c.adaptersUpdated = now
+
+	for _, device := range devices {
+		c.putDevice(device, now)
+	}
+}
+
+// applyDevice updates the cache with a device event. Since the device events only hold the
+// dynamic properties of a device, the properties of newly added devices are fetched from the session.
+func (c *Cache) applyDevice(ev bluetooth.Event[bluetooth.DeviceEventData], now time.Time) {
+	address := ev.Data.Address
+
+	c.lock()
+	device, ok := c.devices[address]
+
+	if ev.Action == bluetooth.EventActionAdded || !ok || (ev.Data.RSSI != 0 && ev.Data.RSSI != device.data.RSSI) {
+		c.lastSeen[address] = now
+	}
+
+	switch {
+	case ev.Action == bluetooth.EventActionRemoved:
+		c.removeDevice(address, now)
+		c.unlock()
+
+		return
+
+	case ok:
+		device.data.DeviceEventData = ev.Data
+		c.putDevice(device.data, now)
+
+		if !ev.Data.Connected {
+			delete(c.players, address)
+		}
+
+		c.unlock()
+
+		return
+	}
+	c.unlock()
+
+	properties, err := c.session.Device(address).Properties()
+	if err != nil {
+		properties = bluetooth.DeviceData{DeviceEventData: ev.Data}
+	}
+
+	properties.DeviceEventData = ev.Data
+
+	c.lock()
+	defer c.unlock()
+
+	c.putDevice(properties, now)
+}
+
+// seed replaces the contents of the cache with the current state of the session.
+func (c *Cache) seed() {
+	now := time.Now()
+
+	adapters := c.session.Adapters()
+	devices := make(map[bluetooth.MacAddress][]bluetooth.DeviceData, len(adapters))
+
+	for _, adapter := range adapters {
+		if list, err := c.session.Adapter(adapter.Address).Devices(); err == nil {
+			devices[adapter.Address] = list
+		}
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	c.reset()
+
+	for _, adapter := range adapters {
+		c.putAdapter(adapter, now)
+
+		for _, device := range devices[adapter.Address] {
+			c.putDevice(device, now)
+		}
+	}
+
+	for address := range c.lastSeen {
+		if _, ok := c.devices[address]; !ok {
+			delete(c.lastSeen, address)
+		}
+	}
+
+	c.adaptersUpdated = now
+	c.seeded = true
+}
+
+// lock locks the cache for an update.
+func (c *Cache) lock() {
+	c.mu.Lock()
+}
+
+// unlock unlocks the cache after an update, and wakes up the callers that are waiting for a change.
+func (c *Cache) unlock() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+
+	c.mu.Unlock()
+}
+
+// reset clears the contents of the cache.
+func (c *Cache) reset() {
+	c.adapters = make(map[bluetooth.MacAddress]*adapterEntry)
+	c.devices = make(map[bluetooth.MacAddress]entry[bluetooth.DeviceData])
+	c.players = make(map[bluetooth.MacAddress]entry[bluetooth.MediaData])
+	c.transfers = make(map[bluetooth.MacAddress]entry[bluetooth.FileTransferEventData])
+}
+
+// putAdapter stores an adapter. The cache must be locked by the caller.
+func (c *Cache) putAdapter(adapter bluetooth.AdapterData, now time.Time) {
+	if current, ok := c.adapters[adapter.Address]; ok {
+		current.data, current.updatedAt = adapter, now
+
+		return
+	}
+
+	c.adapters[adapter.Address] = &adapterEntry{
+		entry:          entry[bluetooth.AdapterData]{adapter, now},
+		devicesUpdated: now,
+	}
+}
+
+// removeAdapter removes an adapter and its devices. The cache must be locked by the caller.
+func (c *Cache) removeAdapter(address bluetooth.MacAddress, now time.Time) {
+	delete(c.adapters, address)
+
+	for deviceAddress, device := range c.devices {
+		if device.data.AssociatedAdapter == address {
+			c.removeDevice(deviceAddress, now)
+		}
+	}
+}
+
+// putDevice stores a device. The cache must be locked by the caller.
+func (c *Cache) putDevice(device bluetooth.DeviceData, now time.Time) {
+	c.devices[device.Address] = entry[bluetooth.DeviceData]{device, now}
+
+	if adapter, ok := c.adapters[device.AssociatedAdapter]; ok {
+		adapter.devicesUpdated = now
+	}
+}
+
+// removeDevice removes a device, its media player, its file transfer and the time at which it was last seen. The cache must be locked by the caller.
+func (c *Cache) removeDevice(address bluetooth.MacAddress, now time.Time) {
+	if device, ok := c.devices[address]; ok {
+		if adapter, ok := c.adapters[device.data.AssociatedAdapter]; ok {
+			adapter.devicesUpdated = now
+		}
+	}
+
+	delete(c.devices, address)
+	delete(c.players, address)
+	delete(c.transfers, address)
+	delete(c.lastSeen, address)
+}
+
+// mergeMedia merges the changed properties of a media player event into the cached media player properties.
+// Since the media player events only hold the changed properties, empty values are left unchanged, and
+// a media player is only cached once its properties have been fetched from the session.
+func mergeMedia(player, changed bluetooth.MediaData) bluetooth.MediaData {
+	if changed.Status != "" {
+		player.Status = changed.Status
+	}
+
+	if changed.Position != 0 {
+		player.Position = changed.Position
+	}
+
+	if changed.TrackData != (bluetooth.TrackData{}) {
+		player.TrackData = changed.TrackData
+	}
+
+	return player
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// testTimeout is the maximum time to wait for an event to be applied.
+const testTimeout = 5 * time.Second
+
+// fakeSession is a session with fixed adapters and devices. Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	adapters []bluetooth.AdapterData
+	devices  []bluetooth.DeviceData
+}
+
+// fakeAdapter is an adapter of a fakeSession.
+type fakeAdapter struct {
+	bluetooth.Adapter
+
+	session *fakeSession
+	address bluetooth.MacAddress
+}
+
+// fakeDevice is a device of a fakeSession.
+type fakeDevice struct {
+	bluetooth.Device
+
+	session *fakeSession
+	address bluetooth.MacAddress
+}
+
+func (s *fakeSession) Adapters() []bluetooth.AdapterData {
+	return s.adapters
+}
+
+func (s *fakeSession) Adapter(address bluetooth.MacAddress) bluetooth.Adapter {
+	return fakeAdapter{session: s, address: address}
+}
+
+func (s *fakeSession) Device(address bluetooth.MacAddress) bluetooth.Device {
+	return fakeDevice{session: s, address: address}
+}
+
+func (a fakeAdapter) Properties() (bluetooth.AdapterData, error) {
+	for _, adapter := range a.session.adapters {
+		if adapter.Address == a.address {
+			return adapter, nil
+		}
+	}
+
+	return bluetooth.AdapterData{}, errors.New("adapter not found")
+}
+
+func (a fakeAdapter) Devices() ([]bluetooth.DeviceData, error) {
+	var devices []bluetooth.DeviceData
+
+	for _, device := range a.session.devices {
+		if device.AssociatedAdapter == a.address {
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
+
+func (d fakeDevice) Properties() (bluetooth.DeviceData, error) {
+	for _, device := range d.session.devices {
+		if device.Address == d.address {
+			return device, nil
+		}
+	}
+
+	return bluetooth.DeviceData{}, errors.New("device not found")
+}
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// testDevice returns the data of a device of an adapter.
+func testDevice(adapter, address, name string, rssi int16) bluetooth.DeviceData {
+	return bluetooth.DeviceData{
+		Name: name,
+		DeviceEventData: bluetooth.DeviceEventData{
+			Address: mac(address), AssociatedAdapter: mac(adapter), RSSI: rssi,
+		},
+	}
+}
+
+// newTestCache starts a cache of the session, which is stopped when the test finishes.
+func newTestCache(t *testing.T, session *fakeSession) (*Cache, *events.Hub) {
+	t.Helper()
+
+	hub := events.NewHub()
+	c := New(session, hub)
+	c.Start()
+
+	t.Cleanup(c.Stop)
+
+	return c, hub
+}
+
+// publish publishes an event, and waits until the cache has applied it.
+func publish[T bluetooth.Events](t *testing.T, c *Cache, hub *events.Hub, id bluetooth.EventID, name string, action bluetooth.EventAction, data T) {
+	t.Helper()
+
+	hub.Publish(id.Value(), name, bluetooth.Event[T]{Action: action, Data: data})
+
+	deadline := time.Now().Add(testTimeout)
+	for c.Seq() != hub.Seq() {
+		if time.Now().After(deadline) {
+			t.Fatal("the event was not applied in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// addresses returns the addresses of the devices.
+func addresses(devices []bluetooth.DeviceData) []string {
+	list := make([]string, 0, len(devices))
+	for _, device := range devices {
+		list = append(list, device.Address.String())
+	}
+
+	return list
+}
+
+func TestSeed(t *testing.T) {
+	session := &fakeSession{
+		adapters: []bluetooth.AdapterData{
+			{Name: "second", AdapterEventData: bluetooth.AdapterEventData{Address: mac("00:1A:7D:DA:71:14")}},
+			{Name: "first", AdapterEventData: bluetooth.AdapterEventData{Address: mac("00:1A:7D:DA:71:13")}},
+		},
+		devices: []bluetooth.DeviceData{
+			testDevice("00:1A:7D:DA:71:13", "00:1B:66:04:05:06", "speaker", 0),
+			testDevice("00:1A:7D:DA:71:14", "00:1B:66:07:08:09", "phone", 0),
+			testDevice("00:1A:7D:DA:71:13", "00:1B:66:01:02:03", "headphones", 0),
+		},
+	}
+
+	c, _ := newTestCache(t, session)
+
+	adapters, _, ok := c.Adapters()
+	if !ok || len(adapters) != 2 || adapters[0].Name != "first" || adapters[1].Name != "second" {
+		t.Fatalf("Adapters() = %+v, %v, want the adapters sorted by address", adapters, ok)
+	}
+
+	devices, _, ok := c.Devices(mac("00:1A:7D:DA:71:13"))
+	if want := []string{"00:1B:66:01:02:03", "00:1B:66:04:05:06"}; !ok || !slices.Equal(addresses(devices), want) {
+		t.Fatalf("Devices() = %v, want %v", addresses(devices), want)
+	}
+
+	if _, _, ok := c.Devices(mac("00:1A:7D:DA:71:15")); ok {
+		t.Fatal("Devices() of an unknown adapter succeeded")
+	}
+
+	snapshot := c.Snapshot()
+	if want := []string{"00:1B:66:01:02:03", "00:1B:66:04:05:06", "00:1B:66:07:08:09"}; !slices.Equal(addresses(snapshot.Devices), want) {
+		t.Fatalf("Snapshot().Devices = %v, want %v", addresses(snapshot.Devices), want)
+	}
+
+	if _, ok := c.LastSeen(mac("00:1B:66:01:02:03")); ok {
+		t.Fatal("a seeded device was seen")
+	}
+}
+
+func TestDeviceEvents(t *testing.T) {
+	adapter := "00:1A:7D:DA:71:13"
+	headphones := testDevice(adapter, "00:1B:66:01:02:03", "headphones", -60)
+
+	session := &fakeSession{
+		adapters: []bluetooth.AdapterData{{AdapterEventData: bluetooth.AdapterEventData{Address: mac(adapter)}}},
+	}
+
+	c, hub := newTestCache(t, session)
+
+	// The device is added after the cache is seeded.
+	session.devices = []bluetooth.DeviceData{headphones}
+
+	device := headphones.DeviceEventData
+	publish(t, c, hub, bluetooth.EventDevice, "device", bluetooth.EventActionAdded, device)
+
+	cached, _, ok := c.Device(device.Address)
+	if !ok || cached.Name != "headphones" {
+		t.Fatalf("Device() = %+v, %v, want the properties to be fetched", cached, ok)
+	}
+
+	added, ok := c.LastSeen(device.Address)
+	if !ok {
+		t.Fatal("the added device was not seen")
+	}
+
+	tests := []struct {
+		name string
+		rssi int16
+		seen bool
+	}{
+		{name: "unchanged signal strength", rssi: -60},
+		{name: "no signal strength", rssi: 0},
+		{name: "changed signal strength", rssi: -70, seen: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			time.Sleep(time.Millisecond)
+
+			last, _ := c.LastSeen(device.Address)
+
+			device.RSSI, device.Connected = test.rssi, true
+			publish(t, c, hub, bluetooth.EventDevice, "device", bluetooth.EventActionUpdated, device)
+
+			cached, _, _ := c.Device(device.Address)
+			if cached.Name != "headphones" || cached.RSSI != test.rssi || !cached.Connected {
+				t.Fatalf("Device() = %+v, want the event to be merged", cached)
+			}
+
+			if seen, _ := c.LastSeen(device.Address); seen.After(last) != test.seen || seen.Before(added) {
+				t.Fatalf("LastSeen() = %v, last sighting at %v, want seen = %v", seen, last, test.seen)
+			}
+		})
+	}
+
+	c.StoreMediaPlayer(device.Address, bluetooth.MediaData{Status: "paused", TrackData: bluetooth.TrackData{Title: "first"}})
+	publish(t, c, hub, bluetooth.EventMediaPlayer, "media_player", bluetooth.EventActionUpdated, bluetooth.MediaEventData{
+		Address:   device.Address,
+		MediaData: bluetooth.MediaData{Status: "playing"},
+	})
+
+	if player, _, ok := c.MediaPlayer(device.Address); !ok || player.Status != "playing" || player.Title != "first" {
+		t.Fatalf("MediaPlayer() = %+v, %v, want the changed status to be merged", player, ok)
+	}
+
+	publish(t, c, hub, bluetooth.EventFileTransfer, "file_transfer", bluetooth.EventActionUpdated, bluetooth.FileTransferEventData{
+		Address: device.Address, Size: 100, Transferred: 50,
+	})
+
+	if transfer, _, ok := c.Transfer(device.Address); !ok || transfer.Transferred != 50 {
+		t.Fatalf("Transfer() = %+v, %v", transfer, ok)
+	}
+
+	publish(t, c, hub, bluetooth.EventFileTransfer, "file_transfer", bluetooth.EventActionUpdated, bluetooth.FileTransferEventData{
+		Address: device.Address, Size: 100, Transferred: 100,
+	})
+
+	if _, _, ok := c.Transfer(device.Address); ok {
+		t.Fatal("the completed transfer is still cached")
+	}
+
+	device.Connected = false
+	publish(t, c, hub, bluetooth.EventDevice, "device", bluetooth.EventActionUpdated, device)
+
+	if _, _, ok := c.MediaPlayer(device.Address); ok {
+		t.Fatal("the media player of the disconnected device is still cached")
+	}
+
+	publish(t, c, hub, bluetooth.EventDevice, "device", bluetooth.EventActionRemoved, device)
+
+	if _, _, ok := c.Device(device.Address); ok {
+		t.Fatal("the removed device is still cached")
+	}
+
+	if _, ok := c.LastSeen(device.Address); ok {
+		t.Fatal("the removed device is still seen")
+	}
+}
+
+func TestAdapterEvents(t *testing.T) {
+	adapter := bluetooth.AdapterData{Name: "test", AdapterEventData: bluetooth.AdapterEventData{Address: mac("00:1A:7D:DA:71:13")}}
+	session := &fakeSession{
+		adapters: []bluetooth.AdapterData{adapter},
+		devices:  []bluetooth.DeviceData{testDevice("00:1A:7D:DA:71:13", "00:1B:66:01:02:03", "headphones", 0)},
+	}
+
+	c, hub := newTestCache(t, session)
+
+	event := adapter.AdapterEventData
+	event.Powered = true
+	publish(t, c, hub, bluetooth.EventAdapter, "adapter", bluetooth.EventActionUpdated, event)
+
+	if cached, _, ok := c.Adapter(adapter.Address); !ok || cached.Name != "test" || !cached.Powered {
+		t.Fatalf("Adapter() = %+v, %v, want the event to be merged", cached, ok)
+	}
+
+	publish(t, c, hub, bluetooth.EventAdapter, "adapter", bluetooth.EventActionRemoved, event)
+
+	if snapshot := c.Snapshot(); len(snapshot.Adapters) != 0 || len(snapshot.Devices) != 0 {
+		t.Fatalf("Snapshot() = %+v, want the adapter and its devices to be removed", snapshot)
+	}
+
+	// A new adapter is fetched from the session, along with its devices.
+	publish(t, c, hub, bluetooth.EventAdapter, "adapter", bluetooth.EventActionAdded, event)
+
+	if cached, _, ok := c.Adapter(adapter.Address); !ok || cached.Name != "test" {
+		t.Fatalf("Adapter() = %+v, %v, want the properties to be fetched", cached, ok)
+	}
+
+	if devices, _, _ := c.Devices(adapter.Address); len(devices) != 1 {
+		t.Fatalf("Devices() = %+v, want the devices to be fetched", devices)
+	}
+}
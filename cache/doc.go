@@ -0,0 +1,5 @@
+/*
+Package cache provides an in-memory model of the adapters, devices, media players and file transfers of a session,
+which is seeded from the session and kept up to date from the session events.
+*/
+package cache
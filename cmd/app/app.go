@@ -4,20 +4,43 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
 	ac "github.com/bluetuith-org/bluetooth-classic/api/appfeatures"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/bluetuith-org/bluetooth-classic/api/config"
-	"github.com/bluetuith-org/bluetooth-classic/api/eventbus"
 	"github.com/bluetuith-org/bluetooth-classic/session"
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/deviceinfo"
+	"github.com/bluetuith-org/bluerestd/discovery"
 	"github.com/bluetuith-org/bluerestd/endpoints"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/instrument"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/bluetuith-org/bluerestd/metrics"
+	"github.com/bluetuith-org/bluerestd/mqttbridge"
+	"github.com/bluetuith-org/bluerestd/presence"
+	"github.com/bluetuith-org/bluerestd/proximity"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluerestd/registry"
+	"github.com/bluetuith-org/bluerestd/setup"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	"github.com/bluetuith-org/bluerestd/systemd"
+	"github.com/bluetuith-org/bluerestd/tracing"
+	"github.com/bluetuith-org/bluerestd/visibility"
+	"github.com/bluetuith-org/bluerestd/webhooks"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/pterm/pterm"
 	"github.com/urfave/cli/v2"
@@ -31,6 +54,15 @@ const (
 
 var sockAddress = path.Join(os.TempDir(), "bluerestd.sock")
 
+var dataDirectory = func() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "bluerestd")
+}()
+
 // These values are set at compile-time.
 var (
 	Version  = ""
@@ -99,58 +131,57 @@ func cliApp() *cli.App {
 				},
 				Action: cmdOpenAPI,
 			},
+			{
+				Name:  "service",
+				Usage: "Manage the systemd service of the daemon.",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "install",
+						Usage:       "Generate and install a systemd service unit and socket unit.",
+						Description: "The units are generated from the provided 'launch' options, and the daemon is socket-activated on the configured TCP address or UNIX socket.\nBy default, the units are installed for the per-user service manager, since the session DBus is required for file transfers.",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:     "system",
+								Usage:    "Install the units for the system service manager instead of the per-user service manager.",
+								Required: false,
+								Value:    false,
+							},
+							&cli.StringFlag{
+								Name:        "output-dir",
+								Usage:       "The directory to write the units to.",
+								Required:    false,
+								DefaultText: "the user or system unit directory",
+							},
+							&cli.DurationFlag{
+								Name:        "watchdog-sec",
+								Usage:       "The watchdog timeout of the service. The watchdog is only notified while the Bluetooth session is up.",
+								Required:    false,
+								DefaultText: systemd.DefaultWatchdogSec.String(),
+								Value:       systemd.DefaultWatchdogSec,
+							},
+							&cli.BoolFlag{
+								Name:     "print",
+								Usage:    "Print the units instead of installing them.",
+								Required: false,
+								Value:    false,
+							},
+							&cli.BoolFlag{
+								Name:     "force",
+								Usage:    "Overwrite existing units.",
+								Required: false,
+								Value:    false,
+							},
+						}, launchFlags()...),
+						Action: cmdServiceInstall,
+					},
+				},
+			},
 			{
 				Name:        "launch",
 				Usage:       "Start the daemon and listen for incoming API requests.",
 				Description: "This subcommand requires either of the 'tcp-address' or 'unix-socket' options to be set.\nIf both options are empty, the default TCP address is used to listen for incoming API requests.",
-				Flags: []cli.Flag{
-					&cli.DurationFlag{
-						Name:        "auth-timeout",
-						Usage:       "The authentication timeout for device pairing and file transfer (in seconds).",
-						Required:    false,
-						DefaultText: "10",
-						Value:       10,
-						Aliases:     []string{"i"},
-						EnvVars:     []string{"BRESTD_AUTHTIMEOUT"},
-					},
-					&cli.StringFlag{
-						Name:        "tcp-address",
-						Usage:       "The TCP address to listen on for API operations.",
-						Required:    false,
-						DefaultText: tcpURI,
-						Value:       tcpURI,
-						Aliases:     []string{"a"},
-						EnvVars:     []string{"BRESTD_TCPADDR"},
-					},
-					&cli.BoolFlag{
-						Name:        "using-default-tcp",
-						Usage:       "Uses the default TCP address to start the daemon",
-						Required:    false,
-						DefaultText: tcpURI,
-						Value:       false,
-						Aliases:     []string{"t"},
-						EnvVars:     []string{"BRESTD_USE_DEFAULT_TCPADDR"},
-					},
-					&cli.StringFlag{
-						Name:        "unix-socket",
-						Usage:       "The UNIX socket path to listen on for API operations.\nIn this case, the 'http+unix' protocol is used, and clients can connect using this protocol.\nNote that the socket does not need to be created prior to using this option, it will be created automatically.\nIf the socket exists, it will return an error. For example, to connect to the socket via 'curl', use:\n curl --unix-socket " + sockAddress + " http://localhost/<endpoint>.",
-						Required:    false,
-						DefaultText: sockAddress,
-						Value:       sockAddress,
-						Aliases:     []string{"s"},
-						EnvVars:     []string{"BRESTD_SOCKET"},
-					},
-					&cli.BoolFlag{
-						Name:        "using-default-socket",
-						Usage:       "Uses the default UNIX socket to start the daemon",
-						Required:    false,
-						DefaultText: sockAddress,
-						Value:       false,
-						Aliases:     []string{"u"},
-						EnvVars:     []string{"BRESTD_USE_DEFAULT_SOCKET"},
-					},
-				},
-				Action: cmdStart,
+				Flags:       launchFlags(),
+				Action:      cmdStart,
 			},
 		},
 		ExitErrHandler: func(_ *cli.Context, err error) {
@@ -172,6 +203,344 @@ func cliApp() *cli.App {
 	}
 }
 
+// launchFlags returns the options of the 'launch' command.
+func launchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "auth-timeout",
+			Usage:       "The authentication timeout for device pairing and file transfer (in seconds).",
+			Required:    false,
+			DefaultText: "10",
+			Value:       10,
+			Aliases:     []string{"i"},
+			EnvVars:     []string{"BRESTD_AUTHTIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:        "tcp-address",
+			Usage:       "The TCP address to listen on for API operations.",
+			Required:    false,
+			DefaultText: tcpURI,
+			Value:       tcpURI,
+			Aliases:     []string{"a"},
+			EnvVars:     []string{"BRESTD_TCPADDR"},
+		},
+		&cli.BoolFlag{
+			Name:        "using-default-tcp",
+			Usage:       "Uses the default TCP address to start the daemon",
+			Required:    false,
+			DefaultText: tcpURI,
+			Value:       false,
+			Aliases:     []string{"t"},
+			EnvVars:     []string{"BRESTD_USE_DEFAULT_TCPADDR"},
+		},
+		&cli.StringFlag{
+			Name:        "unix-socket",
+			Usage:       "The UNIX socket path to listen on for API operations.\nIn this case, the 'http+unix' protocol is used, and clients can connect using this protocol.\nNote that the socket does not need to be created prior to using this option, it will be created automatically.\nIf the socket exists, it will return an error. For example, to connect to the socket via 'curl', use:\n curl --unix-socket " + sockAddress + " http://localhost/<endpoint>.",
+			Required:    false,
+			DefaultText: sockAddress,
+			Value:       sockAddress,
+			Aliases:     []string{"s"},
+			EnvVars:     []string{"BRESTD_SOCKET"},
+		},
+		&cli.BoolFlag{
+			Name:        "using-default-socket",
+			Usage:       "Uses the default UNIX socket to start the daemon",
+			Required:    false,
+			DefaultText: sockAddress,
+			Value:       false,
+			Aliases:     []string{"u"},
+			EnvVars:     []string{"BRESTD_USE_DEFAULT_SOCKET"},
+		},
+		&cli.DurationFlag{
+			Name:        "session-check-interval",
+			Usage:       "The interval between each check of the Bluetooth session. If the session is lost, it is restarted.",
+			Required:    false,
+			DefaultText: supervisor.DefaultCheckInterval.String(),
+			Value:       supervisor.DefaultCheckInterval,
+			EnvVars:     []string{"BRESTD_SESSION_CHECK_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "session-max-backoff",
+			Usage:       "The maximum wait time between each attempt to restart a lost Bluetooth session.",
+			Required:    false,
+			DefaultText: supervisor.DefaultMaxBackoff.String(),
+			Value:       supervisor.DefaultMaxBackoff,
+			EnvVars:     []string{"BRESTD_SESSION_MAX_BACKOFF"},
+		},
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "The minimum level of the logs (one of 'debug', 'info', 'warn' or 'error').",
+			Required:    false,
+			DefaultText: "info",
+			Value:       "info",
+			EnvVars:     []string{"BRESTD_LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:        "log-format",
+			Usage:       "The format of the logs (one of 'text' or 'json').",
+			Required:    false,
+			DefaultText: "text",
+			Value:       "text",
+			EnvVars:     []string{"BRESTD_LOG_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:     "log-file",
+			Usage:    "The path to a file to append the logs to. If this option is empty, logs are written to the standard error.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_LOG_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:     "non-interactive",
+			Usage:    "Disables spinners and styled output. This is enabled automatically when the daemon is run as a systemd service.",
+			Required: false,
+			Value:    false,
+			EnvVars:  []string{"BRESTD_NON_INTERACTIVE"},
+		},
+		&cli.StringFlag{
+			Name:        "data-dir",
+			Usage:       "The directory to store the daemon's persistent state (for example, webhook subscriptions, the device registry and the adapter timeouts) in.",
+			Required:    false,
+			DefaultText: dataDirectory,
+			Value:       dataDirectory,
+			Aliases:     []string{"d"},
+			EnvVars:     []string{"BRESTD_DATA_DIR"},
+		},
+		&cli.StringFlag{
+			Name:        "audit-log",
+			Usage:       "The path to the audit log file, which records all state-changing operations.",
+			Required:    false,
+			DefaultText: filepath.Join("<data-dir>", "audit.jsonl"),
+			EnvVars:     []string{"BRESTD_AUDIT_LOG"},
+		},
+		&cli.IntFlag{
+			Name:        "audit-max-size",
+			Usage:       "The size of the audit log file (in megabytes), after which it is rotated.",
+			Required:    false,
+			DefaultText: strconv.Itoa(audit.DefaultMaxSize >> 20),
+			Value:       audit.DefaultMaxSize >> 20,
+			EnvVars:     []string{"BRESTD_AUDIT_MAX_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:        "audit-max-backups",
+			Usage:       "The number of rotated audit log files to keep.",
+			Required:    false,
+			DefaultText: strconv.Itoa(audit.DefaultMaxBackups),
+			Value:       audit.DefaultMaxBackups,
+			EnvVars:     []string{"BRESTD_AUDIT_MAX_BACKUPS"},
+		},
+		&cli.IntFlag{
+			Name:        "webhook-max-attempts",
+			Usage:       "The maximum number of attempts to deliver an event to a webhook, before it is moved to the dead-letter queue.",
+			Required:    false,
+			DefaultText: strconv.Itoa(webhooks.DefaultMaxAttempts),
+			Value:       webhooks.DefaultMaxAttempts,
+			EnvVars:     []string{"BRESTD_WEBHOOK_MAX_ATTEMPTS"},
+		},
+		&cli.DurationFlag{
+			Name:        "webhook-backoff",
+			Usage:       "The initial wait time before retrying a failed webhook delivery. It is doubled after each retry.",
+			Required:    false,
+			DefaultText: webhooks.DefaultInitialBackoff.String(),
+			Value:       webhooks.DefaultInitialBackoff,
+			EnvVars:     []string{"BRESTD_WEBHOOK_BACKOFF"},
+		},
+		&cli.IntFlag{
+			Name:        "webhook-queue-size",
+			Usage:       "The maximum number of events waiting to be delivered to each webhook. Events that do not fit are moved to the dead-letter queue.",
+			Required:    false,
+			DefaultText: strconv.Itoa(webhooks.DefaultQueueSize),
+			Value:       webhooks.DefaultQueueSize,
+			EnvVars:     []string{"BRESTD_WEBHOOK_QUEUE_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:     "otel-endpoint",
+			Usage:    "The URL of an OpenTelemetry collector to export traces to, using OTLP over HTTP (for example, 'http://127.0.0.1:4318').\nIf the URL has no path, the '/v1/traces' path is used.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_OTEL_ENDPOINT"},
+		},
+		&cli.StringFlag{
+			Name:     "otel-file",
+			Usage:    "The path to a file to export traces to, as JSON.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_OTEL_FILE"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-broker",
+			Usage:    "The URL of an MQTT broker to publish events to and receive commands from (for example, 'tcp://127.0.0.1:1883').\nThe 'ssl://', 'ws://' and 'wss://' schemes are also supported. If this option is empty, the MQTT bridge is disabled.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_BROKER"},
+		},
+		&cli.StringFlag{
+			Name:        "mqtt-client-id",
+			Usage:       "The client ID to connect to the MQTT broker with.",
+			Required:    false,
+			DefaultText: mqttbridge.DefaultClientID,
+			Value:       mqttbridge.DefaultClientID,
+			EnvVars:     []string{"BRESTD_MQTT_CLIENT_ID"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-username",
+			Usage:    "The username to connect to the MQTT broker with.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-password",
+			Usage:    "The password to connect to the MQTT broker with.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:        "mqtt-topic-prefix",
+			Usage:       "The prefix of all MQTT topics that are published and subscribed to.",
+			Required:    false,
+			DefaultText: mqttbridge.DefaultTopicPrefix,
+			Value:       mqttbridge.DefaultTopicPrefix,
+			EnvVars:     []string{"BRESTD_MQTT_TOPIC_PREFIX"},
+		},
+		&cli.UintFlag{
+			Name:        "mqtt-qos",
+			Usage:       "The quality of service level (0, 1 or 2) of all MQTT messages.",
+			Required:    false,
+			DefaultText: "1",
+			Value:       1,
+			EnvVars:     []string{"BRESTD_MQTT_QOS"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-tls-ca",
+			Usage:    "The path to a PEM encoded CA certificate to verify the MQTT broker with.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_TLS_CA"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-tls-cert",
+			Usage:    "The path to a PEM encoded client certificate to authenticate with the MQTT broker.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_TLS_CERT"},
+		},
+		&cli.StringFlag{
+			Name:     "mqtt-tls-key",
+			Usage:    "The path to the PEM encoded private key of the client certificate.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_MQTT_TLS_KEY"},
+		},
+		&cli.BoolFlag{
+			Name:     "mqtt-tls-insecure",
+			Usage:    "Skips the verification of the MQTT broker's certificate.",
+			Required: false,
+			Value:    false,
+			EnvVars:  []string{"BRESTD_MQTT_TLS_INSECURE"},
+		},
+		&cli.BoolFlag{
+			Name:     "mqtt-homeassistant",
+			Usage:    "Publishes Home Assistant MQTT discovery payloads for all adapters and paired devices.",
+			Required: false,
+			Value:    false,
+			EnvVars:  []string{"BRESTD_MQTT_HOMEASSISTANT"},
+		},
+		&cli.StringFlag{
+			Name:        "mqtt-homeassistant-prefix",
+			Usage:       "The Home Assistant MQTT discovery prefix.",
+			Required:    false,
+			DefaultText: mqttbridge.DefaultDiscoveryPrefix,
+			Value:       mqttbridge.DefaultDiscoveryPrefix,
+			EnvVars:     []string{"BRESTD_MQTT_HOMEASSISTANT_PREFIX"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "presence-devices",
+			Usage:       "The addresses of the devices to track the presence of. If this option is empty, all devices are tracked.",
+			Required:    false,
+			DefaultText: "all devices",
+			EnvVars:     []string{"BRESTD_PRESENCE_DEVICES"},
+		},
+		&cli.DurationFlag{
+			Name:        "presence-away-timeout",
+			Usage:       "The time after the last sighting of a device, after which it departs.",
+			Required:    false,
+			DefaultText: presence.DefaultAwayTimeout.String(),
+			Value:       presence.DefaultAwayTimeout,
+			EnvVars:     []string{"BRESTD_PRESENCE_AWAY_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:        "presence-arrive-rssi",
+			Usage:       "The minimum signal strength of a sighting for a device to arrive.",
+			Required:    false,
+			DefaultText: strconv.Itoa(presence.DefaultArriveRSSI),
+			Value:       presence.DefaultArriveRSSI,
+			EnvVars:     []string{"BRESTD_PRESENCE_ARRIVE_RSSI"},
+		},
+		&cli.IntFlag{
+			Name:        "presence-depart-rssi",
+			Usage:       "The minimum signal strength of a sighting to keep a device present. It must not be higher than 'presence-arrive-rssi'.",
+			Required:    false,
+			DefaultText: strconv.Itoa(presence.DefaultDepartRSSI),
+			Value:       presence.DefaultDepartRSSI,
+			EnvVars:     []string{"BRESTD_PRESENCE_DEPART_RSSI"},
+		},
+		&cli.DurationFlag{
+			Name:        "presence-scan-interval",
+			Usage:       "The interval between each background discovery on all powered adapters, to keep the presence of devices up to date.\nIf this option is zero, no background discovery is performed.",
+			Required:    false,
+			DefaultText: "0s",
+			EnvVars:     []string{"BRESTD_PRESENCE_SCAN_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "presence-scan-duration",
+			Usage:       "The duration of each background discovery.",
+			Required:    false,
+			DefaultText: presence.DefaultScanDuration.String(),
+			Value:       presence.DefaultScanDuration,
+			EnvVars:     []string{"BRESTD_PRESENCE_SCAN_DURATION"},
+		},
+		&cli.IntFlag{
+			Name:        "proximity-history-size",
+			Usage:       "The number of signal strength samples to keep for each device.",
+			Required:    false,
+			DefaultText: strconv.Itoa(proximity.DefaultHistorySize),
+			Value:       proximity.DefaultHistorySize,
+			EnvVars:     []string{"BRESTD_PROXIMITY_HISTORY_SIZE"},
+		},
+		&cli.Float64Flag{
+			Name:        "proximity-smoothing",
+			Usage:       "The weight (between 0 and 1) of each new signal strength sample in the smoothed signal strength.\nLower values smooth the signal strength more, but react slower to changes.",
+			Required:    false,
+			DefaultText: strconv.FormatFloat(proximity.DefaultSmoothing, 'g', -1, 64),
+			Value:       proximity.DefaultSmoothing,
+			EnvVars:     []string{"BRESTD_PROXIMITY_SMOOTHING"},
+		},
+		&cli.IntFlag{
+			Name:        "proximity-immediate-rssi",
+			Usage:       "The minimum smoothed signal strength of a device in the 'immediate' proximity.",
+			Required:    false,
+			DefaultText: strconv.Itoa(proximity.DefaultImmediateRSSI),
+			Value:       proximity.DefaultImmediateRSSI,
+			EnvVars:     []string{"BRESTD_PROXIMITY_IMMEDIATE_RSSI"},
+		},
+		&cli.IntFlag{
+			Name:        "proximity-near-rssi",
+			Usage:       "The minimum smoothed signal strength of a device in the 'near' proximity. Devices with a weaker signal are 'far'.",
+			Required:    false,
+			DefaultText: strconv.Itoa(proximity.DefaultNearRSSI),
+			Value:       proximity.DefaultNearRSSI,
+			EnvVars:     []string{"BRESTD_PROXIMITY_NEAR_RSSI"},
+		},
+		&cli.DurationFlag{
+			Name:        "proximity-gone-timeout",
+			Usage:       "The time after the last sighting of a device, after which it is 'gone'.",
+			Required:    false,
+			DefaultText: proximity.DefaultGoneTimeout.String(),
+			Value:       proximity.DefaultGoneTimeout,
+			EnvVars:     []string{"BRESTD_PROXIMITY_GONE_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:     "oui-file",
+			Usage:    "The path to the IEEE MA-L registry (oui.csv), which is used to look up the vendors of devices.\nIf this option is empty, the embedded table is used, which is partial unless it was generated before building.",
+			Required: false,
+			EnvVars:  []string{"BRESTD_OUI_FILE"},
+		},
+	}
+}
+
 // cmdStart handles the 'launch' command.
 func cmdStart(cliCtx *cli.Context) error {
 	if cliCtx.IsSet("using-default-tcp") || cliCtx.IsSet("using-default-socket") {
@@ -183,30 +552,177 @@ func cmdStart(cliCtx *cli.Context) error {
 	}
 
 Start:
+	if nonInteractive(cliCtx) {
+		pterm.DisableStyling()
+	}
+
+	closeLog, err := newLogger(cliCtx)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
 	spinner := infoSpinner("Starting session")
 
-	tcpaddr := cliCtx.String("tcp-address")
-	sockpath := cliCtx.String("unix-socket")
+	listener, err := newListener(cliCtx)
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
 
-	proto, addr := "tcp", tcpaddr
-	if cliCtx.IsSet("using-default-socket") || (cliCtx.IsSet("unix-socket") && sockpath != "") {
-		proto, addr = "unix", sockpath
+	if path := cliCtx.String("oui-file"); path != "" {
+		if err := deviceinfo.LoadOUI(path); err != nil {
+			return newCmdError(spinner, err)
+		}
 	}
 
-	listener, err := net.Listen(proto, addr)
+	st, err := store.Open(filepath.Join(cliCtx.String("data-dir"), "bluerestd.db"))
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+	defer st.Close()
+
+	deviceRegistry, err := registry.New(st)
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+
+	accessPolicy, err := access.New(st)
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+
+	hub := events.NewHub()
+	setupManager := setup.New(hub, accessPolicy)
+
+	visibilityManager, err := visibility.New(st, hub)
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+
+	reconnectManager, err := reconnect.New(st, hub)
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+
+	auditPath := cliCtx.String("audit-log")
+	if auditPath == "" {
+		auditPath = filepath.Join(cliCtx.String("data-dir"), "audit.jsonl")
+	}
+
+	auditLog, err := audit.Open(audit.Config{
+		Path:       auditPath,
+		MaxSize:    int64(cliCtx.Int("audit-max-size")) << 20,
+		MaxBackups: cliCtx.Int("audit-max-backups"),
+	})
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+	defer auditLog.Close()
+
+	presenceConfig, err := newPresenceConfig(cliCtx)
 	if err != nil {
-		return newCmdError(spinner, fmt.Errorf("Cannot listen on %s '%s': %w", proto, addr, err))
+		return newCmdError(spinner, err)
 	}
 
-	session, features, err := newSession(cliCtx)
+	shutdownTracing, err := newTracing(cliCtx)
 	if err != nil {
 		return newCmdError(spinner, err)
 	}
+	defer shutdownTracing()
+
+	m := metrics.New()
+
+	sup, features, err := newSession(cliCtx, hub, endpoints.NewAuthorizer(m, accessPolicy, setupManager))
+	if err != nil {
+		return newCmdError(spinner, err)
+	}
+
+	m.Start(sup, hub)
+	defer m.Stop()
+
+	hooks := []instrument.Hook{logging.Hook(), m.Hook()}
+	if tracingEnabled(cliCtx) {
+		hooks = append(hooks, tracing.Hook())
+	}
+
+	session := instrument.Wrap(sup, hooks...)
+
+	stateCache := cache.New(session, hub)
+	stateCache.Start()
+	defer stateCache.Stop()
+
+	discoveryManager := discovery.New(session, hub, stateCache)
+	discoveryManager.Start()
+
+	presenceTracker := presence.New(presenceConfig, hub, stateCache, discoveryManager)
+	presenceTracker.Start()
+
+	visibilityManager.Start(session)
+	accessPolicy.Start(session)
+	setupManager.Start(session, discoveryManager)
+	reconnectManager.Start(session, stateCache)
+
+	proximityTracker := proximity.New(proximity.Config{
+		HistorySize:   cliCtx.Int("proximity-history-size"),
+		Smoothing:     cliCtx.Float64("proximity-smoothing"),
+		ImmediateRSSI: int16(cliCtx.Int("proximity-immediate-rssi")),
+		NearRSSI:      int16(cliCtx.Int("proximity-near-rssi")),
+		GoneTimeout:   cliCtx.Duration("proximity-gone-timeout"),
+	}, hub)
+	proximityTracker.Start()
+
+	webhookManager := webhooks.New(webhooks.Config{
+		MaxAttempts:    cliCtx.Int("webhook-max-attempts"),
+		InitialBackoff: cliCtx.Duration("webhook-backoff"),
+		QueueSize:      cliCtx.Int("webhook-queue-size"),
+	}, st, hub)
 
 	router := http.NewServeMux()
-	endpoints.Register(router, session, features)
+	endpoints.Register(router, session, features, endpoints.Options{
+		Hub:        hub,
+		Webhooks:   webhookManager,
+		Metrics:    m,
+		Audit:      auditLog,
+		Cache:      stateCache,
+		Discovery:  discoveryManager,
+		Presence:   presenceTracker,
+		Proximity:  proximityTracker,
+		Registry:   deviceRegistry,
+		Access:     accessPolicy,
+		Setup:      setupManager,
+		Reconnect:  reconnectManager,
+		Visibility: visibilityManager,
+		Version:    Version,
+		Revision:   Revision,
+		Supervisor: sup,
+		Tracing:    tracingEnabled(cliCtx),
+	})
+
+	var bridge *mqttbridge.Bridge
+
+	err = webhookManager.Start()
+	if err == nil {
+		bridge, err = newMQTTBridge(cliCtx, session, hub, discoveryManager, reconnectManager, auditLog)
+	}
+
+	if err == nil {
+		stopNotify := notifyService(sup, hub)
+		err = serve(listener, router, spinner)
+		stopNotify()
+	}
+
+	if bridge != nil {
+		bridge.Stop()
+	}
+
+	webhookManager.Stop()
+	proximityTracker.Stop()
+	setupManager.Stop()
+	reconnectManager.Stop()
+	presenceTracker.Stop()
+	visibilityManager.Stop()
+	discoveryManager.Stop()
 
-	err = serve(listener, router, spinner)
 	if e := session.Stop(); e != nil {
 		err = errors.Join(err, fmt.Errorf("Session shutdown error: %w", e))
 	}
@@ -222,7 +738,7 @@ Start:
 func cmdOpenAPI(cliCtx *cli.Context) error {
 	oldFormat := false
 	apifn := func() *huma.OpenAPI {
-		api := endpoints.Register(http.NewServeMux(), nil, ac.MergedFeatureSet())
+		api := endpoints.Register(http.NewServeMux(), nil, ac.MergedFeatureSet(), endpoints.Options{})
 
 		return api.OpenAPI()
 	}
@@ -283,6 +799,7 @@ func serve(listener net.Listener, router *http.ServeMux, spinner *pterm.SpinnerP
 	errchan := make(chan error, 1)
 	server := &http.Server{
 		BaseContext: func(net.Listener) context.Context { return ctx },
+		ConnContext: audit.ConnContext,
 		Handler:     router,
 	}
 
@@ -335,16 +852,118 @@ func serve(listener net.Listener, router *http.ServeMux, spinner *pterm.SpinnerP
 	return err
 }
 
-// newSession initializes and returns a new session.
-func newSession(cliCtx *cli.Context) (bluetooth.Session, ac.FeatureSet, error) {
-	eventbus.DisableEvents()
+// newListener returns the socket-activated listener if the daemon was started by systemd,
+// or listens on the configured TCP address or UNIX socket.
+func newListener(cliCtx *cli.Context) (net.Listener, error) {
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(listeners) > 0 {
+		for _, l := range listeners[1:] {
+			slog.Warn("Ignoring additional socket-activated listener", "address", l.Addr().String())
+			l.Close()
+		}
+
+		return listeners[0], nil
+	}
+
+	proto, addr := listenAddress(cliCtx)
+
+	listener, err := net.Listen(proto, addr)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot listen on %s '%s': %w", proto, addr, err)
+	}
+
+	return listener, nil
+}
+
+// listenAddress returns the configured protocol and address to listen on.
+func listenAddress(cliCtx *cli.Context) (string, string) {
+	tcpaddr := cliCtx.String("tcp-address")
+	sockpath := cliCtx.String("unix-socket")
+
+	proto, addr := "tcp", tcpaddr
+	if cliCtx.IsSet("using-default-socket") || (cliCtx.IsSet("unix-socket") && sockpath != "") {
+		proto, addr = "unix", sockpath
+	}
+
+	return proto, addr
+}
+
+// notifyService notifies systemd that the daemon is ready, sends watchdog notifications while the
+// supervisor is running (including while it restarts the session), and reports the session state as the service status.
+// The returned function notifies systemd that the daemon is stopping.
+func notifyService(sup *supervisor.Supervisor, hub *events.Hub) func() {
+	if _, err := systemd.Ready(); err != nil {
+		slog.Warn("Cannot notify systemd", "error", err)
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go systemd.Watchdog(ctx, sup.Healthy)
+
+	subscriber := hub.Subscribe(supervisor.EventName)
+
+	go func() {
+		for ev := range subscriber.C {
+			status, ok := ev.Data.(supervisor.Status)
+			if !ok {
+				continue
+			}
+
+			msg := "Session " + string(status.State)
+			if status.Reason != "" {
+				msg += ": " + status.Reason
+			}
+
+			systemd.Status(msg)
+		}
+	}()
+
+	return func() {
+		subscriber.Unsubscribe()
+		cancel()
+		systemd.Stopping()
+	}
+}
+
+// newPresenceConfig returns the presence settings from the provided options.
+func newPresenceConfig(cliCtx *cli.Context) (presence.Config, error) {
+	devices := []bluetooth.MacAddress{}
+
+	for _, address := range cliCtx.StringSlice("presence-devices") {
+		device, err := bluetooth.ParseMAC(address)
+		if err != nil {
+			return presence.Config{}, fmt.Errorf("Invalid presence device address '%s': %w", address, err)
+		}
+
+		devices = append(devices, device)
+	}
+
+	return presence.Config{
+		Devices:      devices,
+		AwayTimeout:  cliCtx.Duration("presence-away-timeout"),
+		ArriveRSSI:   int16(cliCtx.Int("presence-arrive-rssi")),
+		DepartRSSI:   int16(cliCtx.Int("presence-depart-rssi")),
+		ScanInterval: cliCtx.Duration("presence-scan-interval"),
+		ScanDuration: cliCtx.Duration("presence-scan-duration"),
+	}, nil
+}
+
+// newSession initializes and returns a new supervised session.
+// All session events are published to the provided event hub.
+func newSession(cliCtx *cli.Context, hub *events.Hub, authorizer *endpoints.Authorizer) (*supervisor.Supervisor, ac.FeatureSet, error) {
 	cfg := config.New()
 	cfg.AuthTimeout = cliCtx.Duration("auth-timeout") * time.Second
 
-	session := session.NewSession()
+	sup := supervisor.New(session.NewSession, hub, supervisor.Config{
+		CheckInterval: cliCtx.Duration("session-check-interval"),
+		MaxBackoff:    cliCtx.Duration("session-max-backoff"),
+	})
 
-	features, pinfo, err := session.Start(endpoints.NewAuthorizer(), cfg)
+	features, pinfo, err := sup.Start(authorizer, cfg)
 	if err != nil {
 		return nil, features, fmt.Errorf("Session initialization error: %w", err)
 	}
@@ -368,9 +987,106 @@ func newSession(cliCtx *cli.Context) (bluetooth.Session, ac.FeatureSet, error) {
 		pterm.DefaultTree.WithRoot(pterm.TreeNode{Children: nodes}).Render()
 	}
 
+	slog.Info("Session initialized", "stack", pinfo.Stack, "os", pinfo.OS)
+
 	printInfo("Session initialized.")
 	printInfo("Bluetooth stack: %s, OS: %s", pinfo.Stack, pinfo.OS)
 	newline()
 
-	return session, features, nil
+	return sup, features, nil
+}
+
+// nonInteractive returns whether spinners and styled output should be disabled.
+// Output is always non-interactive if the daemon is started by systemd.
+func nonInteractive(cliCtx *cli.Context) bool {
+	return cliCtx.Bool("non-interactive") || os.Getenv("INVOCATION_ID") != ""
+}
+
+// newLogger sets up the default logger.
+// The returned function closes the log file, if any.
+func newLogger(cliCtx *cli.Context) (func() error, error) {
+	logger, closeLog, err := logging.New(logging.Config{
+		Format: cliCtx.String("log-format"),
+		Level:  cliCtx.String("log-level"),
+		File:   cliCtx.String("log-file"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Logger initialization error: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	return closeLog, nil
+}
+
+// tracingEnabled returns whether a trace exporter is specified.
+func tracingEnabled(cliCtx *cli.Context) bool {
+	return cliCtx.String("otel-endpoint") != "" || cliCtx.String("otel-file") != ""
+}
+
+// newTracing sets up the trace exporters, if any are specified.
+// The returned function flushes and stops the exporters.
+func newTracing(cliCtx *cli.Context) (func(), error) {
+	if !tracingEnabled(cliCtx) {
+		return func() {}, nil
+	}
+
+	shutdown, err := tracing.Setup(cliCtx.Context, tracing.Config{
+		Endpoint:       cliCtx.String("otel-endpoint"),
+		File:           cliCtx.String("otel-file"),
+		ServiceVersion: Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Tracing initialization error: %w", err)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdown(ctx); err != nil {
+			printWarn("Tracing shutdown error: %s", err)
+		}
+	}, nil
+}
+
+// newMQTTBridge starts and returns a new MQTT bridge, if an MQTT broker is specified.
+func newMQTTBridge(
+	cliCtx *cli.Context, session bluetooth.Session, hub *events.Hub,
+	discoveries *discovery.Manager, reconnects *reconnect.Manager, auditLog *audit.Log,
+) (*mqttbridge.Bridge, error) {
+	broker := cliCtx.String("mqtt-broker")
+	if broker == "" {
+		return nil, nil
+	}
+
+	bridge, err := mqttbridge.New(mqttbridge.Config{
+		Broker:      broker,
+		ClientID:    cliCtx.String("mqtt-client-id"),
+		Username:    cliCtx.String("mqtt-username"),
+		Password:    cliCtx.String("mqtt-password"),
+		TopicPrefix: cliCtx.String("mqtt-topic-prefix"),
+		QoS:         byte(cliCtx.Uint("mqtt-qos")),
+		TLSCACert:   cliCtx.String("mqtt-tls-ca"),
+		TLSCert:     cliCtx.String("mqtt-tls-cert"),
+		TLSKey:      cliCtx.String("mqtt-tls-key"),
+		TLSInsecure: cliCtx.Bool("mqtt-tls-insecure"),
+
+		HomeAssistant:   cliCtx.Bool("mqtt-homeassistant"),
+		DiscoveryPrefix: cliCtx.String("mqtt-homeassistant-prefix"),
+		Discovery:       discoveries,
+		Reconnect:       reconnects,
+		Audit:           auditLog,
+	}, session, hub)
+	if err != nil {
+		return nil, fmt.Errorf("MQTT bridge initialization error: %w", err)
+	}
+
+	if err := bridge.Start(); err != nil {
+		return nil, fmt.Errorf("MQTT bridge startup error: %w", err)
+	}
+
+	printInfo("MQTT bridge started, using broker '%s'.", broker)
+
+	return bridge, nil
 }
@@ -25,6 +25,10 @@ func errorSpinner(spinner *pterm.SpinnerPrinter, err error) error {
 
 // clearSpinner clears the spinner's text.
 func clearSpinner(spinner *pterm.SpinnerPrinter) {
+	if pterm.RawOutput {
+		return
+	}
+
 	updateSpinner(spinner, "%s", strings.Repeat(" ", len(spinner.Text)))
 }
 
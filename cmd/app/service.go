@@ -0,0 +1,192 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/systemd"
+	"github.com/urfave/cli/v2"
+)
+
+// serviceName is the name of the generated units.
+const serviceName = "bluerestd"
+
+// serviceExcludedFlags holds the 'launch' options which are not passed to the service,
+// since the listening address is configured in the socket unit, and the output is always non-interactive.
+var serviceExcludedFlags = map[string]struct{}{
+	"tcp-address":          {},
+	"unix-socket":          {},
+	"using-default-tcp":    {},
+	"using-default-socket": {},
+	"non-interactive":      {},
+	"data-dir":             {},
+}
+
+// serviceSecretFlags holds the 'launch' options which are not written to the units,
+// since unit files are readable by other users.
+var serviceSecretFlags = map[string]string{
+	"mqtt-password": "BRESTD_MQTT_PASSWORD",
+}
+
+// servicePathFlags holds the 'launch' options of files which the daemon writes to.
+var servicePathFlags = []string{"log-file", "audit-log", "otel-file"}
+
+// cmdServiceInstall handles the 'service install' command.
+func cmdServiceInstall(cliCtx *cli.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Cannot find the daemon's executable: %w", err)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	system := cliCtx.Bool("system")
+
+	proto, addr := listenAddress(cliCtx)
+	if addr, err = absPath(addr, proto == "unix"); err != nil {
+		return err
+	}
+
+	unit := systemd.Unit{
+		Name:         serviceName,
+		Description:  "Bluetooth REST API daemon",
+		ExecStart:    []string{exe, "launch"},
+		ListenStream: addr,
+		WatchdogSec:  cliCtx.Duration("watchdog-sec"),
+		User:         !system,
+	}
+
+	for _, flag := range launchFlags() {
+		name := flag.Names()[0]
+		if _, ok := serviceExcludedFlags[name]; ok || !cliCtx.IsSet(name) {
+			continue
+		}
+
+		if env, ok := serviceSecretFlags[name]; ok {
+			printWarn("The '--%s' option is not written to the unit. Set the '%s' environment variable using 'systemctl edit' instead.", name, env)
+
+			continue
+		}
+
+		value := fmt.Sprint(cliCtx.Value(name))
+		if d, ok := cliCtx.Value(name).(time.Duration); ok {
+			value = d.String()
+		}
+
+		if slices.Contains(servicePathFlags, name) {
+			if value, err = absPath(value, true); err != nil {
+				return err
+			}
+
+			unit.ReadWritePaths = append(unit.ReadWritePaths, filepath.Dir(value))
+		}
+
+		unit.ExecStart = append(unit.ExecStart, "--"+name+"="+value)
+	}
+
+	// The system service stores its state in its state directory, unless a data directory is specified.
+	// The user service uses the same default data directory as the 'launch' command.
+	switch {
+	case cliCtx.IsSet("data-dir"):
+		dataDir, err := absPath(cliCtx.String("data-dir"), true)
+		if err != nil {
+			return err
+		}
+
+		unit.ExecStart = append(unit.ExecStart, "--data-dir="+dataDir)
+		unit.ReadWritePaths = append(unit.ReadWritePaths, dataDir)
+
+	case system:
+		unit.StateDirectory = true
+	}
+
+	service, err := unit.Service()
+	if err != nil {
+		return err
+	}
+
+	socket, err := unit.Socket()
+	if err != nil {
+		return err
+	}
+
+	if cliCtx.Bool("print") {
+		fmt.Printf("# %s.service\n%s\n# %s.socket\n%s", serviceName, service, serviceName, socket)
+
+		return nil
+	}
+
+	dir := cliCtx.String("output-dir")
+	if dir == "" {
+		if dir, err = unitDirectory(system); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("Cannot create the unit directory: %w", err)
+	}
+
+	for name, contents := range map[string]string{
+		serviceName + ".service": service,
+		serviceName + ".socket":  socket,
+	} {
+		path := filepath.Join(dir, name)
+
+		if _, err := os.Stat(path); err == nil && !cliCtx.Bool("force") {
+			return fmt.Errorf("The unit '%s' already exists, use '--force' to overwrite it.", path)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			return fmt.Errorf("Cannot write the unit '%s': %w", path, err)
+		}
+
+		printInfo("Installed '%s'.", path)
+	}
+
+	scope := "--user "
+	if system {
+		scope = ""
+	}
+
+	printNote("Run 'systemctl %sdaemon-reload && systemctl %senable --now %s.socket' to start the daemon.", scope, scope, serviceName)
+
+	return nil
+}
+
+// unitDirectory returns the directory of the user or system units.
+func unitDirectory(system bool) (string, error) {
+	if system {
+		return "/etc/systemd/system", nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("Cannot find the user unit directory: %w", err)
+	}
+
+	return filepath.Join(dir, "systemd", "user"), nil
+}
+
+// absPath returns the absolute form of a path, if it is the path of a file.
+func absPath(path string, isFile bool) (string, error) {
+	if !isFile || filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("Cannot resolve the path '%s': %w", path, err)
+	}
+
+	return abs, nil
+}
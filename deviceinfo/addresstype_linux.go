@@ -0,0 +1,81 @@
+package deviceinfo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	// bluezService is the DBus name of the Bluetooth service.
+	bluezService = "org.bluez"
+
+	// deviceInterface is the DBus interface of the devices of the Bluetooth service.
+	deviceInterface = "org.bluez.Device1"
+)
+
+// addressTypeReload is the minimum time between two reloads of the address types,
+// so that looking up unknown devices does not query the Bluetooth service each time.
+const addressTypeReload = time.Second
+
+// addressTypes caches the address types of the devices of the Bluetooth service, which do not change.
+var addressTypes struct {
+	types    map[bluetooth.MacAddress]string
+	loadedAt time.Time
+	mu       sync.Mutex
+}
+
+// deviceAddressType returns the type ("public" or "random") of a device address, from its "AddressType"
+// property in the Bluetooth service. If the device is not cached, the address types of all devices are reloaded.
+func deviceAddressType(address bluetooth.MacAddress) string {
+	addressTypes.mu.Lock()
+	defer addressTypes.mu.Unlock()
+
+	if typ, ok := addressTypes.types[address]; ok || time.Since(addressTypes.loadedAt) < addressTypeReload {
+		return typ
+	}
+
+	addressTypes.loadedAt = time.Now()
+
+	types, err := loadAddressTypes()
+	if err != nil {
+		return ""
+	}
+
+	addressTypes.types = types
+
+	return types[address]
+}
+
+// loadAddressTypes returns the address types of all devices of the Bluetooth service, by their address.
+func loadAddressTypes() (map[bluetooth.MacAddress]string, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	if err := conn.Object(bluezService, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects); err != nil {
+		return nil, err
+	}
+
+	types := make(map[bluetooth.MacAddress]string)
+
+	for _, interfaces := range objects {
+		properties, ok := interfaces[deviceInterface]
+		if !ok {
+			continue
+		}
+
+		address, _ := properties["Address"].Value().(string)
+		typ, _ := properties["AddressType"].Value().(string)
+
+		if mac, err := bluetooth.ParseMAC(address); err == nil && typ != "" {
+			types[mac] = typ
+		}
+	}
+
+	return types, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package deviceinfo
+
+import "github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+
+// deviceAddressType returns an empty string, since the type of a device address is only known on Linux.
+func deviceAddressType(bluetooth.MacAddress) string {
+	return ""
+}
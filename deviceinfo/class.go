@@ -0,0 +1,238 @@
+package deviceinfo
+
+// Class describes a decoded Bluetooth Class of Device.
+type Class struct {
+	Major    string   `doc:"The major device class." example:"Audio/Video" json:"major"`
+	Minor    string   `doc:"The minor device class, if it is known." example:"Headphones" json:"minor,omitempty"`
+	Services []string `doc:"The major service classes." example:"[\"Rendering\", \"Audio\"]" json:"services,omitempty"`
+	Icon     string   `doc:"A hint for the icon of the device, as a freedesktop icon name." example:"audio-headphones" json:"icon,omitempty"`
+}
+
+// serviceClasses maps the bits of the major service classes to their names.
+var serviceClasses = []struct {
+	bit  uint
+	name string
+}{
+	{13, "Limited Discoverable Mode"},
+	{14, "LE Audio"},
+	{16, "Positioning"},
+	{17, "Networking"},
+	{18, "Rendering"},
+	{19, "Capturing"},
+	{20, "Object Transfer"},
+	{21, "Audio"},
+	{22, "Telephony"},
+	{23, "Information"},
+}
+
+// majorClasses maps the major device classes to their names.
+var majorClasses = map[uint32]string{
+	0x00: "Miscellaneous",
+	0x01: "Computer",
+	0x02: "Phone",
+	0x03: "Network Access Point",
+	0x04: "Audio/Video",
+	0x05: "Peripheral",
+	0x06: "Imaging",
+	0x07: "Wearable",
+	0x08: "Toy",
+	0x09: "Health",
+	0x1f: "Uncategorized",
+}
+
+// minorClasses maps the minor device classes of each major device class
+// (except the peripheral, imaging and network classes, which are bit fields) to their names.
+var minorClasses = map[uint32][]string{
+	0x01: {"", "Desktop", "Server", "Laptop", "Handheld PC/PDA", "Palm-size PC/PDA", "Wearable computer", "Tablet"},
+	0x02: {"", "Cellular", "Cordless", "Smartphone", "Modem or voice gateway", "ISDN access"},
+	0x04: {
+		"", "Headset", "Hands-free", "", "Microphone", "Loudspeaker", "Headphones", "Portable audio",
+		"Car audio", "Set-top box", "HiFi audio", "VCR", "Video camera", "Camcorder", "Video monitor",
+		"Video display and loudspeaker", "Video conferencing", "", "Gaming/Toy",
+	},
+	0x07: {"", "Wristwatch", "Pager", "Jacket", "Helmet", "Glasses", "Pin"},
+	0x08: {"", "Robot", "Vehicle", "Doll/Action figure", "Controller", "Game"},
+	0x09: {
+		"", "Blood pressure monitor", "Thermometer", "Weighing scale", "Glucose meter", "Pulse oximeter",
+		"Heart/Pulse rate monitor", "Health data display", "Step counter", "Body composition analyzer",
+		"Peak flow monitor", "Medication monitor", "Knee prosthesis", "Ankle prosthesis",
+		"Generic health manager", "Personal mobility device",
+	},
+}
+
+// peripheralTypes holds the names of the peripheral device types (bits 2 to 5 of the minor class).
+var peripheralTypes = []string{
+	"", "Joystick", "Gamepad", "Remote control", "Sensing device", "Digitizer tablet",
+	"Card reader", "Digital pen", "Handheld scanner", "Gestural input device",
+}
+
+// networkLoads holds the names of the utilization levels of a network access point (bits 5 to 7 of the minor class).
+var networkLoads = []string{
+	"Fully available", "1% to 17% utilized", "17% to 33% utilized", "33% to 50% utilized",
+	"50% to 67% utilized", "67% to 83% utilized", "83% to 99% utilized", "No service available",
+}
+
+// DecodeClass decodes a Class of Device. If the class is zero, nil is returned.
+func DecodeClass(class uint32) *Class {
+	if class == 0 {
+		return nil
+	}
+
+	major := (class >> 8) & 0x1f
+	minor := (class >> 2) & 0x3f
+
+	decoded := &Class{
+		Major: majorClasses[major],
+		Minor: minorClass(major, minor),
+		Icon:  icon(major, minor),
+	}
+	if decoded.Major == "" {
+		decoded.Major = "Reserved"
+	}
+
+	for _, service := range serviceClasses {
+		if class&(1<<service.bit) != 0 {
+			decoded.Services = append(decoded.Services, service.name)
+		}
+	}
+
+	return decoded
+}
+
+// minorClass returns the name of the minor class of a major class, if it is known.
+func minorClass(major, minor uint32) string {
+	switch major {
+	case 0x03:
+		return networkLoads[minor>>3]
+
+	case 0x05:
+		kind := []string{"", "Keyboard", "Pointing device", "Keyboard and pointing device"}[minor>>4]
+
+		device := ""
+		if t := minor & 0x0f; int(t) < len(peripheralTypes) {
+			device = peripheralTypes[t]
+		}
+
+		switch {
+		case kind == "":
+			return device
+
+		case device == "":
+			return kind
+		}
+
+		return kind + " (" + device + ")"
+
+	case 0x06:
+		var kinds []string
+
+		for bit, name := range []string{"Display", "Camera", "Scanner", "Printer"} {
+			if minor&(1<<(bit+2)) != 0 {
+				kinds = append(kinds, name)
+			}
+		}
+
+		return joinNames(kinds)
+	}
+
+	if names := minorClasses[major]; int(minor) < len(names) {
+		return names[minor]
+	}
+
+	return ""
+}
+
+// icon returns the freedesktop icon name of a device class, if any.
+// This is adapted from the icon selection of BlueZ.
+func icon(major, minor uint32) string {
+	switch major {
+	case 0x01:
+		return "computer"
+
+	case 0x02:
+		if minor == 0x04 {
+			return "modem"
+		}
+
+		return "phone"
+
+	case 0x03:
+		return "network-wireless"
+
+	case 0x04:
+		switch minor {
+		case 0x01, 0x02:
+			return "audio-headset"
+
+		case 0x06:
+			return "audio-headphones"
+
+		case 0x0b, 0x0c, 0x0d:
+			return "camera-video"
+
+		case 0x0e, 0x0f:
+			return "video-display"
+
+		case 0x12:
+			return "input-gaming"
+		}
+
+		return "audio-card"
+
+	case 0x05:
+		switch minor >> 4 {
+		case 0x01, 0x03:
+			return "input-keyboard"
+
+		case 0x02:
+			if minor&0x0f == 0x05 {
+				return "input-tablet"
+			}
+
+			return "input-mouse"
+		}
+
+		switch minor & 0x0f {
+		case 0x01, 0x02:
+			return "input-gaming"
+
+		case 0x05:
+			return "input-tablet"
+		}
+
+	case 0x06:
+		switch {
+		case minor&0x20 != 0:
+			return "printer"
+
+		case minor&0x10 != 0:
+			return "scanner"
+
+		case minor&0x08 != 0:
+			return "camera-photo"
+
+		case minor&0x04 != 0:
+			return "video-display"
+		}
+	}
+
+	return "bluetooth"
+}
+
+// joinNames joins the names with commas and a final "and".
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+
+	case 1:
+		return names[0]
+	}
+
+	joined := names[0]
+	for _, name := range names[1 : len(names)-1] {
+		joined += ", " + name
+	}
+
+	return joined + " and " + names[len(names)-1]
+}
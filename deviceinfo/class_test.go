@@ -0,0 +1,46 @@
+package deviceinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		class uint32
+		want  *Class
+	}{
+		{name: "zero", class: 0, want: nil},
+		{
+			name:  "headphones",
+			class: 0x240418,
+			want:  &Class{Major: "Audio/Video", Minor: "Headphones", Services: []string{"Rendering", "Audio"}, Icon: "audio-headphones"},
+		},
+		{
+			name:  "smartphone",
+			class: 0x5a020c,
+			want: &Class{
+				Major: "Phone", Minor: "Smartphone", Icon: "phone",
+				Services: []string{"Networking", "Capturing", "Object Transfer", "Telephony"},
+			},
+		},
+		{name: "laptop", class: 0x10010c, want: &Class{Major: "Computer", Minor: "Laptop", Services: []string{"Object Transfer"}, Icon: "computer"}},
+		{name: "keyboard", class: 0x000540, want: &Class{Major: "Peripheral", Minor: "Keyboard", Icon: "input-keyboard"}},
+		{name: "gamepad", class: 0x000508, want: &Class{Major: "Peripheral", Minor: "Gamepad", Icon: "input-gaming"}},
+		{name: "combo with tablet", class: 0x0005d4, want: &Class{Major: "Peripheral", Minor: "Keyboard and pointing device (Digitizer tablet)", Icon: "input-keyboard"}},
+		{name: "printer and scanner", class: 0x0006c0, want: &Class{Major: "Imaging", Minor: "Scanner and Printer", Icon: "printer"}},
+		{name: "network access point", class: 0x020340, want: &Class{Major: "Network Access Point", Minor: "17% to 33% utilized", Services: []string{"Networking"}, Icon: "network-wireless"}},
+		{name: "wristwatch", class: 0x000704, want: &Class{Major: "Wearable", Minor: "Wristwatch", Icon: "bluetooth"}},
+		{name: "reserved major class", class: 0x000b00, want: &Class{Major: "Reserved", Icon: "bluetooth"}},
+		{name: "unknown minor class", class: 0x0004fc, want: &Class{Major: "Audio/Video", Icon: "audio-card"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DecodeClass(test.class); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("DecodeClass(%#06x) = %+v, want %+v", test.class, got, test.want)
+			}
+		})
+	}
+}
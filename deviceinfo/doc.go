@@ -0,0 +1,12 @@
+/*
+Package deviceinfo decodes the Bluetooth Class of Device into its major, minor and service classes,
+and looks up the vendor of a device from the IEEE OUI (Organizationally Unique Identifier) of its address.
+
+The OUI table is embedded from oui.csv.gz. The committed table is partial, and only holds the OUIs of common
+Bluetooth vendors; running "go generate" replaces it with the complete IEEE MA-L registry, which it downloads.
+The table can also be replaced at runtime with a downloaded copy of the registry (oui.csv) using LoadOUI.
+
+On Linux, whether an address is random is looked up from the address type of the device in BlueZ.
+On other systems, the address type is not known, and it is not reported.
+*/
+package deviceinfo
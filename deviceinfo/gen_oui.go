@@ -0,0 +1,129 @@
+//go:build ignore
+
+// gen_oui downloads the IEEE MA-L registry (oui.csv), and writes its registry, assignment and organization name
+// columns to the gzip-compressed oui.csv.gz file, which is embedded by the package. Run it with "go generate".
+//
+// Use the -in flag to convert an already downloaded copy of the registry instead.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// registryURL is the URL of the IEEE MA-L registry.
+const registryURL = "https://standards-oui.ieee.org/oui/oui.csv"
+
+func main() {
+	in := flag.String("in", "", "The path to a downloaded copy of the registry. If it is empty, the registry is downloaded.")
+	out := flag.String("out", "oui.csv.gz", "The path of the generated table.")
+	flag.Parse()
+
+	if err := generate(*in, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate reads the registry from the path or the registry URL, and writes the table to the output path.
+func generate(in, out string) error {
+	var registry io.Reader
+
+	if in != "" {
+		file, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		registry = file
+	} else {
+		resp, err := http.Get(registryURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("cannot download %s: %s", registryURL, resp.Status)
+		}
+
+		registry = resp.Body
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	compressed := gzip.NewWriter(file)
+
+	count, err := convert(registry, compressed)
+	if err != nil {
+		return err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote %d OUIs to %s", count, out)
+
+	return file.Close()
+}
+
+// convert writes the registry, assignment and organization name columns of the registry,
+// and returns the number of written OUIs.
+func convert(registry io.Reader, w io.Writer) (int, error) {
+	reader := csv.NewReader(registry)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	writer := csv.NewWriter(w)
+	count := -1
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		if count < 0 && (len(record) < 3 || !strings.EqualFold(record[1], "Assignment")) {
+			return 0, errors.New("missing the assignment column")
+		}
+
+		if len(record) < 3 {
+			continue
+		}
+
+		if count >= 0 {
+			record[1] = strings.ToUpper(record[1])
+		}
+
+		if err := writer.Write([]string{record[0], record[1], strings.TrimSpace(record[2])}); err != nil {
+			return 0, err
+		}
+
+		count++
+	}
+
+	if count < 0 {
+		return 0, errors.New("the registry is empty")
+	}
+
+	writer.Flush()
+
+	return count, writer.Error()
+}
@@ -0,0 +1,130 @@
+package deviceinfo
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// Vendor describes the vendor of a device address.
+type Vendor struct {
+	OUI    string `doc:"The OUI of the address." example:"00:1B:66" json:"oui"`
+	Name   string `doc:"The name of the organization the OUI is assigned to, if it is known." example:"Sennheiser electronic GmbH & Co. KG" json:"name,omitempty"`
+	Random *bool  `doc:"Whether the address is a random address, which is not assigned to a vendor. It is only included if the type of the address is known, which is only the case on Linux (from BlueZ)." json:"random,omitempty"`
+}
+
+// embeddedOUI holds the gzip-compressed, embedded OUI table, in the format of the IEEE MA-L registry (oui.csv).
+// The committed table is partial, and only holds the OUIs of common Bluetooth vendors. It is replaced with the
+// complete registry by running "go generate", which downloads it.
+//
+//go:generate go run gen_oui.go
+//go:embed oui.csv.gz
+var embeddedOUI []byte
+
+// addressType returns the type ("public" or "random") of a device address, or an empty string if it is not known.
+var addressType = deviceAddressType
+
+// vendors holds the names of the organizations, by their OUI (as 6 upper-case hexadecimal digits).
+var vendors atomic.Pointer[map[string]string]
+
+func init() {
+	decompressed, err := gzip.NewReader(bytes.NewReader(embeddedOUI))
+	if err != nil {
+		panic(fmt.Errorf("embedded OUI table: %w", err))
+	}
+
+	table, err := parseOUI(decompressed)
+	if err != nil {
+		panic(fmt.Errorf("embedded OUI table: %w", err))
+	}
+
+	vendors.Store(&table)
+}
+
+// LoadOUI replaces the OUI table with the IEEE MA-L registry (oui.csv) at the path.
+func LoadOUI(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	table, err := parseOUI(file)
+	if err != nil {
+		return fmt.Errorf("OUI table %s: %w", path, err)
+	}
+
+	vendors.Store(&table)
+
+	return nil
+}
+
+// LookupVendor returns the vendor of an address. If the address is zero, nil is returned.
+//
+// The address is only reported as random or not if its type is known. Since the OUI of a random address
+// is not assigned to a vendor, random addresses have no vendor name.
+func LookupVendor(address bluetooth.MacAddress) *Vendor {
+	if address == (bluetooth.MacAddress{}) {
+		return nil
+	}
+
+	vendor := &Vendor{OUI: strings.ToUpper(address.String()[:8])}
+
+	if typ := addressType(address); typ != "" {
+		random := typ == "random"
+		vendor.Random = &random
+	}
+
+	if vendor.Random == nil || !*vendor.Random {
+		vendor.Name = (*vendors.Load())[strings.ReplaceAll(vendor.OUI, ":", "")]
+	}
+
+	return vendor
+}
+
+// parseOUI parses an OUI table in the format of the IEEE MA-L registry, whose columns are
+// the registry, the assignment (the OUI), the organization name and the organization address.
+func parseOUI(r io.Reader) (map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) < 3 || !strings.EqualFold(header[1], "Assignment") {
+		return nil, errors.New("missing the assignment column")
+	}
+
+	table := make(map[string]string)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 3 || len(record[1]) != 6 {
+			continue
+		}
+
+		table[strings.ToUpper(record[1])] = strings.TrimSpace(record[2])
+	}
+
+	return table, nil
+}
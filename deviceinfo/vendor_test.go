@@ -0,0 +1,97 @@
+package deviceinfo
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+func TestParseOUI(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "registry",
+			csv: "Registry,Assignment,Organization Name,Organization Address\n" +
+				"MA-L,001B66,Sennheiser electronic GmbH & Co. KG,Am Labor 1 Wedemark DE 30900\n" +
+				"MA-L,00025b,\"Cambridge Silicon Radio\",\n",
+			want: map[string]string{
+				"001B66": "Sennheiser electronic GmbH & Co. KG",
+				"00025B": "Cambridge Silicon Radio",
+			},
+		},
+		{
+			name: "invalid rows",
+			csv: "Registry,Assignment,Organization Name,Organization Address\n" +
+				"MA-L,001B6,Too Short,\n" +
+				"MA-L,001B66\n" +
+				"MA-L,0002C7,\" ALPS ELECTRIC CO., LTD. \",\n",
+			want: map[string]string{"0002C7": "ALPS ELECTRIC CO., LTD."},
+		},
+		{name: "header only", csv: "Registry,Assignment,Organization Name\n", want: map[string]string{}},
+		{name: "missing assignment column", csv: "OUI,Name\n001B66,Sennheiser\n", wantErr: true},
+		{name: "empty", csv: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseOUI(strings.NewReader(test.csv))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseOUI() error = %v, want error %v", err, test.wantErr)
+			}
+
+			if !test.wantErr && !maps.Equal(got, test.want) {
+				t.Fatalf("parseOUI() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLookupVendor(t *testing.T) {
+	public, random := false, true
+
+	types := map[string]string{
+		"00:1B:66:01:02:03": "public",
+		"02:11:22:33:44:55": "public",
+		"00:1B:66:33:44:55": "random",
+		"D4:11:22:33:44:55": "random",
+	}
+
+	defer func(lookup func(bluetooth.MacAddress) string) { addressType = lookup }(addressType)
+	addressType = func(address bluetooth.MacAddress) string {
+		return types[address.String()]
+	}
+
+	tests := []struct {
+		address string
+		want    *Vendor
+	}{
+		{address: "00:00:00:00:00:00", want: nil},
+		{address: "00:1B:66:01:02:03", want: &Vendor{OUI: "00:1B:66", Name: "Sennheiser electronic GmbH & Co. KG", Random: &public}},
+		{address: "00:1B:66:0A:0B:0C", want: &Vendor{OUI: "00:1B:66", Name: "Sennheiser electronic GmbH & Co. KG"}},
+		{address: "02:11:22:33:44:55", want: &Vendor{OUI: "02:11:22", Random: &public}},
+		{address: "00:1B:66:33:44:55", want: &Vendor{OUI: "00:1B:66", Random: &random}},
+		{address: "D4:11:22:33:44:55", want: &Vendor{OUI: "D4:11:22", Random: &random}},
+		{address: "4A:11:22:33:44:55", want: &Vendor{OUI: "4A:11:22"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			address, err := bluetooth.ParseMAC(test.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := LookupVendor(address)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("LookupVendor(%s) = %+v, want %+v", test.address, got, test.want)
+			}
+		})
+	}
+}
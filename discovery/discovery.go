@@ -0,0 +1,437 @@
+package discovery
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/godbus/dbus/v5"
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned when a discovery session does not exist.
+var ErrSessionNotFound = errors.New("discovery session not found")
+
+// Filter describes the devices that are included in the results of a discovery session.
+type Filter struct {
+	Name    string   `doc:"Only include devices whose name or alias contains this text (case-insensitive)." json:"name,omitempty"`
+	UUIDs   []string `doc:"Only include devices which support any of these service profile UUIDs." json:"uuids,omitempty"`
+	Class   uint32   `doc:"Only include devices with this device class." json:"class,omitempty"`
+	MinRSSI int16    `doc:"Only include devices whose signal strength is at least this value." json:"min_rssi,omitempty"`
+}
+
+// Session describes a discovery session.
+type Session struct {
+	CreatedAt time.Time            `doc:"The time at which the session was created." json:"created_at"`
+	ExpiresAt time.Time            `doc:"The time at which the session expires." json:"expires_at"`
+	ID        string               `doc:"The ID of the session." json:"discovery_id"`
+	Owner     string               `doc:"The identity of the caller which created the session." json:"owner,omitempty"`
+	Adapter   bluetooth.MacAddress `doc:"The address of the adapter." json:"adapter"`
+	Filter    Filter               `doc:"The filter of the discovered devices." json:"filter"`
+}
+
+// Manager manages the discovery sessions of all adapters.
+type Manager struct {
+	session    bluetooth.Session
+	hub        *events.Hub
+	cache      *cache.Cache
+	subscriber *events.Subscriber
+	done       chan struct{}
+
+	sessions map[string]*activeSession
+	started  map[bluetooth.MacAddress]struct{}
+	mu       sync.Mutex
+}
+
+// activeSession holds a discovery session and the devices it has discovered.
+type activeSession struct {
+	Session
+
+	seen  map[bluetooth.MacAddress]struct{}
+	timer *time.Timer
+	ended chan struct{}
+}
+
+// New returns a new discovery session manager. Use (*Manager).Start() to start tracking the discovered devices.
+func New(session bluetooth.Session, hub *events.Hub, c *cache.Cache) *Manager {
+	return &Manager{
+		session:  session,
+		hub:      hub,
+		cache:    c,
+		done:     make(chan struct{}),
+		sessions: make(map[string]*activeSession),
+		started:  make(map[bluetooth.MacAddress]struct{}),
+	}
+}
+
+// Start starts tracking the devices that are discovered by the discovery sessions.
+func (m *Manager) Start() {
+	m.subscriber = m.hub.Subscribe("device", supervisor.EventName)
+
+	go m.watch()
+}
+
+// Stop ends all discovery sessions, and stops tracking the discovered devices.
+func (m *Manager) Stop() {
+	if m == nil || m.subscriber == nil {
+		return
+	}
+
+	m.mu.Lock()
+	for id := range m.sessions {
+		m.end(id)
+	}
+	m.mu.Unlock()
+
+	m.subscriber.Unsubscribe()
+	<-m.done
+}
+
+// Create creates a new discovery session on an adapter, which expires after the provided duration.
+// The device discovery of the adapter is started if it is the first session of the adapter,
+// and the discovery is not already running (for example, if it was enabled with the adapter states).
+func (m *Manager) Create(adapter bluetooth.MacAddress, duration time.Duration, filter Filter, owner string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active(adapter) {
+		if err := m.startDiscovery(adapter); err != nil {
+			return Session{}, err
+		}
+	}
+
+	now := time.Now()
+	s := &activeSession{
+		Session: Session{
+			CreatedAt: now,
+			ExpiresAt: now.Add(duration),
+			ID:        uuid.NewString(),
+			Owner:     owner,
+			Adapter:   adapter,
+			Filter:    filter,
+		},
+		seen:  make(map[bluetooth.MacAddress]struct{}),
+		ended: make(chan struct{}),
+	}
+
+	s.timer = time.AfterFunc(duration, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.sessions[s.ID]; ok {
+			slog.Info("Discovery session expired", "discovery_id", s.ID, "adapter", adapter.String())
+			m.end(s.ID)
+		}
+	})
+
+	m.sessions[s.ID] = s
+
+	slog.Info("Discovery session created",
+		"discovery_id", s.ID, "adapter", adapter.String(), "owner", owner, "duration", duration,
+	)
+
+	return s.Session, nil
+}
+
+// Remove ends a discovery session of an adapter, if it was created by the owner. The device discovery
+// of the adapter is stopped if it was the last session of the adapter, and it was started by the manager.
+func (m *Manager) Remove(adapter bluetooth.MacAddress, id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.sessions[id]; !ok || s.Adapter != adapter || !s.ownedBy(owner) {
+		return ErrSessionNotFound
+	}
+
+	return m.end(id)
+}
+
+// Sessions returns the discovery sessions of an adapter which were created by the owner.
+func (m *Manager) Sessions(adapter bluetooth.MacAddress, owner string) []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := []Session{}
+	for _, s := range m.sessions {
+		if s.Adapter == adapter && s.ownedBy(owner) {
+			sessions = append(sessions, s.Session)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return sessions
+}
+
+// Active returns whether the adapter has any discovery sessions.
+func (m *Manager) Active(adapter bluetooth.MacAddress) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active(adapter)
+}
+
+// Results returns the devices that were discovered by a discovery session of the owner and match its filter,
+// with the strongest signals first.
+func (m *Manager) Results(adapter bluetooth.MacAddress, id, owner string) ([]bluetooth.DeviceData, error) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok || s.Adapter != adapter || !s.ownedBy(owner) {
+		m.mu.Unlock()
+
+		return nil, ErrSessionNotFound
+	}
+
+	seen := make([]bluetooth.MacAddress, 0, len(s.seen))
+	for address := range s.seen {
+		seen = append(seen, address)
+	}
+	m.mu.Unlock()
+
+	results := []bluetooth.DeviceData{}
+	for _, address := range seen {
+		if device, ok := m.device(address, nil); ok && s.Filter.Matches(device) {
+			results = append(results, device)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b bluetooth.DeviceData) int {
+		return cmp.Or(
+			cmp.Compare(b.RSSI, a.RSSI),
+			slices.Compare(a.Address[:], b.Address[:]),
+		)
+	})
+
+	return results, nil
+}
+
+// Watch calls the provided function with each discovered device of a discovery session of the owner that matches its filter,
+// until the session ends, the context is done or the function returns false. It returns an error if the session does not exist.
+// The devices which were discovered before the session was watched are only included in its results.
+func (m *Manager) Watch(ctx context.Context, adapter bluetooth.MacAddress, id, owner string, fn func(bluetooth.DeviceData) bool) error {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok || s.Adapter != adapter || !s.ownedBy(owner) {
+		m.mu.Unlock()
+
+		return ErrSessionNotFound
+	}
+
+	// The events are subscribed to while the manager is locked, so that no device that is tracked
+	// after the session is looked up is missed.
+	subscriber := m.hub.Subscribe("device")
+	m.mu.Unlock()
+
+	defer subscriber.Unsubscribe()
+
+	for {
+		select {
+		case <-s.ended:
+			return nil
+
+		case <-ctx.Done():
+			return nil
+
+		case ev := <-subscriber.C:
+			data, ok := ev.Data.(bluetooth.Event[bluetooth.DeviceEventData])
+			if !ok || data.Action == bluetooth.EventActionRemoved || data.Data.AssociatedAdapter != adapter {
+				continue
+			}
+
+			if device, ok := m.device(data.Data.Address, &data.Data); ok && s.Filter.Matches(device) {
+				if !fn(device) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Matches returns whether the device matches the filter.
+func (f Filter) Matches(device bluetooth.DeviceData) bool {
+	name := strings.ToLower(f.Name)
+
+	switch {
+	case f.Name != "" && !strings.Contains(strings.ToLower(device.Name), name) && !strings.Contains(strings.ToLower(device.Alias), name),
+		f.Class != 0 && device.Class != f.Class,
+		f.MinRSSI != 0 && (device.RSSI == 0 || device.RSSI < f.MinRSSI):
+		return false
+
+	case len(f.UUIDs) > 0:
+		return slices.ContainsFunc(device.UUIDs, func(u string) bool {
+			return slices.ContainsFunc(f.UUIDs, func(filter string) bool { return strings.EqualFold(u, filter) })
+		})
+	}
+
+	return true
+}
+
+// watch tracks the devices that are discovered by each discovery session, until the manager is stopped.
+// If the Bluetooth session is re-established, the device discovery of each adapter with sessions is started again.
+func (m *Manager) watch() {
+	defer close(m.done)
+
+	for ev := range m.subscriber.C {
+		switch data := ev.Data.(type) {
+		case bluetooth.Event[bluetooth.DeviceEventData]:
+			m.track(data)
+
+		case supervisor.Status:
+			if data.State == supervisor.StateUp {
+				m.resume()
+			}
+		}
+	}
+}
+
+// track adds or removes a device from the discovered devices of its adapter's sessions.
+func (m *Manager) track(ev bluetooth.Event[bluetooth.DeviceEventData]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.Adapter != ev.Data.AssociatedAdapter {
+			continue
+		}
+
+		if ev.Action == bluetooth.EventActionRemoved {
+			delete(s.seen, ev.Data.Address)
+		} else {
+			s.seen[ev.Data.Address] = struct{}{}
+		}
+	}
+}
+
+// resume starts the device discovery of each adapter with sessions.
+func (m *Manager) resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	adapters := make(map[bluetooth.MacAddress]struct{})
+	for _, s := range m.sessions {
+		adapters[s.Adapter] = struct{}{}
+	}
+
+	for adapter := range adapters {
+		delete(m.started, adapter)
+
+		if err := m.startDiscovery(adapter); err != nil {
+			slog.Warn("Cannot resume discovery", "adapter", adapter.String(), "error", err)
+		}
+	}
+}
+
+// startDiscovery starts the device discovery of an adapter, and records whether it was started by the manager.
+// If the discovery is already running, it is left as is. The manager must be locked by the caller.
+func (m *Manager) startDiscovery(adapter bluetooth.MacAddress) error {
+	adapterCall := m.session.Adapter(adapter)
+
+	if properties, err := adapterCall.Properties(); err == nil && properties.Discovering {
+		return nil
+	}
+
+	if err := adapterCall.StartDiscovery(); err != nil {
+		if inProgress(err) {
+			return nil
+		}
+
+		return fmt.Errorf("cannot start discovery: %w", err)
+	}
+
+	m.started[adapter] = struct{}{}
+
+	return nil
+}
+
+// end ends a discovery session, and stops the device discovery of its adapter if it was
+// the adapter's last session. The manager must be locked by the caller.
+func (m *Manager) end(id string) error {
+	s := m.sessions[id]
+
+	s.timer.Stop()
+	close(s.ended)
+	delete(m.sessions, id)
+
+	if m.active(s.Adapter) {
+		return nil
+	}
+
+	if _, ok := m.started[s.Adapter]; !ok {
+		return nil
+	}
+
+	delete(m.started, s.Adapter)
+
+	if err := m.session.Adapter(s.Adapter).StopDiscovery(); err != nil {
+		return fmt.Errorf("cannot stop discovery: %w", err)
+	}
+
+	return nil
+}
+
+// ownedBy returns whether the session was created by the owner. The owners are compared by their principals,
+// so that sessions can be managed from other connections of the same caller.
+func (s *activeSession) ownedBy(owner string) bool {
+	return audit.Principal(s.Owner) == audit.Principal(owner)
+}
+
+// inProgress returns whether the error is the "in progress" error of BlueZ, which is returned
+// if the device discovery was already started by another client.
+func inProgress(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name == "org.bluez.Error.InProgress"
+	}
+
+	var dbusErrPtr *dbus.Error
+
+	return errors.As(err, &dbusErrPtr) && dbusErrPtr.Name == "org.bluez.Error.InProgress"
+}
+
+// active returns whether the adapter has any discovery sessions. The manager must be locked by the caller.
+func (m *Manager) active(adapter bluetooth.MacAddress) bool {
+	for _, s := range m.sessions {
+		if s.Adapter == adapter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// device returns the properties of a device from the cache, or from the session if it is not cached.
+// If event data is provided, it replaces the dynamic properties of the device.
+func (m *Manager) device(address bluetooth.MacAddress, data *bluetooth.DeviceEventData) (bluetooth.DeviceData, bool) {
+	device, _, ok := m.cache.Device(address)
+	if !ok {
+		properties, err := m.session.Device(address).Properties()
+		if err != nil {
+			return device, false
+		}
+
+		device = properties
+	}
+
+	if data != nil {
+		device.DeviceEventData = *data
+	}
+
+	return device, true
+}
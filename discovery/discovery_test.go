@@ -0,0 +1,376 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// testTimeout is the maximum time to wait for an event to be handled.
+const testTimeout = 5 * time.Second
+
+// The owners of the sessions in the tests. The first two owners have the same principal.
+const (
+	owner      = "127.0.0.1:50000"
+	sameOwner  = "127.0.0.1:50001"
+	otherOwner = "192.168.1.2:50000"
+)
+
+// fakeSession is a session with a single adapter, which records the discovery calls.
+// Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	devices     map[bluetooth.MacAddress]bluetooth.DeviceData
+	discovering bool
+	calls       []string
+	mu          sync.Mutex
+}
+
+// fakeAdapter is the adapter of a fakeSession.
+type fakeAdapter struct {
+	bluetooth.Adapter
+
+	session *fakeSession
+}
+
+// fakeDevice is a device of a fakeSession.
+type fakeDevice struct {
+	bluetooth.Device
+
+	session *fakeSession
+	address bluetooth.MacAddress
+}
+
+func (s *fakeSession) Adapter(bluetooth.MacAddress) bluetooth.Adapter {
+	return fakeAdapter{session: s}
+}
+
+func (s *fakeSession) Device(address bluetooth.MacAddress) bluetooth.Device {
+	return fakeDevice{session: s, address: address}
+}
+
+// recorded returns the recorded discovery calls.
+func (s *fakeSession) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.calls...)
+}
+
+func (a fakeAdapter) Properties() (bluetooth.AdapterData, error) {
+	a.session.mu.Lock()
+	defer a.session.mu.Unlock()
+
+	return bluetooth.AdapterData{AdapterEventData: bluetooth.AdapterEventData{Discovering: a.session.discovering}}, nil
+}
+
+func (a fakeAdapter) StartDiscovery() error {
+	a.session.mu.Lock()
+	defer a.session.mu.Unlock()
+
+	a.session.calls = append(a.session.calls, "start")
+	a.session.discovering = true
+
+	return nil
+}
+
+func (a fakeAdapter) StopDiscovery() error {
+	a.session.mu.Lock()
+	defer a.session.mu.Unlock()
+
+	a.session.calls = append(a.session.calls, "stop")
+	a.session.discovering = false
+
+	return nil
+}
+
+func (d fakeDevice) Properties() (bluetooth.DeviceData, error) {
+	d.session.mu.Lock()
+	defer d.session.mu.Unlock()
+
+	device, ok := d.session.devices[d.address]
+	if !ok {
+		return device, errors.New("device not found")
+	}
+
+	return device, nil
+}
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// testAdapter is the address of the adapter in the tests.
+var testAdapter = mac("00:1A:7D:DA:71:13")
+
+// newTestManager starts a discovery manager of a new session, which is stopped when the test finishes.
+func newTestManager(t *testing.T, discovering bool) (*Manager, *fakeSession, *events.Hub) {
+	t.Helper()
+
+	session := &fakeSession{devices: make(map[bluetooth.MacAddress]bluetooth.DeviceData), discovering: discovering}
+	hub := events.NewHub()
+
+	m := New(session, hub, nil)
+	m.Start()
+
+	t.Cleanup(m.Stop)
+
+	return m, session, hub
+}
+
+// discover adds a device to the session, and publishes its event.
+func discover(session *fakeSession, hub *events.Hub, name, address string, rssi int16) {
+	device := bluetooth.DeviceData{
+		Name:            name,
+		DeviceEventData: bluetooth.DeviceEventData{Address: mac(address), AssociatedAdapter: testAdapter, RSSI: rssi},
+	}
+
+	session.mu.Lock()
+	session.devices[device.Address] = device
+	session.mu.Unlock()
+
+	hub.Publish(bluetooth.EventDevice.Value(), "device", bluetooth.Event[bluetooth.DeviceEventData]{
+		Action: bluetooth.EventActionAdded,
+		Data:   device.DeviceEventData,
+	})
+}
+
+// eventually waits until the condition is true.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReferenceCounting(t *testing.T) {
+	m, session, _ := newTestManager(t, false)
+
+	first, err := m.Create(testAdapter, time.Minute, Filter{}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Create(testAdapter, time.Minute, Filter{}, otherOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Remove(testAdapter, first.ID, sameOwner); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"start"}) || !m.Active(testAdapter) {
+		t.Fatalf("calls = %v, want the discovery to run until the last session ends", calls)
+	}
+
+	if err := m.Remove(testAdapter, second.ID, otherOwner); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"start", "stop"}) || m.Active(testAdapter) {
+		t.Fatalf("calls = %v, want the discovery to stop", calls)
+	}
+}
+
+func TestRunningDiscovery(t *testing.T) {
+	m, session, _ := newTestManager(t, true)
+
+	s, err := m.Create(testAdapter, time.Minute, Filter{}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Remove(testAdapter, s.ID, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := session.recorded(); len(calls) != 0 {
+		t.Fatalf("calls = %v, want the running discovery to be left as is", calls)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	m, _, _ := newTestManager(t, false)
+
+	s, err := m.Create(testAdapter, time.Minute, Filter{}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sessions := m.Sessions(testAdapter, otherOwner); len(sessions) != 0 {
+		t.Fatalf("Sessions() of another owner = %+v, want none", sessions)
+	}
+
+	if sessions := m.Sessions(testAdapter, sameOwner); len(sessions) != 1 || sessions[0].ID != s.ID {
+		t.Fatalf("Sessions() = %+v, want the session", sessions)
+	}
+
+	if _, err := m.Results(testAdapter, s.ID, otherOwner); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Results() of another owner = %v, want ErrSessionNotFound", err)
+	}
+
+	err = m.Watch(context.Background(), testAdapter, s.ID, otherOwner, func(bluetooth.DeviceData) bool { return true })
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Watch() of another owner = %v, want ErrSessionNotFound", err)
+	}
+
+	if err := m.Remove(testAdapter, s.ID, otherOwner); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Remove() of another owner = %v, want ErrSessionNotFound", err)
+	}
+
+	if _, err := m.Results(mac("00:1A:7D:DA:71:14"), s.ID, owner); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Results() of another adapter = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestResults(t *testing.T) {
+	m, session, hub := newTestManager(t, false)
+
+	s, err := m.Create(testAdapter, time.Minute, Filter{Name: "phone", MinRSSI: -80}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watched := make(chan string, 8)
+	watching := make(chan error, 1)
+
+	go func() {
+		watching <- m.Watch(context.Background(), testAdapter, s.ID, sameOwner, func(device bluetooth.DeviceData) bool {
+			watched <- device.Name
+
+			return true
+		})
+	}()
+
+	// Wait until the watcher has subscribed to the device events.
+	eventually(t, func() bool { return hub.Subscribers() == 2 })
+
+	discover(session, hub, "Work Phone", "00:1B:66:01:02:03", -70)
+	discover(session, hub, "Speaker", "00:1B:66:04:05:06", -40)
+	discover(session, hub, "Phone", "00:1B:66:07:08:09", -50)
+	discover(session, hub, "Old Phone", "00:1B:66:0A:0B:0C", -90)
+
+	var results []bluetooth.DeviceData
+
+	eventually(t, func() bool {
+		results, _ = m.Results(testAdapter, s.ID, owner)
+
+		return len(results) == 2
+	})
+
+	if results[0].Name != "Phone" || results[1].Name != "Work Phone" {
+		t.Fatalf("Results() = %+v, want the matching devices with the strongest signals first", results)
+	}
+
+	for _, want := range []string{"Work Phone", "Phone"} {
+		select {
+		case name := <-watched:
+			if name != want {
+				t.Fatalf("watched %q, want %q", name, want)
+			}
+
+		case <-time.After(testTimeout):
+			t.Fatalf("%q was not watched", want)
+		}
+	}
+
+	if err := m.Remove(testAdapter, s.ID, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-watching:
+		if err != nil {
+			t.Fatalf("Watch() = %v, want nil after the session ended", err)
+		}
+
+	case <-time.After(testTimeout):
+		t.Fatal("Watch() did not return after the session ended")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	m, session, _ := newTestManager(t, false)
+
+	if _, err := m.Create(testAdapter, 10*time.Millisecond, Filter{}, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool { return !m.Active(testAdapter) })
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"start", "stop"}) {
+		t.Fatalf("calls = %v, want the discovery to stop when the session expires", calls)
+	}
+}
+
+func TestResume(t *testing.T) {
+	m, session, hub := newTestManager(t, false)
+
+	if _, err := m.Create(testAdapter, time.Minute, Filter{}, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	// The discovery stops when the Bluetooth session is lost.
+	session.mu.Lock()
+	session.discovering = false
+	session.mu.Unlock()
+
+	hub.Publish(supervisor.EventID, supervisor.EventName, supervisor.Status{State: supervisor.StateUp})
+
+	eventually(t, func() bool { return slices.Equal(session.recorded(), []string{"start", "start"}) })
+}
+
+func TestFilterMatches(t *testing.T) {
+	device := bluetooth.DeviceData{
+		Name:  "Headphones",
+		Alias: "Living room",
+		Class: 0x240404,
+		DeviceEventData: bluetooth.DeviceEventData{
+			UUIDs: []string{"0000110B-0000-1000-8000-00805F9B34FB"},
+			RSSI:  -60,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "empty", want: true},
+		{name: "name", filter: Filter{Name: "phones"}, want: true},
+		{name: "alias", filter: Filter{Name: "ROOM"}, want: true},
+		{name: "other name", filter: Filter{Name: "speaker"}},
+		{name: "class", filter: Filter{Class: 0x240404}, want: true},
+		{name: "other class", filter: Filter{Class: 0x5a020c}},
+		{name: "uuid", filter: Filter{UUIDs: []string{"0000110a-0000-1000-8000-00805f9b34fb", "0000110b-0000-1000-8000-00805f9b34fb"}}, want: true},
+		{name: "other uuid", filter: Filter{UUIDs: []string{"0000110a-0000-1000-8000-00805f9b34fb"}}},
+		{name: "signal strength", filter: Filter{MinRSSI: -60}, want: true},
+		{name: "weak signal strength", filter: Filter{MinRSSI: -50}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(device); got != test.want {
+				t.Fatalf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package discovery provides discovery sessions, which are owned by their callers and expire after a duration.
+The device discovery of an adapter is reference-counted across its sessions, and is stopped only when its last session ends.
+*/
+package discovery
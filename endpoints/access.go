@@ -0,0 +1,108 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// AccessOutput is used as the output of the trust and block endpoints.
+type AccessOutput struct {
+	Body access.Rule
+}
+
+// accessEndpoints registers the trust and block endpoints of the "Device" tagged endpoints.
+func accessEndpoints(api huma.API, policy *access.Policy) {
+	deviceAccessEndpoint(api, policy)
+	accessOperationEndpoints(api, policy)
+}
+
+// deviceAccessEndpoint registers the path "/device/{address}/access".
+func deviceAccessEndpoint(api huma.API, policy *access.Policy) {
+	huma.Register(api, huma.Operation{
+		OperationID: "device-access",
+		Method:      http.MethodGet,
+		Path:        "/device/{address}/access",
+		Summary:     "Access",
+		Description: "Fetches whether a device is trusted or blocked by the daemon. It is available while the Bluetooth session is down.",
+		Tags:        []string{"Device"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*AccessOutput, error) {
+		rule, err := policy.Rule(input.Address)
+		if err != nil {
+			rule = access.Rule{Address: input.Address}
+		}
+
+		return &AccessOutput{rule}, nil
+	})
+}
+
+// accessOperationEndpoints registers the paths "/device/{address}/trust", "/device/{address}/untrust",
+// "/device/{address}/block" and "/device/{address}/unblock".
+func accessOperationEndpoints(api huma.API, policy *access.Policy) {
+	operations := []struct {
+		Name        string
+		Summary     string
+		Description string
+		Set         func(bluetooth.MacAddress) (access.Rule, error)
+	}{
+		{
+			Name:        "trust",
+			Summary:     "Trust",
+			Description: "Trusts a device, so that its service authorization requests (for example, when it reconnects) are accepted without an *\"auth\"* event. On Linux, the device is also trusted in BlueZ, if it is known to BlueZ.",
+			Set: func(address bluetooth.MacAddress) (access.Rule, error) {
+				return policy.SetTrusted(address, true)
+			},
+		},
+		{
+			Name:        "untrust",
+			Summary:     "Untrust",
+			Description: "Removes the trust of a device, so that its service authorization requests are sent as *\"auth\"* events again. On Linux, the device is also untrusted in BlueZ, if it is known to BlueZ.",
+			Set: func(address bluetooth.MacAddress) (access.Rule, error) {
+				return policy.SetTrusted(address, false)
+			},
+		},
+		{
+			Name:        "block",
+			Summary:     "Block",
+			Description: "Blocks a device, so that all of its pairing, service and file transfer authorization requests are rejected without an *\"auth\"* event. On Linux, the device is also blocked in BlueZ, if it is known to BlueZ, which disconnects it.",
+			Set: func(address bluetooth.MacAddress) (access.Rule, error) {
+				return policy.SetBlocked(address, true)
+			},
+		},
+		{
+			Name:        "unblock",
+			Summary:     "Unblock",
+			Description: "Unblocks a device, so that its authorization requests are sent as *\"auth\"* events again. On Linux, the device is also unblocked in BlueZ, if it is known to BlueZ.",
+			Set: func(address bluetooth.MacAddress) (access.Rule, error) {
+				return policy.SetBlocked(address, false)
+			},
+		},
+	}
+
+	for _, op := range operations {
+		huma.Register(api, huma.Operation{
+			OperationID: "device-" + op.Name,
+			Method:      http.MethodGet,
+			Path:        "/device/{address}/" + op.Name,
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        []string{"Device"},
+		}, func(_ context.Context, input *struct {
+			AddressInput
+		},
+		) (*AccessOutput, error) {
+			rule, err := op.Set(input.Address)
+			if err != nil {
+				return nil, err
+			}
+
+			return &AccessOutput{rule}, nil
+		})
+	}
+}
@@ -4,22 +4,29 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluerestd/registry"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 )
 
 // adapterEndpoints registers the endpoints for the "Adapter" tagged endpoints.
-func adapterEndpoints(api huma.API, session bluetooth.Session) {
-	devicesEndpoint(api, session)
-	statesEndpoint(api, session)
-	adapterPropertiesEndpoint(api, session)
+func adapterEndpoints(api huma.API, session bluetooth.Session, opts Options) {
+	devicesEndpoint(api, session, opts.Cache, opts.Registry)
+	statesEndpoint(api, session, opts.Discovery)
+	adapterPropertiesEndpoint(api, session, opts.Cache)
+	adapterConfigEndpoints(api, opts.Visibility, opts.Cache)
 }
 
 // devicesEndpoint registers the path "/adapter/{address}/devices".
-func devicesEndpoint(api huma.API, session bluetooth.Session) {
+func devicesEndpoint(api huma.API, session bluetooth.Session, c *cache.Cache, r *registry.Registry) {
 	type AdapterDevicesOutput struct {
-		Body []bluetooth.DeviceData
+		CacheOutput
+		DeviceListOutput
+		Body []sparseDevice
 	}
 
 	huma.Register(api, huma.Operation{
@@ -27,23 +34,44 @@ func devicesEndpoint(api huma.API, session bluetooth.Session) {
 		Method:      http.MethodGet,
 		Path:        "/adapter/{address}/devices",
 		Summary:     "Devices",
-		Description: "Fetches the devices associated with an adapter.",
+		Description: "Fetches the devices associated with an adapter, with the registry entry of each device. The devices can be filtered, sorted and paginated, and only the selected properties of each device can be fetched. If there are more devices to list, use the `X-Next-Cursor` header as the `cursor` parameter to fetch the next page.",
 		Tags:        []string{"Adapter"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
+		CacheInput
+		DeviceListInput
 	},
 	) (*AdapterDevicesOutput, error) {
-		adapterCall := session.Adapter(input.Address)
+		page, headers, err := cachedRead(ctx, c, &input.CacheInput, func(fresh bool) (devicePage, time.Time, error) {
+			if !fresh {
+				if devices, updatedAt, ok := c.Devices(input.Address); ok {
+					return input.list(c, r, devices), updatedAt, nil
+				}
+			}
+
+			adapterCall := sessionFor(ctx, session).Adapter(input.Address)
 
-		devices, err := adapterCall.Devices()
+			devices, err := adapterCall.Devices()
+			if err != nil {
+				return devicePage{}, time.Time{}, err
+			}
 
-		return &AdapterDevicesOutput{devices}, err
+			updatedAt := c.StoreDevices(input.Address, devices)
+
+			return input.list(c, r, devices), updatedAt, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &AdapterDevicesOutput{headers, page.DeviceListOutput, page.Devices}, nil
 	})
 }
 
 // adapterPropertiesEndpoint registers the path "/adapter/{address}/properties".
-func adapterPropertiesEndpoint(api huma.API, session bluetooth.Session) {
+func adapterPropertiesEndpoint(api huma.API, session bluetooth.Session, c *cache.Cache) {
 	type AdapterPropertiesOutput struct {
+		CacheOutput
 		Body bluetooth.AdapterData
 	}
 
@@ -54,23 +82,37 @@ func adapterPropertiesEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Properties",
 		Description: "Fetches the properties of an adapter.",
 		Tags:        []string{"Adapter"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
+		CacheInput
 	},
 	) (*AdapterPropertiesOutput, error) {
-		adapterCall := session.Adapter(input.Address)
+		properties, headers, err := cachedRead(ctx, c, &input.CacheInput, func(fresh bool) (bluetooth.AdapterData, time.Time, error) {
+			if !fresh {
+				if properties, updatedAt, ok := c.Adapter(input.Address); ok {
+					return properties, updatedAt, nil
+				}
+			}
+
+			adapterCall := sessionFor(ctx, session).Adapter(input.Address)
+
+			properties, perr := adapterCall.Properties()
+			if perr != nil {
+				return properties, time.Time{}, perr
+			}
 
-		properties, perr := adapterCall.Properties()
-		if perr != nil {
-			return nil, perr
+			return properties, c.StoreAdapter(properties), nil
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		return &AdapterPropertiesOutput{properties}, nil
+		return &AdapterPropertiesOutput{headers, properties}, nil
 	})
 }
 
 // statesEndpoint registers the path "/adapter/{address}/states".
-func statesEndpoint(api huma.API, session bluetooth.Session) {
+func statesEndpoint(api huma.API, session bluetooth.Session, manager *discovery.Manager) {
 	type AdapterStatesInput struct {
 		Powered      string `doc:"Set the adapter's powered state."            enum:"enable,disable" query:"powered"`
 		Pairable     string `doc:"Set the adapter's pairable state."           enum:"enable,disable" query:"pairable"`
@@ -92,15 +134,19 @@ func statesEndpoint(api huma.API, session bluetooth.Session) {
 		Method:      http.MethodGet,
 		Path:        "/adapter/{address}/states",
 		Summary:     "States",
-		Description: "This endpoint, when called by itself, fetches the different states (powered, pairable, discoverable and device discovery) of an adapter. Use the **query parameters** to `enable` or `disable` each state. Note that when **discovery** is **enabled**, all discovered devices will be published to the `/event` stream, with the ***event-name*** as *'device'*, and with ***event-action*** as *'added'*.",
+		Description: "This endpoint, when called by itself, fetches the different states (powered, pairable, discoverable and device discovery) of an adapter. Use the **query parameters** to `enable` or `disable` each state. Note that when **discovery** is **enabled**, all discovered devices will be published to the `/event` stream, with the ***event-name*** as *'device'*, and with ***event-action*** as *'added'*. To share the device discovery with other clients, use the discovery sessions instead.",
 		Tags:        []string{"Adapter"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AdapterStatesInput
 		AddressInput
 	},
 	) (*AdapterStatesOutput, error) {
+		if input.Discovery == "disable" && manager.Active(input.Address) {
+			return nil, huma.Error409Conflict("The adapter has active discovery sessions, end them using the `/adapter/{address}/discovery/{discovery_id}` endpoint instead.")
+		}
+
 		states := &AdapterStatesOutput{}
-		adapterCall := session.Adapter(input.Address)
+		adapterCall := sessionFor(ctx, session).Adapter(input.Address)
 
 		inputs := []struct {
 			EnableFunc        func() error
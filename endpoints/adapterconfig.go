@@ -0,0 +1,132 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/visibility"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// AdapterConfigOutput is used as the output of the adapter configuration endpoints.
+type AdapterConfigOutput struct {
+	Body visibility.Visibility
+}
+
+// adapterConfigEndpoints registers the adapter configuration endpoints of the "Adapter" tagged endpoints.
+func adapterConfigEndpoints(api huma.API, manager *visibility.Manager, c *cache.Cache) {
+	adapterConfigEndpoint(api, manager)
+	adapterConfigureEndpoint(api, manager, c)
+}
+
+// adapterConfigEndpoint registers the path "/adapter/{address}/config".
+func adapterConfigEndpoint(api huma.API, manager *visibility.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "adapter-config",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/config",
+		Summary:     "Configuration",
+		Description: "Fetches the discoverable and pairable timeouts of an adapter, and the times at which its discoverable and pairable states are reverted.",
+		Tags:        []string{"Adapter"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*AdapterConfigOutput, error) {
+		return &AdapterConfigOutput{manager.Visibility(input.Address)}, nil
+	})
+}
+
+// adapterConfigureEndpoint registers the path "/adapter/{address}/config" with the PATCH method.
+func adapterConfigureEndpoint(api huma.API, manager *visibility.Manager, c *cache.Cache) {
+	type AdapterConfigureInput struct {
+		Body struct {
+			Alias               *string `doc:"The alias (friendly name) of the adapter." json:"alias,omitempty" maxLength:"248"`
+			Class               *uint32 `doc:"The device class of the adapter. It cannot be set through BlueZ, so setting it returns a 501 status." json:"class,omitempty" maximum:"16777215"`
+			DiscoverableTimeout *uint32 `doc:"The number of seconds after which a discoverable adapter stops being discoverable. Set it to zero to keep the adapter discoverable." json:"discoverable_timeout,omitempty"`
+			PairableTimeout     *uint32 `doc:"The number of seconds after which a pairable adapter stops being pairable. Set it to zero to keep the adapter pairable." json:"pairable_timeout,omitempty"`
+			DiscoverableFor     uint32  `doc:"Makes the adapter discoverable for this number of seconds, regardless of its discoverable timeout." json:"discoverable_for,omitempty"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "adapter-configure",
+		Method:      http.MethodPatch,
+		Path:        "/adapter/{address}/config",
+		Summary:     "Configure",
+		Description: "Sets the discoverable and pairable timeouts of an adapter, which are stored and enforced by the daemon, and optionally makes the adapter discoverable for a number of seconds. Only the provided properties are changed. The alias of the adapter can only be set on Linux; on other systems, setting it returns a 501 status. The device class of the adapter cannot be set: BlueZ only sets it from the `Class` option of its main.conf file, or through the kernel's management API, which the daemon does not use. Setting it returns a 501 status, and no other property is changed.",
+		Tags:        []string{"Adapter"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		AdapterConfigureInput
+	},
+	) (*AdapterConfigOutput, error) {
+		body := input.Body
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			if body.Alias != nil {
+				entry.Params["alias"] = *body.Alias
+			}
+
+			if body.DiscoverableTimeout != nil {
+				entry.Params["discoverable_timeout"] = *body.DiscoverableTimeout
+			}
+
+			if body.PairableTimeout != nil {
+				entry.Params["pairable_timeout"] = *body.PairableTimeout
+			}
+
+			if body.DiscoverableFor > 0 {
+				entry.Params["discoverable_for"] = body.DiscoverableFor
+			}
+
+			if body.Class != nil {
+				entry.Params["class"] = *body.Class
+			}
+		})
+
+		if body.Class != nil {
+			return nil, huma.Error501NotImplemented(
+				"The device class of an adapter cannot be set through BlueZ, set the 'Class' option in the BlueZ main.conf file instead.",
+			)
+		}
+
+		if body.Alias != nil {
+			properties, err := manager.SetAlias(input.Address, *body.Alias)
+			if errors.Is(err, visibility.ErrAliasUnsupported) {
+				return nil, huma.Error501NotImplemented(err.Error())
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			c.StoreAdapter(properties)
+		}
+
+		config := manager.Visibility(input.Address)
+
+		if body.DiscoverableTimeout != nil || body.PairableTimeout != nil {
+			v, err := manager.SetTimeouts(input.Address, body.DiscoverableTimeout, body.PairableTimeout)
+			if err != nil {
+				return nil, err
+			}
+
+			config = v
+		}
+
+		if body.DiscoverableFor > 0 {
+			v, err := manager.DiscoverableFor(input.Address, time.Duration(body.DiscoverableFor)*time.Second)
+			if err != nil {
+				return nil, err
+			}
+
+			config = v
+		}
+
+		return &AdapterConfigOutput{config}, nil
+	})
+}
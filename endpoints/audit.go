@@ -0,0 +1,165 @@
+package endpoints
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// auditedOperations holds the IDs of the state-changing operations, which are recorded in the audit log.
+var auditedOperations = map[string]struct{}{
+	"auth":                         {},
+	"adapter-states":               {},
+	"adapter-configure":            {},
+	"discovery-create":             {},
+	"discovery-remove":             {},
+	"setup-create":                 {},
+	"setup-cancel":                 {},
+	"device-remove":                {},
+	"device-pair":                  {},
+	"device-connect":               {},
+	"device-disconnect":            {},
+	"device-trust":                 {},
+	"device-untrust":               {},
+	"device-block":                 {},
+	"device-unblock":               {},
+	"device-reconnect-put":         {},
+	"device-reconnect-remove":      {},
+	"device-media-player-controls": {},
+	"device-network-connect":       {},
+	"device-network-disconnect":    {},
+	"file-transfer-start":          {},
+	"file-transfer-stop":           {},
+	"registry-put":                 {},
+	"registry-remove":              {},
+	"registry-group-connect":       {},
+	"registry-group-disconnect":    {},
+	"webhook-add":                  {},
+	"webhook-remove":               {},
+	"webhook-dead-letter-replay":   {},
+	"webhook-dead-letter-remove":   {},
+}
+
+// auditEntryKey is the context key of the audit log entry of a request.
+type auditEntryKey struct{}
+
+// auditEndpoints registers the endpoints for the "Audit" tagged endpoints.
+func auditEndpoints(api huma.API, log *audit.Log) {
+	auditLogEndpoint(api, log)
+}
+
+// auditLogEndpoint registers the path "/admin/audit".
+func auditLogEndpoint(api huma.API, log *audit.Log) {
+	type AuditLogOutput struct {
+		Body []audit.Entry
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "audit-log",
+		Method:      http.MethodGet,
+		Path:        "/admin/audit",
+		Summary:     "Audit Log",
+		Description: "Fetches the entries of the audit log, with the most recent entries first.",
+		Tags:        []string{"Audit"},
+	}, func(_ context.Context, input *struct {
+		Since     time.Time `doc:"Only fetch the entries recorded at or after this time." format:"date-time" query:"since"`
+		Until     time.Time `doc:"Only fetch the entries recorded at or before this time." format:"date-time" query:"until"`
+		Address   string    `doc:"Only fetch the entries targeting the adapter or device with this address." example:"11:22:33:AA:BB:CC" query:"address"`
+		Operation string    `doc:"Only fetch the entries of this operation ID." example:"device-pair" query:"operation"`
+		Limit     int       `doc:"The maximum number of entries to fetch." default:"100" maximum:"1000" minimum:"1" query:"limit"`
+	},
+	) (*AuditLogOutput, error) {
+		entries, err := log.Query(audit.Filter{
+			Since:     input.Since,
+			Until:     input.Until,
+			Device:    input.Address,
+			Operation: input.Operation,
+			Limit:     input.Limit,
+		})
+
+		return &AuditLogOutput{entries}, err
+	})
+}
+
+// auditMiddleware returns a middleware which records each state-changing operation in the audit log.
+func auditMiddleware(log *audit.Log) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		op := ctx.Operation()
+		if _, ok := auditedOperations[op.OperationID]; !ok {
+			next(ctx)
+
+			return
+		}
+
+		entry := &audit.Entry{
+			Caller:    audit.Caller(ctx.Context()),
+			RequestID: logging.RequestID(ctx.Context()),
+			Operation: op.OperationID,
+			Params:    make(map[string]any),
+		}
+
+		if entry.Caller == "" {
+			entry.Caller = ctx.RemoteAddr()
+		}
+
+		for _, param := range op.Parameters {
+			var value string
+
+			switch param.In {
+			case "path":
+				value = ctx.Param(param.Name)
+			case "query":
+				value = ctx.Query(param.Name)
+			}
+
+			if value != "" {
+				entry.Params[param.Name] = value
+			}
+		}
+
+		// The states of an adapter are only changed if any of the states are specified.
+		if op.OperationID == "adapter-states" && len(entry.Params) <= 1 {
+			next(ctx)
+
+			return
+		}
+
+		if address, ok := entry.Params["address"].(string); ok {
+			delete(entry.Params, "address")
+
+			if strings.HasPrefix(op.Path, "/adapter/") {
+				entry.Adapter = address
+			} else {
+				entry.Device = address
+			}
+		}
+
+		next(huma.WithContext(ctx, context.WithValue(ctx.Context(), auditEntryKey{}, entry)))
+
+		entry.Status = ctx.Status()
+		entry.Outcome = audit.OutcomeSuccess
+		if entry.Status >= http.StatusBadRequest {
+			entry.Outcome = audit.OutcomeFailure
+		}
+
+		if err := log.Record(*entry); err != nil {
+			slog.ErrorContext(ctx.Context(), "Cannot record audit log entry",
+				"operation", op.OperationID, "error", err,
+			)
+		}
+	}
+}
+
+// annotateAudit adds the details known only to an operation's handler,
+// like the parameters in the request body, to the audit log entry of the request.
+func annotateAudit(ctx context.Context, annotate func(entry *audit.Entry)) {
+	if entry, ok := ctx.Value(auditEntryKey{}).(*audit.Entry); ok {
+		annotate(entry)
+	}
+}
@@ -1,10 +1,23 @@
 package endpoints
 
 import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/bluetuith-org/bluerestd/metrics"
+	"github.com/bluetuith-org/bluerestd/setup"
+	"github.com/bluetuith-org/bluerestd/tracing"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/bluetuith-org/bluetooth-classic/api/eventbus"
 	"github.com/google/uuid"
 	"github.com/puzpuzpuz/xsync/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // authRequestEvent describes a request authorization event.
@@ -38,6 +51,23 @@ type authTransferEvent struct {
 type authEventReply struct {
 	reason string
 	reply  bool
+
+	// span holds the span context of the request which replied to the authorization event.
+	span trace.SpanContext
+
+	// requestID holds the ID of the request which replied to the authorization event.
+	requestID string
+}
+
+// authRequest describes a pending authorization request.
+type authRequest struct {
+	reply chan authEventReply
+
+	// span holds the span context of the wait for the reply.
+	span trace.SpanContext
+
+	// event holds the published authorization event.
+	event authRequestEvent
 }
 
 // authEventID is the authorization event ID.
@@ -47,20 +77,30 @@ type authEventID uint
 const authEvent = authEventID(100)
 
 // requests store the pending authorization requests.
-var requests = xsync.NewMapOf[int64, chan authEventReply]()
+var requests = xsync.NewMapOf[int64, authRequest]()
 
 // Authorizer implements the bluetooth.SessionAuthorizer interface.
 type Authorizer struct {
-	id *xsync.Counter
+	id      *xsync.Counter
+	metrics *metrics.Metrics
+	access  *access.Policy
+	setups  *setup.Manager
 }
 
 // NewAuthorizer returns a new authorizer to use as the session's authorization handler.
-func NewAuthorizer() *Authorizer {
-	return &Authorizer{id: xsync.NewCounter()}
+// The outcomes of the authorization requests are recorded in the provided metrics, if any.
+// The requests of the trusted and blocked devices in the provided access policy are decided automatically,
+// and the pairing requests of the devices which are being set up are decided by the policy of their setup.
+func NewAuthorizer(m *metrics.Metrics, policy *access.Policy, setups *setup.Manager) *Authorizer {
+	return &Authorizer{id: xsync.NewCounter(), metrics: m, access: policy, setups: setups}
 }
 
 // AuthorizeTransfer sends a "transfer" authentication request.
 func (a *Authorizer) AuthorizeTransfer(timeout bluetooth.AuthTimeout, props bluetooth.FileTransferData) error {
+	if decided, err := a.checkAccess(props.Address, "transfer", false); decided {
+		return err
+	}
+
 	return a.sendAndWait(timeout, authRequestEvent{
 		AuthType:      "transfer",
 		ReplyRequired: true,
@@ -72,7 +112,16 @@ func (a *Authorizer) AuthorizeTransfer(timeout bluetooth.AuthTimeout, props blue
 
 // DisplayPinCode sends a "display-pincode" pairing authentication request.
 func (a *Authorizer) DisplayPinCode(_ bluetooth.AuthTimeout, address bluetooth.MacAddress, pincode string) error {
+	if decided, err := a.checkAccess(address, "display-pincode", false); decided {
+		return err
+	}
+
+	if decided, err := a.checkSetup(address, "display-pincode", 0, pincode); decided {
+		return err
+	}
+
 	a.send(authRequestEvent{
+		ID:            a.nextID(),
 		AuthType:      "pairing",
 		ReplyRequired: false,
 		PairingParams: &authPairingEvent{
@@ -87,7 +136,16 @@ func (a *Authorizer) DisplayPinCode(_ bluetooth.AuthTimeout, address bluetooth.M
 
 // DisplayPasskey sends a "display-passkey" pairing authentication request.
 func (a *Authorizer) DisplayPasskey(_ bluetooth.AuthTimeout, address bluetooth.MacAddress, passkey uint32, entered uint16) error {
+	if decided, err := a.checkAccess(address, "display-passkey", false); decided {
+		return err
+	}
+
+	if decided, err := a.checkSetup(address, "display-passkey", passkey, ""); decided {
+		return err
+	}
+
 	a.send(authRequestEvent{
+		ID:            a.nextID(),
 		AuthType:      "pairing",
 		ReplyRequired: false,
 		PairingParams: &authPairingEvent{
@@ -103,6 +161,14 @@ func (a *Authorizer) DisplayPasskey(_ bluetooth.AuthTimeout, address bluetooth.M
 
 // ConfirmPasskey sends a "confirm-passkey" pairing authentication request.
 func (a *Authorizer) ConfirmPasskey(timeout bluetooth.AuthTimeout, address bluetooth.MacAddress, passkey uint32) error {
+	if decided, err := a.checkAccess(address, "confirm-passkey", false); decided {
+		return err
+	}
+
+	if decided, err := a.checkSetup(address, "confirm-passkey", passkey, ""); decided {
+		return err
+	}
+
 	return a.sendAndWait(timeout, authRequestEvent{
 		AuthType:      "pairing",
 		ReplyRequired: true,
@@ -116,6 +182,14 @@ func (a *Authorizer) ConfirmPasskey(timeout bluetooth.AuthTimeout, address bluet
 
 // AuthorizePairing sends a "authorize-pairing" pairing authentication request.
 func (a *Authorizer) AuthorizePairing(timeout bluetooth.AuthTimeout, address bluetooth.MacAddress) error {
+	if decided, err := a.checkAccess(address, "authorize-pairing", false); decided {
+		return err
+	}
+
+	if decided, err := a.checkSetup(address, "authorize-pairing", 0, ""); decided {
+		return err
+	}
+
 	return a.sendAndWait(timeout, authRequestEvent{
 		AuthType:      "pairing",
 		ReplyRequired: true,
@@ -127,7 +201,16 @@ func (a *Authorizer) AuthorizePairing(timeout bluetooth.AuthTimeout, address blu
 }
 
 // AuthorizeService sends a "authorize-service" pairing authentication request.
+// The requests of trusted devices are accepted without being sent.
 func (a *Authorizer) AuthorizeService(timeout bluetooth.AuthTimeout, address bluetooth.MacAddress, uuid uuid.UUID) error {
+	if decided, err := a.checkAccess(address, "authorize-service", true); decided {
+		return err
+	}
+
+	if decided, err := a.checkSetup(address, "authorize-service", 0, ""); decided {
+		return err
+	}
+
 	return a.sendAndWait(timeout, authRequestEvent{
 		AuthType:      "pairing",
 		ReplyRequired: true,
@@ -139,34 +222,150 @@ func (a *Authorizer) AuthorizeService(timeout bluetooth.AuthTimeout, address blu
 	})
 }
 
-// send publishes the authorization request to the event stream.
-func (a *Authorizer) send(data authRequestEvent) int64 {
+// checkAccess decides an authorization request of a device using the access policy, and returns
+// whether it was decided. The requests of blocked devices are rejected, and if autoAccept is set,
+// the requests of trusted devices are accepted. Undecided requests must be sent to the clients.
+func (a *Authorizer) checkAccess(address bluetooth.MacAddress, requestType string, autoAccept bool) (bool, error) {
+	switch {
+	case a.access.Blocked(address):
+		a.metrics.AuthRequested()
+		a.metrics.AuthCompleted(metrics.AuthBlocked)
+
+		slog.Warn("Authorization request of blocked device rejected", "request_type", requestType, "address", address.String())
+
+		return true, authEventReply{reason: "The device is blocked."}
+
+	case autoAccept && a.access.Trusted(address):
+		a.metrics.AuthRequested()
+		a.metrics.AuthCompleted(metrics.AuthAccepted)
+
+		slog.Info("Authorization request of trusted device accepted", "request_type", requestType, "address", address.String())
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// checkSetup decides a pairing request of a device using the authorization policy of its running setup,
+// and returns whether it was decided. Undecided requests must be sent to the clients.
+func (a *Authorizer) checkSetup(address bluetooth.MacAddress, requestType string, passkey uint32, pincode string) (bool, error) {
+	decided, accepted := a.setups.Authorize(address, requestType, passkey, pincode)
+	if !decided {
+		return false, nil
+	}
+
+	a.metrics.AuthRequested()
+
+	if !accepted {
+		a.metrics.AuthCompleted(metrics.AuthRejected)
+
+		slog.Warn("Authorization request of device setup rejected", "request_type", requestType, "address", address.String())
+
+		return true, authEventReply{reason: "The passkey or pincode does not match the PIN of the setup."}
+	}
+
+	a.metrics.AuthCompleted(metrics.AuthAccepted)
+
+	slog.Info("Authorization request of device setup accepted", "request_type", requestType, "address", address.String())
+
+	return true, nil
+}
+
+// nextID returns a new authorization request ID.
+func (a *Authorizer) nextID() int64 {
 	a.id.Inc()
-	data.ID = a.id.Value()
 
+	return a.id.Value()
+}
+
+// send publishes the authorization request to the event stream.
+func (a *Authorizer) send(data authRequestEvent) {
 	eventbus.Publish(authEvent, data)
 
-	return data.ID
+	attrs := []any{"auth_id", data.ID, "auth_type", data.AuthType}
+	if data.PairingParams != nil {
+		attrs = append(attrs,
+			"pairing_type", data.PairingParams.PairingType,
+			"address", data.PairingParams.Address.String(),
+		)
+	}
+
+	slog.Info("Authorization request sent", attrs...)
 }
 
 // sendAndWait publishes the authorization request to the event stream and waits for a response.
 func (a *Authorizer) sendAndWait(timeout bluetooth.AuthTimeout, data authRequestEvent) error {
 	var reply authEventReply
 
+	a.metrics.AuthRequested()
+
+	_, span := tracing.Tracer().Start(context.Background(), "auth.wait",
+		trace.WithAttributes(attribute.String("auth.type", data.AuthType)),
+	)
+	defer span.End()
+
+	// The request is stored before it is published, so that it is included
+	// in any snapshot which is taken after the event is published.
 	ch := make(chan authEventReply, 1)
-	requests.Store(a.send(data), ch)
+	id := a.nextID()
+	data.ID = id
+	requests.Store(id, authRequest{ch, span.SpanContext(), data})
+	a.send(data)
+
+	span.SetAttributes(attribute.Int64("auth.id", id))
+
 	select {
 	case <-timeout.Done():
+		requests.Delete(id)
+		a.metrics.AuthCompleted(metrics.AuthExpired)
+		span.SetStatus(codes.Error, "authorization request expired")
+
+		slog.Warn("Authorization request expired", "auth_id", id)
+
+		return reply
+
 	case reply = <-ch:
+		if reply.span.IsValid() {
+			span.AddLink(trace.Link{SpanContext: reply.span})
+		}
+
+		span.SetAttributes(attribute.Bool("auth.accepted", reply.reply))
+
+		ctx := logging.WithRequestID(context.Background(), reply.requestID)
+		slog.InfoContext(ctx, "Authorization request completed",
+			"auth_id", id, "accepted", reply.reply,
+		)
 	}
 
 	if reply.reply {
+		a.metrics.AuthCompleted(metrics.AuthAccepted)
+
 		return nil
 	}
 
+	a.metrics.AuthCompleted(metrics.AuthRejected)
+
 	return reply
 }
 
+// pendingAuthRequests returns the authorization requests which are waiting for a reply.
+func pendingAuthRequests() []authRequestEvent {
+	pending := []authRequestEvent{}
+
+	requests.Range(func(_ int64, request authRequest) bool {
+		pending = append(pending, request.event)
+
+		return true
+	})
+
+	slices.SortFunc(pending, func(a, b authRequestEvent) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return pending
+}
+
 func (i authEventID) String() string {
 	return "auth"
 }
@@ -0,0 +1,156 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
+)
+
+// maxWait is the maximum duration that a long-poll request can wait for a change.
+const maxWait = 5 * time.Minute
+
+// CacheInput is used as the general input parameter of the endpoints which are served from the state cache.
+type CacheInput struct {
+	conditional.Params
+
+	Fresh bool   `doc:"Fetch the data from the Bluetooth stack instead of the state cache, and update the cache with it." query:"fresh"`
+	Wait  string "doc:\"Wait until the data changes from the ETag provided in the `If-None-Match` header, or from its current state, for up to the provided duration (at most 5m).\" example:\"30s\" query:\"wait\""
+
+	wait time.Duration
+}
+
+// CacheOutput is used as the general output header of the endpoints which are served from the state cache.
+type CacheOutput struct {
+	ETag         string `doc:"The entity tag of the returned data." header:"ETag"`
+	LastModified string `doc:"The time at which the returned data was last updated." header:"Last-Modified"`
+	UpdatedAt    string `doc:"The time at which the returned data was last updated, with sub-second precision." header:"X-Updated-At"`
+}
+
+// Resolve validates the wait duration.
+func (c *CacheInput) Resolve(_ huma.Context) []error {
+	if c.Wait == "" {
+		return nil
+	}
+
+	wait, err := time.ParseDuration(c.Wait)
+	if err == nil && (wait < 0 || wait > maxWait) {
+		err = fmt.Errorf("duration must be between 0s and %s", maxWait)
+	}
+
+	if err != nil {
+		return []error{&huma.ErrorDetail{
+			Message:  err.Error(),
+			Location: "query.wait",
+			Value:    c.Wait,
+		}}
+	}
+
+	c.wait = wait
+
+	return nil
+}
+
+// cachedRead reads data which is served from the state cache, using the provided read function.
+// If a wait duration is provided and the data is unchanged from the ETag in the "If-None-Match"
+// header (or no ETag was provided), the request is held until the cache updates the data, or until
+// the duration elapses. If the conditional headers match the data, a 304 status is returned.
+func cachedRead[T any](
+	ctx context.Context, c *cache.Cache, input *CacheInput,
+	read func(fresh bool) (T, time.Time, error),
+) (T, CacheOutput, error) {
+	changed := c.Changed()
+
+	data, updatedAt, err := read(input.Fresh)
+	if err != nil {
+		return data, CacheOutput{}, err
+	}
+
+	etag := etagOf(data)
+
+	if input.wait > 0 && (len(input.IfNoneMatch) == 0 || matchesETag(input.IfNoneMatch, etag)) {
+		timer := time.NewTimer(input.wait)
+		defer timer.Stop()
+
+		for initial := etag; etag == initial; {
+			select {
+			case <-changed:
+			case <-timer.C:
+				return data, cacheOutput(etag, updatedAt), notModified(input, etag, updatedAt)
+
+			case <-ctx.Done():
+				return data, CacheOutput{}, ctx.Err()
+			}
+
+			changed = c.Changed()
+
+			data, updatedAt, err = read(false)
+			if err != nil {
+				return data, CacheOutput{}, err
+			}
+
+			etag = etagOf(data)
+		}
+	}
+
+	return data, cacheOutput(etag, updatedAt), notModified(input, etag, updatedAt)
+}
+
+// notModified returns a 304 status error, if the conditional headers of the request match the data.
+func notModified(input *CacheInput, etag string, updatedAt time.Time) error {
+	if !input.HasConditionalParams() {
+		return nil
+	}
+
+	if err := input.PreconditionFailed(etag, updatedAt.Truncate(time.Second)); err != nil {
+		output := cacheOutput(etag, updatedAt)
+
+		return huma.ErrorWithHeaders(err, http.Header{
+			"Etag":          {output.ETag},
+			"Last-Modified": {output.LastModified},
+			"X-Updated-At":  {output.UpdatedAt},
+		})
+	}
+
+	return nil
+}
+
+// matchesETag returns whether any of the provided entity tags matches the ETag.
+func matchesETag(tags []string, etag string) bool {
+	for _, tag := range tags {
+		if strings.Trim(strings.TrimPrefix(tag, "W/"), `"`) == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheOutput returns the output headers for data with the provided ETag, which was last updated at the provided time.
+func cacheOutput(etag string, updatedAt time.Time) CacheOutput {
+	return CacheOutput{
+		ETag:         `"` + etag + `"`,
+		LastModified: updatedAt.UTC().Format(http.TimeFormat),
+		UpdatedAt:    updatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// etagOf returns the entity tag of the data, which is computed from its JSON encoding.
+func etagOf(data any) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(sum[:16])
+}
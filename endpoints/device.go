@@ -3,25 +3,30 @@ package endpoints
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluerestd/registry"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
 
 // deviceEndpoints registers the endpoints for the "Device" tagged endpoints.
-func deviceEndpoints(api huma.API, session bluetooth.Session) {
+func deviceEndpoints(api huma.API, session bluetooth.Session, opts Options) {
 	connectEndpoint(api, session)
-	disconnectEndpoint(api, session)
+	disconnectEndpoint(api, session, opts.Reconnect)
 	pairEndpoint(api, session)
 	removeEndpoint(api, session)
-	devicePropertiesEndpoint(api, session)
+	devicePropertiesEndpoint(api, session, opts.Cache, opts.Registry)
 }
 
 // devicePropertiesEndpoint registers the path "/device/{address}/properties".
-func devicePropertiesEndpoint(api huma.API, session bluetooth.Session) {
+func devicePropertiesEndpoint(api huma.API, session bluetooth.Session, c *cache.Cache, r *registry.Registry) {
 	type DevicePropertiesOutput struct {
-		Body bluetooth.DeviceData
+		CacheOutput
+		Body RegisteredDevice
 	}
 
 	huma.Register(api, huma.Operation{
@@ -29,17 +34,34 @@ func devicePropertiesEndpoint(api huma.API, session bluetooth.Session) {
 		Method:      http.MethodGet,
 		Path:        "/device/{address}/properties",
 		Summary:     "Properties",
-		Description: "Fetches the properties of the device.",
+		Description: "Fetches the properties of the device, with its decoded class, vendor, registry entry and known service profiles.",
 		Tags:        []string{"Device"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
+		CacheInput
 	},
 	) (*DevicePropertiesOutput, error) {
-		deviceCall := session.Device(input.Address)
-
-		properties, err := deviceCall.Properties()
+		properties, headers, err := cachedRead(ctx, c, &input.CacheInput, func(fresh bool) (RegisteredDevice, time.Time, error) {
+			if !fresh {
+				if properties, updatedAt, ok := c.Device(input.Address); ok {
+					return registered(r, properties), updatedAt, nil
+				}
+			}
+
+			deviceCall := sessionFor(ctx, session).Device(input.Address)
+
+			properties, err := deviceCall.Properties()
+			if err != nil {
+				return RegisteredDevice{}, time.Time{}, err
+			}
+
+			return registered(r, properties), c.StoreDevice(properties), nil
+		})
+		if err != nil {
+			return nil, err
+		}
 
-		return &DevicePropertiesOutput{properties}, err
+		return &DevicePropertiesOutput{headers, properties}, nil
 	})
 }
 
@@ -52,11 +74,11 @@ func removeEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Remove",
 		Description: "Removes a device from its associated adapter.",
 		Tags:        []string{"Device"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 	},
 	) (*struct{}, error) {
-		deviceCall := session.Device(input.Address)
+		deviceCall := sessionFor(ctx, session).Device(input.Address)
 
 		return nil, deviceCall.Remove()
 	})
@@ -71,12 +93,12 @@ func pairEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Pairing",
 		Description: "Starts a pairing process to an unpaired device in pairing mode. If the `cancel` parameter is specified, an ongoing pairing operation to the device, if it exists, will be stopped.",
 		Tags:        []string{"Device"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 		Cancel bool `doc:"Specifies if an ongoing pairing operation to the device should be cancelled." query:"cancel"`
 	},
 	) (*struct{}, error) {
-		deviceCall := session.Device(input.Address)
+		deviceCall := sessionFor(ctx, session).Device(input.Address)
 
 		if input.Cancel {
 			return nil, deviceCall.CancelPairing()
@@ -93,14 +115,14 @@ func connectEndpoint(api huma.API, session bluetooth.Session) {
 		Method:      http.MethodGet,
 		Path:        "/device/{address}/connect",
 		Summary:     "Connection",
-		Description: "Starts a connection process to a paired device. If a service profile UUID is specified, it will attempt to connect to it, otherwise a profile will be chosen and connected to automatically.",
+		Description: "Starts a connection process to a paired device. If a service profile UUID or short name is specified, it will attempt to connect to it, otherwise a profile will be chosen and connected to automatically.",
 		Tags:        []string{"Device"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
-		UUID uuid.UUID `doc:"The Bluetooth service profile UUID." example:"00001124-0000-1000-8000-00805f9b34fb" format:"uuid" query:"profile_uuid"`
+		ProfileInput
 	},
 	) (*struct{}, error) {
-		deviceCall := session.Device(input.Address)
+		deviceCall := sessionFor(ctx, session).Device(input.Address)
 
 		if input.UUID != uuid.Nil {
 			return nil, deviceCall.ConnectProfile(input.UUID)
@@ -111,20 +133,22 @@ func connectEndpoint(api huma.API, session bluetooth.Session) {
 }
 
 // disconnectEndpoint registers the path "/device/{address}/disconnect".
-func disconnectEndpoint(api huma.API, session bluetooth.Session) {
+// The disconnection is marked as requested, so that it does not trigger a reconnection.
+func disconnectEndpoint(api huma.API, session bluetooth.Session, reconnects *reconnect.Manager) {
 	huma.Register(api, huma.Operation{
 		OperationID: "device-disconnect",
 		Method:      http.MethodGet,
 		Path:        "/device/{address}/disconnect",
 		Summary:     "Disconnection",
-		Description: "Starts a disconnection process from a paired device. If a service profile UUID is specified, it will attempt to disconnect from it.",
+		Description: "Starts a disconnection process from a paired device. If a service profile UUID or short name is specified, it will attempt to disconnect from it.",
 		Tags:        []string{"Device"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
-		UUID uuid.UUID `doc:"The Bluetooth service profile UUID." example:"00001124-0000-1000-8000-00805f9b34fb" format:"uuid" query:"profile_uuid"`
+		ProfileInput
 	},
 	) (*struct{}, error) {
-		deviceCall := session.Device(input.Address)
+		deviceCall := sessionFor(ctx, session).Device(input.Address)
+		reconnects.Disconnecting(input.Address)
 
 		if input.UUID != uuid.Nil {
 			return nil, deviceCall.DisconnectProfile(input.UUID)
@@ -0,0 +1,58 @@
+package endpoints
+
+import (
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/deviceinfo"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// IdentifiedDevice describes the properties of a device, with its decoded class and vendor.
+type IdentifiedDevice struct {
+	bluetooth.DeviceData
+
+	ClassInfo *deviceinfo.Class  `doc:"The decoded class of the device, if its class is known." json:"class_info,omitempty"`
+	Vendor    *deviceinfo.Vendor `doc:"The vendor of the device, looked up from the OUI of its address." json:"vendor,omitempty"`
+}
+
+// identifiedDeviceEvent describes a "device" event, whose data includes the decoded class and vendor of the device.
+type identifiedDeviceEvent struct {
+	ID     bluetooth.EventID         `doc:"The event ID." json:"event_id,omitempty"`
+	Action bluetooth.EventAction     `doc:"The corresponding action associated with this event" enum:"updated,added,removed" json:"event_action,omitempty"`
+	Data   identifiedDeviceEventData `doc:"The actual event data." json:"event_data,omitempty"`
+}
+
+// identifiedDeviceEventData holds the data of a "device" event, with the decoded class and vendor of the device.
+type identifiedDeviceEventData struct {
+	bluetooth.DeviceEventData
+
+	ClassInfo *deviceinfo.Class  `doc:"The decoded class of the device, if its class is known." json:"class_info,omitempty"`
+	Vendor    *deviceinfo.Vendor `doc:"The vendor of the device, looked up from the OUI of its address." json:"vendor,omitempty"`
+}
+
+// identified returns the device properties with the decoded class and vendor of the device.
+func identified(device bluetooth.DeviceData) IdentifiedDevice {
+	return IdentifiedDevice{
+		DeviceData: device,
+		ClassInfo:  deviceinfo.DecodeClass(device.Class),
+		Vendor:     deviceinfo.LookupVendor(device.Address),
+	}
+}
+
+// identifiedEvent returns the device event with the decoded class and vendor of the device.
+// Since device events do not include the class, it is fetched from the state cache, if the device is cached.
+func identifiedEvent(c *cache.Cache, ev bluetooth.Event[bluetooth.DeviceEventData]) identifiedDeviceEvent {
+	identified := identifiedDeviceEvent{
+		ID:     ev.ID,
+		Action: ev.Action,
+		Data: identifiedDeviceEventData{
+			DeviceEventData: ev.Data,
+			Vendor:          deviceinfo.LookupVendor(ev.Data.Address),
+		},
+	}
+
+	if device, _, ok := c.Device(ev.Data.Address); ok {
+		identified.Data.ClassInfo = deviceinfo.DecodeClass(device.Class)
+	}
+
+	return identified
+}
@@ -0,0 +1,243 @@
+package endpoints
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/registry"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// defaultDeviceListLimit is the number of listed devices, if a cursor is specified without a limit.
+const defaultDeviceListLimit = 100
+
+// DeviceListInput is used as the input parameter of the device listing endpoints,
+// to filter, sort, paginate and select the fields of the listed devices.
+type DeviceListInput struct {
+	Paired    string `doc:"Only list devices with this paired state."    enum:"true,false" query:"paired"`
+	Connected string `doc:"Only list devices with this connected state." enum:"true,false" query:"connected"`
+	Trusted   string `doc:"Only list devices with this trusted state."   enum:"true,false" query:"trusted"`
+	Blocked   string `doc:"Only list devices with this blocked state."   enum:"true,false" query:"blocked"`
+
+	Class     uint32    `doc:"Only list devices with this device class." query:"class"`
+	Type      string    `doc:"Only list devices of this type (case-insensitive)." example:"Phone" query:"type"`
+	Name      string    `doc:"Only list devices whose name or alias contains this text (case-insensitive)." query:"name"`
+	UUID      string    `doc:"Only list devices which support this service profile UUID." example:"0000110b-0000-1000-8000-00805f9b34fb" query:"uuid"`
+	MinRSSI   int16     `doc:"Only list devices whose signal strength is at least this value." example:"-70" query:"min_rssi"`
+	SeenSince time.Time `doc:"Only list devices which were last seen (added, or reported a new signal strength) at or after this time." format:"date-time" query:"seen_since"`
+	Group     string    `doc:"Only list devices which are members of this registry group." query:"group"`
+	Tag       string    `doc:"Only list devices with this registry tag." query:"tag"`
+
+	Sort   string   `doc:"The property to sort the devices by. Devices are sorted by address by default, and ties are broken by address." enum:"name,rssi,last_seen" query:"sort"`
+	Order  string   `default:"asc" doc:"The sort order." enum:"asc,desc" query:"order"`
+	Limit  int      `doc:"The maximum number of devices to list. All devices are listed by default, unless a cursor is specified, in which case 100 devices are listed." maximum:"1000" minimum:"1" query:"limit"`
+	Cursor string   "doc:\"The cursor of the page to list, from the `X-Next-Cursor` header of the previous page.\" query:\"cursor\""
+	Fields []string `doc:"The properties to include for each device. All properties are included by default." enum:"name,class,type,alias,legacy_pairing,address,associated_adapter,paired,connected,trusted,blocked,bonded,rssi,percentage,uuids,class_info,vendor,registry,profiles" query:"fields"`
+
+	cursor deviceCursor
+}
+
+// DeviceListOutput is used as the output header of the device listing endpoints.
+type DeviceListOutput struct {
+	NextCursor string `doc:"The cursor of the next page, if there are more devices to list." header:"X-Next-Cursor"`
+	TotalCount int    `doc:"The number of devices that matched the filters, across all pages." header:"X-Total-Count"`
+}
+
+// devicePage describes a single page of listed devices.
+type devicePage struct {
+	Devices []sparseDevice
+	DeviceListOutput
+}
+
+// deviceCursor describes the position of the last listed device of a page.
+type deviceCursor struct {
+	Key     deviceSortKey        `json:"k"`
+	Address bluetooth.MacAddress `json:"a"`
+}
+
+// deviceSortKey holds the value of a device's sort property.
+type deviceSortKey struct {
+	Text   string `json:"s,omitempty"`
+	Number int64  `json:"n,omitempty"`
+}
+
+// sparseDevice holds a device, whose JSON encoding only includes the selected fields, if any.
+type sparseDevice struct {
+	RegisteredDevice
+
+	fields []string
+}
+
+// Resolve validates the device listing cursor.
+func (d *DeviceListInput) Resolve(_ huma.Context) []error {
+	if d.Cursor == "" {
+		return nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(d.Cursor)
+	if err == nil {
+		err = json.Unmarshal(decoded, &d.cursor)
+	}
+
+	if err != nil {
+		return []error{&huma.ErrorDetail{
+			Message:  "invalid cursor",
+			Location: "query.cursor",
+			Value:    d.Cursor,
+		}}
+	}
+
+	return nil
+}
+
+// list filters, sorts and paginates the devices, and merges the registry entry of each listed device.
+func (d *DeviceListInput) list(c *cache.Cache, r *registry.Registry, devices []bluetooth.DeviceData) devicePage {
+	listed := make([]bluetooth.DeviceData, 0, len(devices))
+	keys := make(map[bluetooth.MacAddress]deviceSortKey, len(devices))
+
+	for _, device := range devices {
+		lastSeen, _ := c.LastSeen(device.Address)
+		entry, _ := r.Entry(device.Address)
+
+		if !d.matches(device, lastSeen) || !d.matchesEntry(entry) {
+			continue
+		}
+
+		listed = append(listed, device)
+		keys[device.Address] = d.sortKey(device, lastSeen)
+	}
+
+	compare := func(a, b deviceCursor) int {
+		order := cmp.Or(
+			cmp.Compare(a.Key.Text, b.Key.Text),
+			cmp.Compare(a.Key.Number, b.Key.Number),
+		)
+		if d.Order == "desc" {
+			order = -order
+		}
+
+		return cmp.Or(order, slices.Compare(a.Address[:], b.Address[:]))
+	}
+
+	slices.SortFunc(listed, func(a, b bluetooth.DeviceData) int {
+		return compare(deviceCursor{keys[a.Address], a.Address}, deviceCursor{keys[b.Address], b.Address})
+	})
+
+	page := devicePage{Devices: []sparseDevice{}}
+	page.TotalCount = len(listed)
+
+	if d.Cursor != "" {
+		start, found := slices.BinarySearchFunc(listed, d.cursor, func(device bluetooth.DeviceData, cursor deviceCursor) int {
+			return compare(deviceCursor{keys[device.Address], device.Address}, cursor)
+		})
+		if found {
+			start++
+		}
+
+		listed = listed[start:]
+	}
+
+	limit := d.Limit
+	if limit == 0 && d.Cursor != "" {
+		limit = defaultDeviceListLimit
+	}
+
+	if limit > 0 && len(listed) > limit {
+		last := listed[limit-1]
+		listed = listed[:limit]
+
+		cursor, _ := json.Marshal(deviceCursor{keys[last.Address], last.Address})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(cursor)
+	}
+
+	for _, device := range listed {
+		page.Devices = append(page.Devices, sparseDevice{registered(r, device), d.Fields})
+	}
+
+	return page
+}
+
+// matches returns whether the device matches all the filters.
+func (d *DeviceListInput) matches(device bluetooth.DeviceData, lastSeen time.Time) bool {
+	switch {
+	case !matchesState(d.Paired, device.Paired),
+		!matchesState(d.Connected, device.Connected),
+		!matchesState(d.Trusted, device.Trusted),
+		!matchesState(d.Blocked, device.Blocked):
+		return false
+
+	case d.Class != 0 && device.Class != d.Class,
+		d.Type != "" && !strings.EqualFold(device.Type, d.Type),
+		d.UUID != "" && !slices.ContainsFunc(device.UUIDs, func(uuid string) bool { return strings.EqualFold(uuid, d.UUID) }),
+		d.MinRSSI != 0 && (device.RSSI == 0 || device.RSSI < d.MinRSSI),
+		!d.SeenSince.IsZero() && lastSeen.Before(d.SeenSince):
+		return false
+
+	case d.Name != "":
+		name := strings.ToLower(d.Name)
+
+		return strings.Contains(strings.ToLower(device.Name), name) || strings.Contains(strings.ToLower(device.Alias), name)
+	}
+
+	return true
+}
+
+// matchesEntry returns whether the registry entry of the device matches the registry filters.
+func (d *DeviceListInput) matchesEntry(entry registry.Metadata) bool {
+	return (d.Group == "" || slices.Contains(entry.Groups, d.Group)) &&
+		(d.Tag == "" || slices.Contains(entry.Tags, d.Tag))
+}
+
+// sortKey returns the value of the device's sort property.
+func (d *DeviceListInput) sortKey(device bluetooth.DeviceData, lastSeen time.Time) deviceSortKey {
+	switch d.Sort {
+	case "name":
+		return deviceSortKey{Text: strings.ToLower(cmp.Or(device.Alias, device.Name))}
+
+	case "rssi":
+		return deviceSortKey{Number: int64(device.RSSI)}
+
+	case "last_seen":
+		return deviceSortKey{Number: lastSeen.UnixNano()}
+	}
+
+	return deviceSortKey{}
+}
+
+// Schema returns the schema of the registered device, since only the encoding of the device differs.
+func (sparseDevice) Schema(r huma.Registry) *huma.Schema {
+	return r.Schema(reflect.TypeFor[RegisteredDevice](), true, "")
+}
+
+// MarshalJSON encodes the device with only its selected fields.
+func (s sparseDevice) MarshalJSON() ([]byte, error) {
+	encoded, err := json.Marshal(s.RegisteredDevice)
+	if err != nil || len(s.fields) == 0 {
+		return encoded, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		if !slices.Contains(s.fields, name) {
+			delete(fields, name)
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// matchesState returns whether the state matches the filter value, if any.
+func matchesState(filter string, state bool) bool {
+	return filter == "" || (filter == "true") == state
+}
@@ -0,0 +1,197 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/sse"
+)
+
+// discoveryEndEvent describes an "end" event, which is sent when a discovery session ends.
+type discoveryEndEvent struct {
+	ID     string `doc:"The ID of the discovery session." json:"discovery_id"`
+	Reason string `doc:"The reason why the event stream ended." enum:"ended,not_found" json:"reason"`
+}
+
+// DiscoveryInput is used as the general input parameter for a discovery session ID.
+type DiscoveryInput struct {
+	ID string `doc:"The ID of the discovery session." path:"discovery_id"`
+}
+
+// discoveryEndpoints registers the endpoints for the "Discovery" tagged endpoints.
+func discoveryEndpoints(api huma.API, manager *discovery.Manager) {
+	createDiscoveryEndpoint(api, manager)
+	discoverySessionsEndpoint(api, manager)
+	removeDiscoveryEndpoint(api, manager)
+	discoveryResultsEndpoint(api, manager)
+	discoveryEventsEndpoint(api, manager)
+}
+
+// createDiscoveryEndpoint registers the path "/adapter/{address}/discovery" (POST).
+func createDiscoveryEndpoint(api huma.API, manager *discovery.Manager) {
+	type CreateDiscoveryInput struct {
+		Body struct {
+			Duration int `default:"60" doc:"The number of seconds after which the session expires." maximum:"3600" minimum:"1" json:"duration"`
+
+			discovery.Filter
+		}
+	}
+
+	type CreateDiscoveryOutput struct {
+		Body discovery.Session
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "discovery-create",
+		Method:        http.MethodPost,
+		Path:          "/adapter/{address}/discovery",
+		Summary:       "Start Discovery",
+		Description:   "Creates a discovery session on an adapter, which expires after its duration. The device discovery of the adapter is started if it is not already running, and is stopped only when its last session ends.",
+		Tags:          []string{"Discovery"},
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		CreateDiscoveryInput
+	},
+	) (*CreateDiscoveryOutput, error) {
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["duration"] = input.Body.Duration
+		})
+
+		session, err := manager.Create(
+			input.Address, time.Duration(input.Body.Duration)*time.Second,
+			input.Body.Filter, audit.Caller(ctx),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["discovery_id"] = session.ID
+		})
+
+		return &CreateDiscoveryOutput{session}, nil
+	})
+}
+
+// discoverySessionsEndpoint registers the path "/adapter/{address}/discovery".
+func discoverySessionsEndpoint(api huma.API, manager *discovery.Manager) {
+	type DiscoverySessionsOutput struct {
+		Body []discovery.Session
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "discovery-sessions",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/discovery",
+		Summary:     "Discovery Sessions",
+		Description: "Fetches the discovery sessions of an adapter which were created by the caller.",
+		Tags:        []string{"Discovery"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+	},
+	) (*DiscoverySessionsOutput, error) {
+		return &DiscoverySessionsOutput{manager.Sessions(input.Address, audit.Caller(ctx))}, nil
+	})
+}
+
+// removeDiscoveryEndpoint registers the path "/adapter/{address}/discovery/{discovery_id}".
+func removeDiscoveryEndpoint(api huma.API, manager *discovery.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "discovery-remove",
+		Method:      http.MethodDelete,
+		Path:        "/adapter/{address}/discovery/{discovery_id}",
+		Summary:     "Stop Discovery",
+		Description: "Ends a discovery session which was created by the caller. The device discovery of the adapter is stopped if it was the last session of the adapter, unless the discovery was already running before the first session.",
+		Tags:        []string{"Discovery"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		DiscoveryInput
+	},
+	) (*struct{}, error) {
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["discovery_id"] = input.ID
+		})
+
+		return nil, discoveryError(manager.Remove(input.Address, input.ID, audit.Caller(ctx)))
+	})
+}
+
+// discoveryResultsEndpoint registers the path "/adapter/{address}/discovery/{discovery_id}/results".
+func discoveryResultsEndpoint(api huma.API, manager *discovery.Manager) {
+	type DiscoveryResultsOutput struct {
+		Body []IdentifiedDevice
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "discovery-results",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/discovery/{discovery_id}/results",
+		Summary:     "Discovery Results",
+		Description: "Fetches the devices that were discovered during a discovery session which was created by the caller, and match its filter, with the strongest signals first.",
+		Tags:        []string{"Discovery"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		DiscoveryInput
+	},
+	) (*DiscoveryResultsOutput, error) {
+		results, err := manager.Results(input.Address, input.ID, audit.Caller(ctx))
+		if err != nil {
+			return nil, discoveryError(err)
+		}
+
+		devices := make([]IdentifiedDevice, 0, len(results))
+		for _, device := range results {
+			devices = append(devices, identified(device))
+		}
+
+		return &DiscoveryResultsOutput{devices}, nil
+	})
+}
+
+// discoveryEventsEndpoint registers the path "/adapter/{address}/discovery/{discovery_id}/events".
+func discoveryEventsEndpoint(api huma.API, manager *discovery.Manager) {
+	sse.Register(api, huma.Operation{
+		OperationID: "discovery-events",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/discovery/{discovery_id}/events",
+		Summary:     "Discovery Events",
+		Description: "Subscribe to this EventSource for the devices that are discovered during a discovery session which was created by the caller, and match its filter. An *\"end\"* event is sent when the session ends.",
+		Tags:        []string{"Discovery"},
+	}, map[string]any{
+		"device": IdentifiedDevice{},
+		"end":    discoveryEndEvent{},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		DiscoveryInput
+	}, send sse.Sender,
+	) {
+		reason := "ended"
+
+		err := manager.Watch(ctx, input.Address, input.ID, audit.Caller(ctx), func(device bluetooth.DeviceData) bool {
+			return send.Data(identified(device)) == nil
+		})
+		if errors.Is(err, discovery.ErrSessionNotFound) {
+			reason = "not_found"
+		}
+
+		if ctx.Err() == nil {
+			send.Data(discoveryEndEvent{ID: input.ID, Reason: reason})
+		}
+	})
+}
+
+// discoveryError converts the "not found" discovery errors to a 404 status error.
+func discoveryError(err error) error {
+	if errors.Is(err, discovery.ErrSessionNotFound) {
+		return huma.Error404NotFound(err.Error())
+	}
+
+	return err
+}
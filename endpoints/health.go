@@ -0,0 +1,211 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	ac "github.com/bluetuith-org/bluetooth-classic/api/appfeatures"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/platforminfo"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// featureNames maps each feature to the name used in the API.
+var featureNames = map[ac.Features]string{
+	ac.FeatureConnection:  "connection",
+	ac.FeaturePairing:     "pairing",
+	ac.FeatureSendFile:    "send-file",
+	ac.FeatureReceiveFile: "receive-file",
+	ac.FeatureNetwork:     "network",
+	ac.FeatureMediaPlayer: "media-player",
+}
+
+// featureStatus describes the status of a feature.
+type featureStatus struct {
+	Name        string `doc:"The name of the feature." json:"name"`
+	Description string `doc:"The description of the feature." json:"description"`
+	Error       string `doc:"The error which occurred while enabling the feature, if it is not supported." json:"error,omitempty"`
+	Supported   bool   `doc:"Indicates whether the feature is supported." json:"supported"`
+}
+
+// adapterStatus describes the status of an adapter.
+type adapterStatus struct {
+	Name        string               `doc:"The system-assigned name of the adapter." json:"name"`
+	UniqueName  string               `doc:"A unique name for the adapter." json:"unique_name,omitempty"`
+	Address     bluetooth.MacAddress `doc:"The Bluetooth MAC address of the adapter." json:"address"`
+	Powered     bool                 `doc:"Indicates whether the adapter is powered on." json:"powered"`
+	Discovering bool                 `doc:"Indicates whether the adapter is discovering." json:"discovering"`
+}
+
+// readiness describes the readiness of the session.
+type readiness struct {
+	Status string `doc:"The readiness status." enum:"ready,not-ready" json:"status"`
+	Reason string `doc:"The reason if the session is not ready." json:"reason,omitempty"`
+}
+
+// healthEndpoints registers the endpoints for the "Health" tagged endpoints.
+func healthEndpoints(api huma.API, session bluetooth.Session, opts Options) {
+	livenessEndpoint(api)
+	readinessEndpoint(api, session, opts.Supervisor)
+	statusEndpoint(api, session, opts)
+}
+
+// livenessEndpoint registers the path "/healthz".
+func livenessEndpoint(api huma.API) {
+	type LivenessOutput struct {
+		Body struct {
+			Status string `doc:"The liveness status." enum:"ok" json:"status"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "healthz",
+		Method:      http.MethodGet,
+		Path:        "/healthz",
+		Summary:     "Liveness",
+		Description: "Reports whether the daemon is running and serving requests.",
+		Tags:        []string{"Health"},
+		Metadata:    map[string]any{logging.QuietMetadata: true},
+	}, func(_ context.Context, _ *struct{}) (*LivenessOutput, error) {
+		output := &LivenessOutput{}
+		output.Body.Status = "ok"
+
+		return output, nil
+	})
+}
+
+// readinessEndpoint registers the path "/readyz".
+func readinessEndpoint(api huma.API, session bluetooth.Session, sup *supervisor.Supervisor) {
+	type ReadinessOutput struct {
+		Body readiness
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "readyz",
+		Method:      http.MethodGet,
+		Path:        "/readyz",
+		Summary:     "Readiness",
+		Description: "Reports whether the Bluetooth session is started, and at least one adapter is present. If the session is not ready, a `503` status is returned.",
+		Tags:        []string{"Health"},
+		Metadata:    map[string]any{logging.QuietMetadata: true},
+	}, func(_ context.Context, _ *struct{}) (*ReadinessOutput, error) {
+		ready := sessionReadiness(session, sup)
+		if ready.Status != "ready" {
+			return nil, huma.Error503ServiceUnavailable("The session is not ready: " + ready.Reason)
+		}
+
+		return &ReadinessOutput{ready}, nil
+	})
+}
+
+// statusEndpoint registers the path "/status".
+func statusEndpoint(api huma.API, session bluetooth.Session, opts Options) {
+	type StatusOutput struct {
+		Body struct {
+			Status           string                    `doc:"The readiness status." enum:"ready,not-ready" json:"status"`
+			Reason           string                    `doc:"The reason if the session is not ready." json:"reason,omitempty"`
+			Session          supervisor.Status         `doc:"The state of the session." json:"session"`
+			StartedAt        time.Time                 `doc:"The time at which the session was started." json:"started_at"`
+			Platform         platforminfo.PlatformInfo `doc:"The Bluetooth stack and operating system of the session." json:"platform"`
+			Adapters         []adapterStatus           `doc:"The status of each adapter." json:"adapters"`
+			Features         []featureStatus           `doc:"The status of each feature." json:"features"`
+			Uptime           float64                   `doc:"The time since the session was started, in seconds." json:"uptime"`
+			EventStreams     int64                     "doc:\"The number of clients subscribed to the `/events` endpoint.\" json:\"event_streams\""
+			EventSubscribers int                       `doc:"The number of all event subscribers, including webhooks and the MQTT bridge." json:"event_subscribers"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "status",
+		Method:      http.MethodGet,
+		Path:        "/status",
+		Summary:     "Status",
+		Description: "Fetches the detailed status of the daemon, which includes the session uptime, platform information, the status of each adapter and feature, and the number of event subscribers.",
+		Tags:        []string{"Health"},
+	}, func(_ context.Context, _ *struct{}) (*StatusOutput, error) {
+		status := &StatusOutput{}
+
+		ready := sessionReadiness(session, opts.Supervisor)
+
+		status.Body.Status, status.Body.Reason = ready.Status, ready.Reason
+		status.Body.Session = opts.Supervisor.Status()
+		status.Body.StartedAt = opts.Supervisor.StartedAt()
+		status.Body.Uptime = time.Since(status.Body.StartedAt).Seconds()
+		status.Body.Platform = opts.Supervisor.Platform()
+		status.Body.Features = featureStatuses(opts.Supervisor.Features())
+		status.Body.EventStreams = eventStreams.Load()
+		status.Body.EventSubscribers = hubSubscribers(opts.Hub)
+
+		status.Body.Adapters = []adapterStatus{}
+		if session != nil {
+			for _, adapter := range session.Adapters() {
+				status.Body.Adapters = append(status.Body.Adapters, adapterStatus{
+					Name:        adapter.Name,
+					UniqueName:  adapter.UniqueName,
+					Address:     adapter.Address,
+					Powered:     adapter.Powered,
+					Discovering: adapter.Discovering,
+				})
+			}
+		}
+
+		return status, nil
+	})
+}
+
+// sessionReadiness returns whether the session is up and has at least one adapter.
+func sessionReadiness(session bluetooth.Session, sup *supervisor.Supervisor) readiness {
+	switch status := sup.Status(); {
+	case session == nil:
+		return readiness{Status: "not-ready", Reason: "session not started"}
+
+	case status.State != supervisor.StateUp:
+		return readiness{Status: "not-ready", Reason: "session " + string(status.State) + ": " + status.Reason}
+
+	case len(session.Adapters()) == 0:
+		return readiness{Status: "not-ready", Reason: "no adapters available"}
+	}
+
+	return readiness{Status: "ready"}
+}
+
+// featureStatuses returns the status of each feature in the feature set.
+func featureStatuses(features ac.FeatureSet) []featureStatus {
+	errs, _ := features.Errors.Exists()
+
+	statuses := make([]featureStatus, 0, len(featureNames))
+	for feature, name := range featureNames {
+		status := featureStatus{
+			Name:        name,
+			Description: ac.FeatureMap[feature],
+			Supported:   features.Has(feature),
+		}
+
+		if err, ok := errs[feature]; ok && err.FeatureErrors != nil {
+			status.Error = err.FeatureErrors.Error()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	slices.SortFunc(statuses, func(a, b featureStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return statuses
+}
+
+// hubSubscribers returns the number of subscribers of the event hub, if any.
+func hubSubscribers(hub *events.Hub) int {
+	if hub == nil {
+		return 0
+	}
+
+	return hub.Subscribers()
+}
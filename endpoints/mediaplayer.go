@@ -3,20 +3,23 @@ package endpoints
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/bluetuith-org/bluerestd/cache"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 )
 
 // mediaPlayerEndpoints registers the endpoints for the "MediaPlayer" tagged endpoints.
-func mediaPlayerEndpoints(api huma.API, session bluetooth.Session) {
+func mediaPlayerEndpoints(api huma.API, session bluetooth.Session, c *cache.Cache) {
 	mediaPlayerControlEndpoint(api, session)
-	mediaPlayerPropertiesEndpoint(api, session)
+	mediaPlayerPropertiesEndpoint(api, session, c)
 }
 
 // mediaPlayerPropertiesEndpoint registers the path "/device/{address}/media_player/properties".
-func mediaPlayerPropertiesEndpoint(api huma.API, session bluetooth.Session) {
+func mediaPlayerPropertiesEndpoint(api huma.API, session bluetooth.Session, c *cache.Cache) {
 	type MediaPropertiesOutput struct {
+		CacheOutput
 		Body bluetooth.MediaData
 	}
 
@@ -27,20 +30,32 @@ func mediaPlayerPropertiesEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Properties",
 		Description: "Sends a media control command to the device's media player, if available.",
 		Tags:        []string{"Media Player"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
+		CacheInput
 	},
 	) (*MediaPropertiesOutput, error) {
-		var err error
+		properties, headers, err := cachedRead(ctx, c, &input.CacheInput, func(fresh bool) (bluetooth.MediaData, time.Time, error) {
+			if !fresh {
+				if properties, updatedAt, ok := c.MediaPlayer(input.Address); ok {
+					return properties, updatedAt, nil
+				}
+			}
+
+			mediaCall := sessionFor(ctx, session).MediaPlayer(input.Address)
 
-		mediaCall := session.MediaPlayer(input.Address)
+			properties, err := mediaCall.Properties()
+			if err != nil {
+				return properties, time.Time{}, err
+			}
 
-		properties, err := mediaCall.Properties()
+			return properties, c.StoreMediaPlayer(input.Address, properties), nil
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		return &MediaPropertiesOutput{properties}, nil
+		return &MediaPropertiesOutput{headers, properties}, nil
 	})
 }
 
@@ -57,14 +72,14 @@ func mediaPlayerControlEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Controls",
 		Description: "Sends a media control command to the device's media player, if available.",
 		Tags:        []string{"Media Player"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 		MediaControlInput
 	},
 	) (*struct{}, error) {
 		var err error
 
-		mediaCall := session.MediaPlayer(input.Address)
+		mediaCall := sessionFor(ctx, session).MediaPlayer(input.Address)
 
 		switch input.Control {
 		case "play":
@@ -28,19 +28,19 @@ func connectNetworkEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Connection (PANU, DUN)",
 		Description: "Attempts to tether to the internet connection of the device.",
 		Tags:        []string{"Network"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 		NetworkTypeInput
 	},
 	) (*struct{}, error) {
-		device, err := session.Device(input.Address).Properties()
+		device, err := sessionFor(ctx, session).Device(input.Address).Properties()
 		if err != nil {
 			return nil, err
 		}
 
 		networkName := device.Name + " Connection (" + device.Address.String() + ", " + strings.ToUpper(input.Type.String()) + ")"
 
-		return nil, session.Network(input.Address).Connect(networkName, input.Type)
+		return nil, sessionFor(ctx, session).Network(input.Address).Connect(networkName, input.Type)
 	})
 }
 
@@ -53,10 +53,10 @@ func disconnectNetworkEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Disconnection",
 		Description: "Attempts to untether from the internet connection of the device.",
 		Tags:        []string{"Network"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 	},
 	) (*struct{}, error) {
-		return nil, session.Network(input.Address).Disconnect()
+		return nil, sessionFor(ctx, session).Network(input.Address).Disconnect()
 	})
 }
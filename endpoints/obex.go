@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/bluetuith-org/bluerestd/audit"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 )
@@ -23,11 +24,11 @@ func cancelTransferEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Stop Transfers",
 		Description: "Attempts to stop an ongoing file transfer session.",
 		Tags:        []string{"File Transfer"},
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		AddressInput
 	},
 	) (*struct{}, error) {
-		return nil, session.Obex(input.Address).FileTransfer().CancelTransfer()
+		return nil, sessionFor(ctx, session).Obex(input.Address).FileTransfer().CancelTransfer()
 	})
 }
 
@@ -61,7 +62,11 @@ func startTransferEndpoint(api huma.API, session bluetooth.Session) {
 			return nil, huma.Error422UnprocessableEntity("Empty filepath set provided.")
 		}
 
-		obexCall := session.Obex(input.Address)
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["file_paths"] = input.Body.FilePaths
+		})
+
+		obexCall := sessionFor(ctx, session).Obex(input.Address)
 		if err := obexCall.FileTransfer().CreateSession(ctx); err != nil {
 			return nil, err
 		}
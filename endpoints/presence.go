@@ -0,0 +1,69 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bluetuith-org/bluerestd/presence"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// presenceEndpoints registers the endpoints for the "Presence" tagged endpoints.
+func presenceEndpoints(api huma.API, tracker *presence.Tracker) {
+	presenceListEndpoint(api, tracker)
+	devicePresenceEndpoint(api, tracker)
+}
+
+// presenceListEndpoint registers the path "/presence".
+func presenceListEndpoint(api huma.API, tracker *presence.Tracker) {
+	type PresenceListOutput struct {
+		Body []presence.Presence
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "presence",
+		Method:      http.MethodGet,
+		Path:        "/presence",
+		Summary:     "Presence",
+		Description: "Fetches the presence of all tracked devices which have been seen.",
+		Tags:        []string{"Presence"},
+	}, func(_ context.Context, input *struct {
+		Present string `doc:"Only list devices with this presence state." enum:"true,false" query:"present"`
+	},
+	) (*PresenceListOutput, error) {
+		devices := []presence.Presence{}
+		for _, device := range tracker.Devices() {
+			if matchesState(input.Present, device.Present) {
+				devices = append(devices, device)
+			}
+		}
+
+		return &PresenceListOutput{devices}, nil
+	})
+}
+
+// devicePresenceEndpoint registers the path "/presence/{address}".
+func devicePresenceEndpoint(api huma.API, tracker *presence.Tracker) {
+	type DevicePresenceOutput struct {
+		Body presence.Presence
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "presence-device",
+		Method:      http.MethodGet,
+		Path:        "/presence/{address}",
+		Summary:     "Device Presence",
+		Description: "Fetches the presence of a device. A 404 status is returned if the device is not tracked, or has not been seen yet.",
+		Tags:        []string{"Presence"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*DevicePresenceOutput, error) {
+		device, ok := tracker.Device(input.Address)
+		if !ok {
+			return nil, huma.Error404NotFound("The device has not been seen.")
+		}
+
+		return &DevicePresenceOutput{device}, nil
+	})
+}
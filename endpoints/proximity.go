@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/bluetuith-org/bluerestd/proximity"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// proximityEndpoints registers the proximity endpoints of the "Presence" tagged endpoints.
+func proximityEndpoints(api huma.API, tracker *proximity.Tracker) {
+	proximityListEndpoint(api, tracker)
+	deviceProximityEndpoint(api, tracker)
+}
+
+// proximityListEndpoint registers the path "/proximity".
+func proximityListEndpoint(api huma.API, tracker *proximity.Tracker) {
+	type ProximityListOutput struct {
+		Body []proximity.Device
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "proximity",
+		Method:      http.MethodGet,
+		Path:        "/proximity",
+		Summary:     "Proximity",
+		Description: "Fetches the estimated proximity and smoothed signal strength of all devices with signal strength samples.",
+		Tags:        []string{"Presence"},
+	}, func(_ context.Context, input *struct {
+		Proximity []string `doc:"Only list devices with any of these proximities." enum:"immediate,near,far,gone" query:"proximity"`
+	},
+	) (*ProximityListOutput, error) {
+		devices := []proximity.Device{}
+		for _, device := range tracker.Devices() {
+			if len(input.Proximity) == 0 || slices.Contains(input.Proximity, string(device.Proximity)) {
+				devices = append(devices, device)
+			}
+		}
+
+		return &ProximityListOutput{devices}, nil
+	})
+}
+
+// deviceProximityEndpoint registers the path "/proximity/{address}".
+func deviceProximityEndpoint(api huma.API, tracker *proximity.Tracker) {
+	type DeviceProximityOutput struct {
+		Body proximity.Device
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "proximity-device",
+		Method:      http.MethodGet,
+		Path:        "/proximity/{address}",
+		Summary:     "Device Proximity",
+		Description: "Fetches the signal strength samples of a device, with the smoothed signal strength after each sample, and its estimated proximity. A 404 status is returned if the device has no samples.",
+		Tags:        []string{"Presence"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*DeviceProximityOutput, error) {
+		device, ok := tracker.Device(input.Address)
+		if !ok {
+			return nil, huma.Error404NotFound("The device has no signal strength samples.")
+		}
+
+		return &DeviceProximityOutput{device}, nil
+	})
+}
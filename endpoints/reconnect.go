@@ -0,0 +1,169 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// DeviceReconnection describes the reconnection policy of a device, with its reconnection status.
+type DeviceReconnection struct {
+	Policy reconnect.Policy       `doc:"The reconnection policy of the device." json:"policy"`
+	Status reconnect.Reconnection `doc:"The reconnection status of the device." json:"status"`
+}
+
+// backoffDelay describes a delay of a backoff schedule, in seconds.
+type backoffDelay uint32
+
+// Schema returns the schema of a backoff delay, which must be at least one second.
+func (backoffDelay) Schema(_ huma.Registry) *huma.Schema {
+	minimum := 1.0
+
+	return &huma.Schema{Type: huma.TypeInteger, Format: "int32", Minimum: &minimum}
+}
+
+// DeviceReconnectionOutput is used as the output of the device reconnection endpoints.
+type DeviceReconnectionOutput struct {
+	Body DeviceReconnection
+}
+
+// reconnectEndpoints registers the endpoints for the "Reconnection" tagged endpoints.
+func reconnectEndpoints(api huma.API, manager *reconnect.Manager) {
+	reconnectPoliciesEndpoint(api, manager)
+	deviceReconnectEndpoint(api, manager)
+	putDeviceReconnectEndpoint(api, manager)
+	removeDeviceReconnectEndpoint(api, manager)
+}
+
+// reconnectPoliciesEndpoint registers the path "/reconnect".
+func reconnectPoliciesEndpoint(api huma.API, manager *reconnect.Manager) {
+	type ReconnectPoliciesOutput struct {
+		Body []DeviceReconnection
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "reconnect-policies",
+		Method:      http.MethodGet,
+		Path:        "/reconnect",
+		Summary:     "Policies",
+		Description: "Fetches the reconnection policies of all devices, with their reconnection status.",
+		Tags:        []string{"Reconnection"},
+	}, func(_ context.Context, _ *struct{}) (*ReconnectPoliciesOutput, error) {
+		policies := manager.Policies()
+
+		reconnections := make([]DeviceReconnection, 0, len(policies))
+		for _, p := range policies {
+			reconnections = append(reconnections, DeviceReconnection{p, manager.Status(p.Address)})
+		}
+
+		return &ReconnectPoliciesOutput{reconnections}, nil
+	})
+}
+
+// deviceReconnectEndpoint registers the path "/device/{address}/reconnect".
+func deviceReconnectEndpoint(api huma.API, manager *reconnect.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "device-reconnect",
+		Method:      http.MethodGet,
+		Path:        "/device/{address}/reconnect",
+		Summary:     "Policy",
+		Description: "Fetches the reconnection policy of a device, with its reconnection status.",
+		Tags:        []string{"Reconnection"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*DeviceReconnectionOutput, error) {
+		p, err := manager.Policy(input.Address)
+		if err != nil {
+			return nil, reconnectError(err)
+		}
+
+		return &DeviceReconnectionOutput{DeviceReconnection{p, manager.Status(input.Address)}}, nil
+	})
+}
+
+// putDeviceReconnectEndpoint registers the path "/device/{address}/reconnect" (PUT).
+func putDeviceReconnectEndpoint(api huma.API, manager *reconnect.Manager) {
+	type PutDeviceReconnectInput struct {
+		Body struct {
+			Enabled     bool             `default:"true" doc:"Whether the device is reconnected to automatically." json:"enabled"`
+			ProfileUUID string           `doc:"The Bluetooth service profile UUID to reconnect to, or the short name of a profile listed by the Profiles endpoint. If it is not provided, a profile is chosen automatically." example:"a2dp-sink" json:"profile_uuid,omitempty"`
+			Backoff     []backoffDelay   `doc:"The number of seconds to wait before each reconnection attempt, each at least one second. The last value is used for all further attempts. If it is not provided, the default schedule of 5, 15, 30, 60 and 300 seconds is used." example:"[5, 30, 60]" json:"backoff,omitempty" maxItems:"32"`
+			MaxAttempts int              `default:"10" doc:"The maximum number of reconnection attempts. If it is zero, the attempts are not limited." json:"max_attempts" minimum:"0"`
+			ActiveHours *reconnect.Hours `doc:"The hours of the day (in the local time of the daemon) within which the reconnection attempts are made. If it is not provided, the attempts are made at any time." json:"active_hours,omitempty"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "device-reconnect-put",
+		Method:      http.MethodPut,
+		Path:        "/device/{address}/reconnect",
+		Summary:     "Set Policy",
+		Description: "Creates or replaces the reconnection policy of a device. If the policy is disabled, the running reconnection of the device is cancelled. The new policy applies from the next reconnection.",
+		Tags:        []string{"Reconnection"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		PutDeviceReconnectInput
+	},
+	) (*DeviceReconnectionOutput, error) {
+		body := input.Body
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["enabled"] = body.Enabled
+			entry.Params["max_attempts"] = body.MaxAttempts
+		})
+
+		p := reconnect.Policy{
+			Address:     input.Address,
+			Enabled:     body.Enabled,
+			MaxAttempts: body.MaxAttempts,
+			ActiveHours: body.ActiveHours,
+			Profile:     body.ProfileUUID,
+		}
+
+		for _, delay := range body.Backoff {
+			p.Backoff = append(p.Backoff, uint32(delay))
+		}
+
+		p, err := manager.Put(p)
+		if err != nil {
+			return nil, reconnectError(err)
+		}
+
+		return &DeviceReconnectionOutput{DeviceReconnection{p, manager.Status(input.Address)}}, nil
+	})
+}
+
+// removeDeviceReconnectEndpoint registers the path "/device/{address}/reconnect" (DELETE).
+func removeDeviceReconnectEndpoint(api huma.API, manager *reconnect.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "device-reconnect-remove",
+		Method:      http.MethodDelete,
+		Path:        "/device/{address}/reconnect",
+		Summary:     "Remove Policy",
+		Description: "Removes the reconnection policy of a device, and cancels its running reconnection.",
+		Tags:        []string{"Reconnection"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*struct{}, error) {
+		return nil, reconnectError(manager.Delete(input.Address))
+	})
+}
+
+// reconnectError converts the "not found" and "invalid" reconnection errors to 404 and 422 status errors.
+func reconnectError(err error) error {
+	switch {
+	case errors.Is(err, reconnect.ErrPolicyNotFound):
+		return huma.Error404NotFound(err.Error())
+
+	case errors.Is(err, reconnect.ErrInvalidPolicy):
+		return huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	return err
+}
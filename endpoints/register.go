@@ -1,20 +1,120 @@
 package endpoints
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/bluetuith-org/bluerestd/metrics"
+	"github.com/bluetuith-org/bluerestd/presence"
+	"github.com/bluetuith-org/bluerestd/proximity"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluerestd/registry"
+	"github.com/bluetuith-org/bluerestd/setup"
+	"github.com/bluetuith-org/bluerestd/supervisor"
+	"github.com/bluetuith-org/bluerestd/tracing"
+	"github.com/bluetuith-org/bluerestd/visibility"
+	"github.com/bluetuith-org/bluerestd/webhooks"
 	ac "github.com/bluetuith-org/bluetooth-classic/api/appfeatures"
 	bluetooth "github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humago"
 )
 
+// Options holds the daemon components that are used by the endpoints.
+type Options struct {
+	// Hub is used to stream events to the clients of the "/events" endpoint.
+	Hub *events.Hub
+
+	// Webhooks manages the webhook subscriptions.
+	Webhooks *webhooks.Manager
+
+	// Metrics records the request and event stream metrics, and is served at the "/metrics" path.
+	Metrics *metrics.Metrics
+
+	// Audit records the state-changing operations, and is queried at the "/admin/audit" path.
+	Audit *audit.Log
+
+	// Cache holds the state of the session, which is used to serve the read endpoints.
+	// If it is nil, the read endpoints always fetch the data from the session.
+	Cache *cache.Cache
+
+	// Discovery manages the discovery sessions of the adapters.
+	Discovery *discovery.Manager
+
+	// Presence tracks the presence of devices, and is queried at the "/presence" path.
+	Presence *presence.Tracker
+
+	// Proximity tracks the signal strength and proximity of devices, and is queried at the "/proximity" path.
+	Proximity *proximity.Tracker
+
+	// Registry holds the persistent device metadata, which is managed at the "/registry" path,
+	// and merged into the device properties.
+	Registry *registry.Registry
+
+	// Access holds the trusted and blocked devices, which are managed at the "/device/{address}/trust"
+	// and "/device/{address}/block" paths.
+	Access *access.Policy
+
+	// Reconnect reconnects to the devices with a reconnection policy, which are managed at the "/reconnect" path
+	// and the "/device/{address}/reconnect" path.
+	Reconnect *reconnect.Manager
+
+	// Setup runs the one-shot device setups, which are managed at the "/adapter/{address}/setup" path.
+	Setup *setup.Manager
+
+	// Visibility enforces the discoverable and pairable timeouts of the adapters, which are managed
+	// at the "/adapter/{address}/config" path.
+	Visibility *visibility.Manager
+
+	// Version and Revision hold the version of the daemon.
+	Version, Revision string
+
+	// Supervisor supervises the session. While the session is down, the session-dependent
+	// endpoints return a 503 status, with a "Retry-After" header of the next restart attempt.
+	Supervisor *supervisor.Supervisor
+
+	// Tracing enables tracing of each API operation.
+	Tracing bool
+}
+
 // Register selectively registers endpoints based on the available features of the session.
-func Register(router *http.ServeMux, session bluetooth.Session, features ac.FeatureSet) huma.API {
+func Register(router *http.ServeMux, session bluetooth.Session, features ac.FeatureSet, opts Options) huma.API {
 	api := registerAPI(router)
+	api.UseMiddleware(logging.Middleware)
+	api.UseMiddleware(sessionMiddleware(api, opts.Supervisor))
+
+	if opts.Tracing {
+		api.UseMiddleware(tracing.Middleware)
+	}
 
-	adapterEndpoints(api, session)
-	deviceEndpoints(api, session)
+	if opts.Metrics != nil {
+		router.Handle("GET /metrics", opts.Metrics.Handler())
+		api.UseMiddleware(metricsMiddleware(opts.Metrics))
+	}
+
+	if opts.Audit != nil {
+		api.UseMiddleware(auditMiddleware(opts.Audit))
+	}
+
+	adapterEndpoints(api, session, opts)
+	deviceEndpoints(api, session, opts)
+
+	if features.Has(ac.FeaturePairing) {
+		accessEndpoints(api, opts.Access)
+		setupEndpoints(api, opts.Setup)
+	}
+
+	if features.Has(ac.FeatureConnection) {
+		reconnectEndpoints(api, opts.Reconnect)
+	}
 
 	if features.Has(ac.FeatureSendFile, ac.FeatureReceiveFile) {
 		obexEndpoints(api, session)
@@ -25,14 +125,74 @@ func Register(router *http.ServeMux, session bluetooth.Session, features ac.Feat
 	}
 
 	if features.Has(ac.FeatureMediaPlayer) {
-		mediaPlayerEndpoints(api, session)
+		mediaPlayerEndpoints(api, session, opts.Cache)
 	}
 
-	sessionEndpoints(api, session)
+	discoveryEndpoints(api, opts.Discovery)
+	presenceEndpoints(api, opts.Presence)
+	proximityEndpoints(api, opts.Proximity)
+	registryEndpoints(api, session, opts.Registry, opts.Reconnect)
+	sessionEndpoints(api, session, opts)
+	healthEndpoints(api, session, opts)
+	webhookEndpoints(api, opts.Webhooks)
+	auditEndpoints(api, opts.Audit)
 
 	return api
 }
 
+// metricsMiddleware returns a middleware which records the status and latency of each request.
+func metricsMiddleware(m *metrics.Metrics) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		start := time.Now()
+
+		next(ctx)
+
+		m.ObserveRequest(ctx.Operation().OperationID, ctx.Status(), time.Since(start))
+	}
+}
+
+// sessionMiddleware returns a middleware which rejects the requests to the session-dependent endpoints
+// with a 503 status while the session is down. The "Retry-After" header is set to the time until the
+// next restart attempt of the session.
+func sessionMiddleware(api huma.API, sup *supervisor.Supervisor) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		retryAfter := sup.RetryAfter()
+		if retryAfter == 0 || !requiresSession(ctx.Operation()) {
+			ctx.SetHeader("Retry-After", "10")
+			next(ctx)
+
+			return
+		}
+
+		status := sup.Status()
+
+		ctx.SetHeader("Retry-After", strconv.Itoa(retryAfter))
+		huma.WriteErr(api, ctx, http.StatusServiceUnavailable,
+			fmt.Sprintf("The Bluetooth session is %s (%s), please retry after %d seconds.", status.State, status.Reason, retryAfter),
+		)
+	}
+}
+
+// requiresSession returns whether the operation requires the session to be up.
+func requiresSession(op *huma.Operation) bool {
+	switch op.OperationID {
+	case "adapters", "snapshot", "registry-group-connect", "registry-group-disconnect":
+		return true
+
+	case "device-access":
+		return false
+	}
+
+	for _, tag := range op.Tags {
+		switch tag {
+		case "Adapter", "Device", "Discovery", "Setup", "Network", "File Transfer", "Media Player":
+			return true
+		}
+	}
+
+	return false
+}
+
 // registerAPI registers the endpoints to the router.
 func registerAPI(router *http.ServeMux) huma.API {
 	config := huma.DefaultConfig("", "")
@@ -44,11 +204,6 @@ func registerAPI(router *http.ServeMux) huma.API {
 	})
 
 	api := humago.New(router, config)
-	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
-		ctx.SetHeader("Retry-After", "10")
-		next(ctx)
-	})
-
 	api.OpenAPI().Info = staticAPIInfo
 
 	for tag, desc := range staticTagDescriptions {
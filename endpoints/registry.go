@@ -0,0 +1,239 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/profiles"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluerestd/registry"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// RegisteredDevice describes the properties of a device, with its decoded class, vendor, registry entry and the names of its service profiles.
+type RegisteredDevice struct {
+	IdentifiedDevice
+
+	Registry *registry.Metadata `doc:"The registry entry of the device, if it exists." json:"registry,omitempty"`
+	Profiles []profiles.Profile `doc:"The known service profiles of the device, resolved from its profile UUIDs." json:"profiles,omitempty"`
+}
+
+// groupResult describes the result of a group operation on a single device.
+type groupResult struct {
+	Address bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Error   string               `doc:"The error of the operation, if it failed." json:"error,omitempty"`
+}
+
+// GroupInput is used as the general input parameter for a registry group.
+type GroupInput struct {
+	Group string `doc:"The name of the group." path:"group"`
+}
+
+// registryEndpoints registers the endpoints for the "Registry" tagged endpoints.
+func registryEndpoints(api huma.API, session bluetooth.Session, r *registry.Registry, reconnects *reconnect.Manager) {
+	registryEntriesEndpoint(api, r)
+	registryEntryEndpoint(api, r)
+	putRegistryEntryEndpoint(api, r)
+	removeRegistryEntryEndpoint(api, r)
+	registryGroupsEndpoint(api, r)
+	groupOperationEndpoint(api, session, r, reconnects, "connect")
+	groupOperationEndpoint(api, session, r, reconnects, "disconnect")
+}
+
+// registryEntriesEndpoint registers the path "/registry".
+func registryEntriesEndpoint(api huma.API, r *registry.Registry) {
+	type RegistryEntriesOutput struct {
+		Body []registry.Metadata
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "registry",
+		Method:      http.MethodGet,
+		Path:        "/registry",
+		Summary:     "Entries",
+		Description: "Fetches the registry entries of all devices.",
+		Tags:        []string{"Registry"},
+	}, func(_ context.Context, input *struct {
+		Group string `doc:"Only fetch the entries of the devices in this group." query:"group"`
+		Tag   string `doc:"Only fetch the entries of the devices with this tag." query:"tag"`
+	},
+	) (*RegistryEntriesOutput, error) {
+		return &RegistryEntriesOutput{r.Entries(input.Group, input.Tag)}, nil
+	})
+}
+
+// registryEntryEndpoint registers the path "/registry/{address}".
+func registryEntryEndpoint(api huma.API, r *registry.Registry) {
+	type RegistryEntryOutput struct {
+		Body registry.Metadata
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "registry-entry",
+		Method:      http.MethodGet,
+		Path:        "/registry/{address}",
+		Summary:     "Entry",
+		Description: "Fetches the registry entry of a device.",
+		Tags:        []string{"Registry"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*RegistryEntryOutput, error) {
+		entry, ok := r.Entry(input.Address)
+		if !ok {
+			return nil, registryError(registry.ErrEntryNotFound)
+		}
+
+		return &RegistryEntryOutput{entry}, nil
+	})
+}
+
+// putRegistryEntryEndpoint registers the path "/registry/{address}" (PUT).
+func putRegistryEntryEndpoint(api huma.API, r *registry.Registry) {
+	type PutRegistryEntryInput struct {
+		Body struct {
+			Label  string   `doc:"The friendly label of the device." example:"Living room speaker" json:"label,omitempty"`
+			Notes  string   `doc:"Free-form notes about the device." json:"notes,omitempty"`
+			Owner  string   `doc:"The owner of the device." json:"owner,omitempty"`
+			Tags   []string `doc:"The tags of the device." example:"audio" json:"tags,omitempty"`
+			Groups []string `doc:"The groups which the device is a member of." example:"living-room" json:"groups,omitempty"`
+		}
+	}
+
+	type PutRegistryEntryOutput struct {
+		Body registry.Metadata
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "registry-put",
+		Method:      http.MethodPut,
+		Path:        "/registry/{address}",
+		Summary:     "Set Entry",
+		Description: "Creates or replaces the registry entry of a device. The device does not need to be known to the session.",
+		Tags:        []string{"Registry"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		PutRegistryEntryInput
+	},
+	) (*PutRegistryEntryOutput, error) {
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["label"] = input.Body.Label
+			entry.Params["groups"] = input.Body.Groups
+		})
+
+		entry, err := r.Put(registry.Metadata{
+			Address: input.Address,
+			Label:   input.Body.Label,
+			Notes:   input.Body.Notes,
+			Owner:   input.Body.Owner,
+			Tags:    input.Body.Tags,
+			Groups:  input.Body.Groups,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &PutRegistryEntryOutput{entry}, nil
+	})
+}
+
+// removeRegistryEntryEndpoint registers the path "/registry/{address}" (DELETE).
+func removeRegistryEntryEndpoint(api huma.API, r *registry.Registry) {
+	huma.Register(api, huma.Operation{
+		OperationID: "registry-remove",
+		Method:      http.MethodDelete,
+		Path:        "/registry/{address}",
+		Summary:     "Remove Entry",
+		Description: "Removes the registry entry of a device.",
+		Tags:        []string{"Registry"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*struct{}, error) {
+		return nil, registryError(r.Delete(input.Address))
+	})
+}
+
+// registryGroupsEndpoint registers the path "/registry/groups".
+func registryGroupsEndpoint(api huma.API, r *registry.Registry) {
+	type RegistryGroupsOutput struct {
+		Body []registry.Group
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "registry-groups",
+		Method:      http.MethodGet,
+		Path:        "/registry/groups",
+		Summary:     "Groups",
+		Description: "Fetches all groups, with the addresses of their member devices.",
+		Tags:        []string{"Registry"},
+	}, func(_ context.Context, _ *struct{}) (*RegistryGroupsOutput, error) {
+		return &RegistryGroupsOutput{r.Groups()}, nil
+	})
+}
+
+// groupOperationEndpoint registers the path "/registry/groups/{group}/{operation}",
+// where the operation is either "connect" or "disconnect". The disconnections are marked as requested,
+// so that they do not trigger reconnections.
+func groupOperationEndpoint(api huma.API, session bluetooth.Session, r *registry.Registry, reconnects *reconnect.Manager, operation string) {
+	type GroupOperationOutput struct {
+		Body []groupResult
+	}
+
+	summary, call := "Connect Group", bluetooth.Device.Connect
+	if operation == "disconnect" {
+		summary, call = "Disconnect Group", bluetooth.Device.Disconnect
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "registry-group-" + operation,
+		Method:      http.MethodGet,
+		Path:        "/registry/groups/{group}/" + operation,
+		Summary:     summary,
+		Description: "Attempts to " + operation + " each device in a group, one after the other, and returns the result for each device. A device failing to " + operation + " does not stop the operation on the other devices.",
+		Tags:        []string{"Registry"},
+	}, func(ctx context.Context, input *GroupInput) (*GroupOperationOutput, error) {
+		members, err := r.Members(input.Group)
+		if err != nil {
+			return nil, registryError(err)
+		}
+
+		results := make([]groupResult, 0, len(members))
+		for _, address := range members {
+			result := groupResult{Address: address}
+			if operation == "disconnect" {
+				reconnects.Disconnecting(address)
+			}
+
+			if err := call(sessionFor(ctx, session).Device(address)); err != nil {
+				result.Error = err.Error()
+			}
+
+			results = append(results, result)
+		}
+
+		return &GroupOperationOutput{results}, nil
+	})
+}
+
+// registered returns the device properties with the decoded class and vendor, the registry entry of the device, if any, and its known service profiles.
+func registered(r *registry.Registry, device bluetooth.DeviceData) RegisteredDevice {
+	registered := RegisteredDevice{IdentifiedDevice: identified(device), Profiles: profiles.Names(device.UUIDs)}
+	if entry, ok := r.Entry(device.Address); ok {
+		registered.Registry = &entry
+	}
+
+	return registered
+}
+
+// registryError converts the "not found" registry errors to a 404 status error.
+func registryError(err error) error {
+	if errors.Is(err, registry.ErrEntryNotFound) || errors.Is(err, registry.ErrGroupNotFound) {
+		return huma.Error404NotFound(err.Error())
+	}
+
+	return err
+}
@@ -3,25 +3,157 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/logging"
+	"github.com/bluetuith-org/bluerestd/metrics"
+	"github.com/bluetuith-org/bluerestd/presence"
+	"github.com/bluetuith-org/bluerestd/profiles"
+	"github.com/bluetuith-org/bluerestd/proximity"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluerestd/setup"
+	"github.com/bluetuith-org/bluerestd/supervisor"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
-	"github.com/bluetuith-org/bluetooth-classic/api/eventbus"
+	"github.com/bluetuith-org/bluetooth-classic/api/platforminfo"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/sse"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// eventTypes maps the name of each event to its data type.
+var eventTypes = map[string]any{
+	"auth":         authRequestEvent{},
+	"adapter":      bluetooth.AdapterEvent(),
+	"error":        bluetooth.ErrorEvent(),
+	"device":       identifiedDeviceEvent{},
+	"mediaplayer":  bluetooth.MediaEvent(),
+	"filetransfer": bluetooth.FileTransferEvent(),
+	"session":      supervisor.Status{},
+	"presence":     presence.Event{},
+	"proximity":    proximity.Change{},
+	"setup":        setup.Setup{},
+	"reconnect":    reconnect.Reconnection{},
+	"resync":       resyncEvent{},
+}
+
+// eventStreams holds the number of clients subscribed to the "/events" endpoint.
+var eventStreams atomic.Int64
+
 // sessionEndpoints registers the endpoints for the "Session" tagged endpoints.
-func sessionEndpoints(api huma.API, session bluetooth.Session) {
-	eventsEndpoint(api)
+func sessionEndpoints(api huma.API, session bluetooth.Session, opts Options) {
+	eventsEndpoint(api, opts.Hub, opts.Cache, opts.Metrics)
 	authEndpoint(api)
 
-	adaptersEndpoint(api, session)
+	adaptersEndpoint(api, session, opts.Cache)
+	snapshotEndpoint(api, opts.Cache)
+	sessionInfoEndpoint(api, opts)
+	profilesEndpoint(api)
+}
+
+// snapshotEndpoint registers the path "/snapshot".
+func snapshotEndpoint(api huma.API, c *cache.Cache) {
+	type SnapshotOutput struct {
+		Body struct {
+			Seq          uint64                            "doc:\"The sequence number of the last event that is reflected in the snapshot. Use it as the `since` parameter of the `/events` endpoint to receive all later events.\" json:\"seq\""
+			Adapters     []bluetooth.AdapterData           `doc:"All available adapters." json:"adapters"`
+			Devices      []bluetooth.DeviceData            `doc:"The devices of all adapters." json:"devices"`
+			MediaPlayers []bluetooth.MediaEventData        `doc:"The media players of the devices, whose properties have been fetched or reported." json:"media_players"`
+			Transfers    []bluetooth.FileTransferEventData `doc:"The progress of the active file transfers." json:"transfers"`
+			AuthRequests []authRequestEvent                `doc:"The authorization requests that are waiting for a reply." json:"auth_requests"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "snapshot",
+		Method:      http.MethodGet,
+		Path:        "/snapshot",
+		Summary:     "Snapshot",
+		Tags:        []string{"Session"},
+		Description: "Fetches the complete state of the session, along with the sequence number of the last event that it reflects, to bootstrap clients without gaps.",
+	}, func(_ context.Context, _ *struct{}) (*SnapshotOutput, error) {
+		snapshot := c.Snapshot()
+		output := &SnapshotOutput{}
+
+		output.Body.Seq = snapshot.Seq
+		output.Body.Adapters = snapshot.Adapters
+		output.Body.Devices = snapshot.Devices
+		output.Body.MediaPlayers = snapshot.MediaPlayers
+		output.Body.Transfers = snapshot.Transfers
+		output.Body.AuthRequests = pendingAuthRequests()
+
+		return output, nil
+	})
+}
+
+// profilesEndpoint registers the path "/profiles".
+func profilesEndpoint(api huma.API) {
+	type ProfilesOutput struct {
+		Body []profiles.Profile
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "profiles",
+		Method:      http.MethodGet,
+		Path:        "/profiles",
+		Summary:     "Profiles",
+		Tags:        []string{"Session"},
+		Description: "Fetches the built-in registry of Bluetooth service profiles. The short name of a profile can be used in place of its UUID while connecting to or disconnecting from a device.",
+	}, func(_ context.Context, _ *struct{}) (*ProfilesOutput, error) {
+		return &ProfilesOutput{profiles.All()}, nil
+	})
+}
+
+// sessionInfoEndpoint registers the path "/session/info".
+func sessionInfoEndpoint(api huma.API, opts Options) {
+	type SessionInfoOutput struct {
+		Body struct {
+			Version             string                    `doc:"The version of the daemon." json:"version"`
+			Revision            string                    `doc:"The revision of the daemon." json:"revision"`
+			Platform            platforminfo.PlatformInfo `doc:"The Bluetooth stack and operating system of the session." json:"platform"`
+			SupportedFeatures   []featureStatus           `doc:"The features supported by the session." json:"supported_features"`
+			UnavailableFeatures []featureStatus           `doc:"The features that are not available, with the error that occurred while enabling each feature, if any." json:"unavailable_features"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "session-info",
+		Method:      http.MethodGet,
+		Path:        "/session/info",
+		Summary:     "Session Information",
+		Tags:        []string{"Session"},
+		Description: "Fetches the version of the daemon, the Bluetooth stack and operating system of the session, and the supported and unavailable features. The endpoints of unavailable features are not registered.",
+	}, func(_ context.Context, _ *struct{}) (*SessionInfoOutput, error) {
+		info := &SessionInfoOutput{}
+
+		info.Body.Version = opts.Version
+		info.Body.Revision = opts.Revision
+		info.Body.Platform = opts.Supervisor.Platform()
+		info.Body.SupportedFeatures = []featureStatus{}
+		info.Body.UnavailableFeatures = []featureStatus{}
+
+		for _, feature := range featureStatuses(opts.Supervisor.Features()) {
+			if feature.Supported {
+				info.Body.SupportedFeatures = append(info.Body.SupportedFeatures, feature)
+			} else {
+				info.Body.UnavailableFeatures = append(info.Body.UnavailableFeatures, feature)
+			}
+		}
+
+		return info, nil
+	})
 }
 
 // adaptersEndpoint registers the path "/adapters".
-func adaptersEndpoint(api huma.API, session bluetooth.Session) {
+func adaptersEndpoint(api huma.API, session bluetooth.Session, c *cache.Cache) {
 	type AdaptersOutput struct {
+		CacheOutput
 		Body []bluetooth.AdapterData
 	}
 
@@ -32,8 +164,26 @@ func adaptersEndpoint(api huma.API, session bluetooth.Session) {
 		Summary:     "Adapters",
 		Tags:        []string{"Session"},
 		Description: "Fetches all available adapters.",
-	}, func(_ context.Context, _ *struct{}) (*AdaptersOutput, error) {
-		return &AdaptersOutput{session.Adapters()}, nil
+	}, func(ctx context.Context, input *struct {
+		CacheInput
+	},
+	) (*AdaptersOutput, error) {
+		adapters, headers, err := cachedRead(ctx, c, &input.CacheInput, func(fresh bool) ([]bluetooth.AdapterData, time.Time, error) {
+			if !fresh {
+				if adapters, updatedAt, ok := c.Adapters(); ok {
+					return adapters, updatedAt, nil
+				}
+			}
+
+			adapters := session.Adapters()
+
+			return adapters, c.StoreAdapters(adapters), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &AdaptersOutput{headers, adapters}, nil
 	})
 }
 
@@ -46,7 +196,7 @@ func authEndpoint(api huma.API) {
 		Summary:     "Authorization",
 		Tags:        []string{"Session"},
 		Description: "Enables responses to authorization requests, like device pairing or receiving file transfers.",
-	}, func(_ context.Context, input *struct {
+	}, func(ctx context.Context, input *struct {
 		Reply  string `doc:"The reply to an authorization request." enum:"yes,no" example:"yes" json:"reply,omitempty" path:"reply"`
 		Reason string "doc:\"An optional user-specified reason if the reply is `no`.\" example:\"The user did not accept the request.\" json:\"reason,omitempty\" query:\"reason\""
 		ID     int64  "doc:\"The authorization ID provided by the `auth` event.\" example:\"1\" path:\"auth_id\""
@@ -56,43 +206,87 @@ func authEndpoint(api huma.API) {
 			return nil, errors.New("invalid authorization ID")
 		}
 
-		ch, ok := requests.LoadAndDelete(input.ID)
+		request, ok := requests.LoadAndDelete(input.ID)
 		if !ok {
 			return nil, errors.New("authorization ID not found")
 		}
 
-		ch <- authEventReply{input.Reason, input.Reply == "yes"}
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Int64("auth.id", input.ID))
+		span.AddLink(trace.Link{SpanContext: request.span})
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["auth_type"] = request.event.AuthType
+			if input.Reason != "" {
+				entry.Params["reason"] = input.Reason
+			}
+
+			switch {
+			case request.event.PairingParams != nil:
+				entry.Params["pairing_type"] = request.event.PairingParams.PairingType
+				entry.Device = request.event.PairingParams.Address.String()
+
+			case request.event.TransferParams != nil:
+				entry.Params["file_name"] = request.event.TransferParams.FileProperties.Name
+				entry.Device = request.event.TransferParams.FileProperties.Address.String()
+			}
+		})
+
+		slog.InfoContext(ctx, "Authorization request answered",
+			"auth_id", input.ID, "reply", input.Reply,
+		)
+
+		request.reply <- authEventReply{
+			reason:    input.Reason,
+			reply:     input.Reply == "yes",
+			span:      span.SpanContext(),
+			requestID: logging.RequestID(ctx),
+		}
 
 		return nil, nil
 	})
 }
 
 // eventsEndpoint registers the path "/events".
-func eventsEndpoint(api huma.API) {
-	newPublisher := func(sender sse.Sender) (*eventPublisher, eventbus.EventSubscriber) {
-		eh := eventbus.NilHandler()
-
-		return &eventPublisher{sender}, eh
-	}
-
+func eventsEndpoint(api huma.API, hub *events.Hub, c *cache.Cache, m *metrics.Metrics) {
 	sse.Register(api, huma.Operation{
 		OperationID: "events",
 		Method:      http.MethodGet,
 		Path:        "/events",
 		Tags:        []string{"Session"},
 		Summary:     "Events",
-		Description: "Subscribe to this EventSource for all Bluetooth events. For documentation on each watchable event, look at the *Responses* section.",
-	}, map[string]any{
-		"auth":         authRequestEvent{},
-		"adapter":      bluetooth.AdapterEvent(),
-		"error":        bluetooth.ErrorEvent(),
-		"device":       bluetooth.DeviceEvent(),
-		"mediaplayer":  bluetooth.MediaEvent(),
-		"filetransfer": bluetooth.FileTransferEvent(),
-	}, func(ctx context.Context, _ *struct{}, send sse.Sender) {
-		eventbus.RegisterEventHandlers(newPublisher(send))
-		defer eventbus.DisableEvents()
-
-		<-ctx.Done()
+		Description: "Subscribe to this EventSource for all Bluetooth events. The ID of each event is its sequence number. For documentation on each watchable event, look at the *Responses* section.",
+	}, eventTypes, func(ctx context.Context, input *EventsInput, send sse.Sender) {
+		publisher := &eventPublisher{send, c}
+
+		subscriber, retained := hub.Subscribe(), true
+		if input.resume {
+			subscriber, retained = hub.SubscribeSince(input.since)
+		}
+		defer subscriber.Unsubscribe()
+
+		if !retained {
+			if err := send.Data(resyncEvent{Since: input.since, Seq: hub.Seq()}); err != nil {
+				return
+			}
+		}
+
+		m.SubscriberAdded()
+		defer m.SubscriberRemoved()
+
+		eventStreams.Add(1)
+		defer eventStreams.Add(-1)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case ev := <-subscriber.C:
+				if err := publisher.Publish(ev); err != nil {
+					return
+				}
+			}
+		}
 	})
 }
@@ -0,0 +1,190 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/setup"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// SetupInput is used as the general input parameter for a setup ID.
+type SetupInput struct {
+	ID string `doc:"The ID of the setup." path:"setup_id"`
+}
+
+// SetupOutput is used as the output of the setup endpoints.
+type SetupOutput struct {
+	Body setup.Setup
+}
+
+// setupEndpoints registers the endpoints for the "Setup" tagged endpoints.
+func setupEndpoints(api huma.API, manager *setup.Manager) {
+	createSetupEndpoint(api, manager)
+	setupsEndpoint(api, manager)
+	setupEndpoint(api, manager)
+	cancelSetupEndpoint(api, manager)
+}
+
+// createSetupEndpoint registers the path "/adapter/{address}/setup" (POST).
+func createSetupEndpoint(api huma.API, manager *setup.Manager) {
+	type CreateSetupInput struct {
+		Body struct {
+			Address          string    `doc:"The address of the device. Either this or the name must be provided." example:"11:22:33:AA:BB:CC" json:"address,omitempty"`
+			Name             string    `doc:"Set up the first discovered device whose name or alias contains this text (case-insensitive)." example:"Headset" json:"name,omitempty"`
+			DiscoveryTimeout int       `default:"30" doc:"The number of seconds after which the setup fails, if no matching device was discovered." maximum:"300" minimum:"1" json:"discovery_timeout"`
+			Auth             string    "default:\"confirm\" doc:\"How the authorization requests of the device are answered: `manual` sends them as *auth* events, `confirm` accepts them and confirms any passkey, and `pin` only confirms a passkey or pincode that matches the `pin`, and rejects pairing without a passkey.\" enum:\"manual,confirm,pin\" json:\"auth\""
+			PIN              string    "doc:\"The expected passkey or pincode of the `pin` authorization policy. It is compared with the passkey or pincode displayed by the device, or the passkey to be confirmed.\" example:\"123456\" json:\"pin,omitempty\" maxLength:\"16\""
+			Trust            bool      `doc:"Trust the device after it is paired (on Linux, also in BlueZ), so that its service authorization requests are accepted automatically." json:"trust,omitempty"`
+			ProfileUUID      uuid.UUID `doc:"The Bluetooth service profile UUID to connect to. If it is not provided, a profile is chosen automatically." example:"0000110b-0000-1000-8000-00805f9b34fb" format:"uuid" json:"profile_uuid,omitempty"`
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "setup-create",
+		Method:        http.MethodPost,
+		Path:          "/adapter/{address}/setup",
+		Summary:       "Start Setup",
+		Description:   "Starts setting up a device on an adapter: the device is discovered (if it is not known to the adapter), paired with (if it is not paired), optionally trusted, and connected to. The progress is published as *setup* events, and can be fetched using the `/adapter/{address}/setup/{setup_id}` endpoint. If any step fails, the completed steps are rolled back.",
+		Tags:          []string{"Setup"},
+		DefaultStatus: http.StatusAccepted,
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		CreateSetupInput
+	},
+	) (*SetupOutput, error) {
+		body := input.Body
+
+		req := setup.Request{
+			Adapter:          input.Address,
+			Name:             body.Name,
+			DiscoveryTimeout: time.Duration(body.DiscoveryTimeout) * time.Second,
+			Auth:             setup.Auth(body.Auth),
+			PIN:              body.PIN,
+			Trust:            body.Trust,
+			Profile:          body.ProfileUUID,
+			Owner:            audit.Caller(ctx),
+		}
+
+		if body.Address != "" {
+			address, err := bluetooth.ParseMAC(body.Address)
+			if err != nil {
+				return nil, huma.Error422UnprocessableEntity("The device address is invalid.", &huma.ErrorDetail{
+					Message: err.Error(), Location: "body.address", Value: body.Address,
+				})
+			}
+
+			req.Address = address
+		}
+
+		switch {
+		case body.Address == "" && body.Name == "":
+			return nil, huma.Error422UnprocessableEntity("Either the address or the name of the device must be provided.")
+
+		case req.Auth == setup.AuthPIN && req.PIN == "":
+			return nil, huma.Error422UnprocessableEntity("The `pin` authorization policy requires a PIN.")
+		}
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["device"] = body.Address
+			entry.Params["name"] = body.Name
+			entry.Params["auth"] = body.Auth
+			entry.Params["trust"] = body.Trust
+		})
+
+		s, err := manager.Create(req)
+		if err != nil {
+			return nil, setupError(err)
+		}
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["setup_id"] = s.ID
+		})
+
+		return &SetupOutput{s}, nil
+	})
+}
+
+// setupsEndpoint registers the path "/adapter/{address}/setup".
+func setupsEndpoint(api huma.API, manager *setup.Manager) {
+	type SetupsOutput struct {
+		Body []setup.Setup
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "setups",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/setup",
+		Summary:     "Setups",
+		Description: "Fetches the running and recently finished setups of an adapter.",
+		Tags:        []string{"Setup"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+	},
+	) (*SetupsOutput, error) {
+		return &SetupsOutput{manager.Setups(input.Address)}, nil
+	})
+}
+
+// setupEndpoint registers the path "/adapter/{address}/setup/{setup_id}".
+func setupEndpoint(api huma.API, manager *setup.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "setup",
+		Method:      http.MethodGet,
+		Path:        "/adapter/{address}/setup/{setup_id}",
+		Summary:     "Setup",
+		Description: "Fetches the progress of a setup.",
+		Tags:        []string{"Setup"},
+	}, func(_ context.Context, input *struct {
+		AddressInput
+		SetupInput
+	},
+	) (*SetupOutput, error) {
+		s, err := manager.Setup(input.Address, input.ID)
+		if err != nil {
+			return nil, setupError(err)
+		}
+
+		return &SetupOutput{s}, nil
+	})
+}
+
+// cancelSetupEndpoint registers the path "/adapter/{address}/setup/{setup_id}" (DELETE).
+func cancelSetupEndpoint(api huma.API, manager *setup.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "setup-cancel",
+		Method:      http.MethodDelete,
+		Path:        "/adapter/{address}/setup/{setup_id}",
+		Summary:     "Cancel Setup",
+		Description: "Cancels a running setup, whose completed steps are then rolled back. Cancelling a finished setup has no effect. A 404 status is returned if the setup was created by another caller.",
+		Tags:        []string{"Setup"},
+	}, func(ctx context.Context, input *struct {
+		AddressInput
+		SetupInput
+	},
+	) (*struct{}, error) {
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["setup_id"] = input.ID
+		})
+
+		return nil, setupError(manager.Cancel(input.Address, input.ID, audit.Caller(ctx)))
+	})
+}
+
+// setupError converts the "not found" and "running" setup errors to 404 and 409 status errors.
+func setupError(err error) error {
+	switch {
+	case errors.Is(err, setup.ErrSetupNotFound):
+		return huma.Error404NotFound(err.Error())
+
+	case errors.Is(err, setup.ErrSetupRunning):
+		return huma.Error409Conflict(err.Error())
+	}
+
+	return err
+}
@@ -55,6 +55,18 @@ This is especially required for authenticating any pairing/file transfer request
 - For authorization requests, watch the *"auth"* event. All *"auth"* events return
   an authorization ID (auth_id), which can be used with the [Authorization endpoint](#tag/session/GET/auth/{auth_id}/{reply}). 
 - Then, to fetch a list of available adapters, use the [Adapters endpoint](#tag/session/GET/adapters).
+- To check which features are supported by the session before showing their controls, use the [Session Information endpoint](#tag/session/GET/session/info).
+- To list the known service profiles with their short names (for example, in a profile selection), use the [Profiles endpoint](#tag/session/GET/profiles).
+
+To bootstrap a client without missing any events, fetch the complete state with the [Snapshot endpoint](#tag/session/GET/snapshot),
+and then subscribe to the EventSource with the *since* parameter set to the *seq* of the snapshot. The ID of each event is its sequence number,
+so EventSource clients resume from the last received event on reconnection. If the requested events are no longer available
+(or the sequence number is ahead of the daemon, e.g. after a restart),
+a *"resync"* event is sent first, and a new snapshot should be fetched.
+
+If the Bluetooth service restarts, the session is restarted automatically, and if all adapters are removed, the session is down until an adapter is added.
+Watch the *"session"* event for changes of the session state. While the session is down, the adapter and device
+endpoints return a 503 status, with the *Retry-After* header set to the time until the next restart attempt.
 
 To interact with an adapter from the list, go to the [Adapter](#tag/adapter) section.
 `,
@@ -67,6 +79,8 @@ The **address** parameter refers to the **adapter's** Bluetooth address, and is
   [States endpoint](#tag/adapter/GET/adapter/{address}/states).
 - To view the properties of the adapter, use the [Properties endpoint](#tag/adapter/GET/adapter/{address}/properties).
 - To fetch a list of devices associated with this adapter, use the [Devices endpoint](#tag/adapter/GET/adapter/{address}/devices).
+- To set the discoverable and pairable timeouts of the adapter, or to make it discoverable for a number of seconds, use the
+  [Configure endpoint](#tag/adapter/PATCH/adapter/{address}/config). The timeouts are enforced by the daemon.
 
 To interact with a device from the list, go to the [Device](#tag/device) section.
 
@@ -74,6 +88,55 @@ To interact with a device from the list, go to the [Device](#tag/device) section
 When discovery is enabled, all discovered devices will be published to the **/event** stream, with the *event_name* as *'device'*, and with *event_action* as *'added'*.
 `,
 
+	"Discovery": `
+These set of endpoints manage the discovery sessions of an adapter.
+
+A discovery session is created by a client with a duration and an optional device filter,
+and the device discovery of the adapter runs as long as it has any discovery sessions.
+Ending a session, or its expiry, does not affect the sessions of other clients.
+
+- Create a session using the [Start Discovery endpoint](#tag/discovery/POST/adapter/{address}/discovery), and note the 'discovery_id'.
+- Fetch the matching devices using the [Discovery Results endpoint](#tag/discovery/GET/adapter/{address}/discovery/{discovery_id}/results),
+  or subscribe to the [Discovery Events endpoint](#tag/discovery/GET/adapter/{address}/discovery/{discovery_id}/events).
+- End the session using the [Stop Discovery endpoint](#tag/discovery/DELETE/adapter/{address}/discovery/{discovery_id}).
+`,
+
+	"Setup": `
+These set of endpoints run the one-shot device setup, which replaces the separate discovery, pairing,
+authorization and connection steps when onboarding a new device.
+
+A setup takes the address of the device or a pattern of its name, a discovery timeout, an authorization policy,
+a trust flag and an optional service profile UUID. The device is discovered (if it is not known to the adapter),
+paired with (if it is not paired), optionally trusted, and connected to. During the setup, the authorization
+requests of the device are answered by the authorization policy of the setup, instead of being sent as *"auth"* events,
+unless the *manual* policy is used. If any step fails, or the setup is cancelled, the completed steps are rolled back.
+
+- Start a setup using the [Start Setup endpoint](#tag/setup/POST/adapter/{address}/setup), and note the 'setup_id'.
+- Watch the *"setup"* event in the [Events endpoint](#tag/session/GET/events) for the progress of the setup,
+  or fetch it using the [Setup endpoint](#tag/setup/GET/adapter/{address}/setup/{setup_id}).
+- Cancel a running setup using the [Cancel Setup endpoint](#tag/setup/DELETE/adapter/{address}/setup/{setup_id}).
+
+#### Note
+The Bluetooth backend does not request a pincode from the daemon. Instead, the *pin* policy only confirms a passkey
+or pincode of the device that matches the provided PIN.
+`,
+
+	"Presence": `
+These set of endpoints report the presence of devices, which is tracked from their sightings.
+
+A device arrives when it is seen with a signal stronger than the arrival threshold, and departs when it has not been
+seen with a signal stronger than the (lower) departure threshold within the away timeout. Connected devices do not depart.
+Watch the *"presence"* event in the [Events endpoint](#tag/session/GET/events) for arrivals and departures.
+
+- Fetch the presence of all seen devices using the [Presence endpoint](#tag/presence/GET/presence).
+- Fetch the presence of a single device using the [Device Presence endpoint](#tag/presence/GET/presence/{address}).
+
+The proximity of each device (*immediate*, *near*, *far* or *gone*) is estimated from its smoothed signal strength.
+Watch the *"proximity"* event for changes of the proximity of a device.
+
+- Fetch the proximity of all devices using the [Proximity endpoint](#tag/presence/GET/proximity).
+- Fetch the signal strength samples of a device using the [Device Proximity endpoint](#tag/presence/GET/proximity/{address}).
+`,
 	"Device": `
 These set of endpoints interact with an individual device.
 The **address** parameter refers to the **device's** Bluetooth address, and is required. 
@@ -99,6 +162,15 @@ Use the:
 - [Connect endpoint](#tag/device/GET/device/{address}/connect) and [Disconnect endpoint](#tag/device/GET/device/{address}/disconnect) to connect to/disconnect from a device.
 - [Properties endpoint](#tag/device/GET/device/{address}/properties) to view the properties of a device.
 - [Remove endpoint](#tag/device/GET/device/{address}/remove) to remove a device from its associated adapter. 
+
+## Trust and block
+The daemon keeps its own lists of trusted and blocked devices, which are enforced by its authorization agent.
+The service authorization requests of trusted devices (for example, when they reconnect) are accepted without an *auth* event,
+and all pairing, service and file transfer authorization requests of blocked devices are rejected without an *auth* event.
+
+Use the [Trust endpoint](#tag/device/GET/device/{address}/trust), [Untrust endpoint](#tag/device/GET/device/{address}/untrust),
+[Block endpoint](#tag/device/GET/device/{address}/block) and [Unblock endpoint](#tag/device/GET/device/{address}/unblock) to change the lists,
+and the [Access endpoint](#tag/device/GET/device/{address}/access) to check whether a device is trusted or blocked.
 `,
 
 	"Network": `
@@ -134,5 +206,67 @@ Then, watch the following events:
 - The *filetransfer* event, filter for the *updated* 'event_action' to get the status of the file transfers.
 
 To cancel an ongoing transfer, use the [Stop Transfers endpoint](#tag/file-transfer/GET/device/{address}/stop_file_transfer).
+`,
+
+	"Reconnection": `
+These set of endpoints manage the reconnection policies of devices, which are stored by the daemon.
+
+When a device with an enabled policy disconnects, the adapter of the device is powered on, or the daemon starts,
+the daemon reconnects to the device. Each attempt is made after the next delay of the backoff schedule of the policy,
+and only within the active hours of the policy, until the device is connected or the maximum number of attempts is reached.
+Watch the *"reconnect"* event in the [Events endpoint](#tag/session/GET/events) for changes of the reconnection status of a device.
+
+- Set the policy of a device using the [Set Policy endpoint](#tag/reconnection/PUT/device/{address}/reconnect).
+- Fetch the policy and the reconnection status of a device using the [Policy endpoint](#tag/reconnection/GET/device/{address}/reconnect),
+  or of all devices using the [Policies endpoint](#tag/reconnection/GET/reconnect).
+`,
+
+	"Registry": `
+These set of endpoints manage the device registry, which persistently stores metadata about devices
+(a friendly label, free-form notes, tags, an owner and group memberships) across daemon restarts.
+
+The registry entry of a device is included in the *registry* property of the [Devices endpoint](#tag/adapter/GET/adapter/{address}/devices)
+and the [Properties endpoint](#tag/device/GET/device/{address}/properties) of the device.
+
+- Set the entry of a device using the [Set Entry endpoint](#tag/registry/PUT/registry/{address}).
+- List the groups and their members using the [Groups endpoint](#tag/registry/GET/registry/groups).
+- Connect to or disconnect from all devices of a group using the [Connect Group endpoint](#tag/registry/GET/registry/groups/{group}/connect)
+  and the [Disconnect Group endpoint](#tag/registry/GET/registry/groups/{group}/disconnect).
+`,
+
+	"Webhooks": `
+These set of endpoints manage outgoing webhooks, for clients that cannot subscribe to the **/events** stream.
+
+Each webhook subscription has a target URL, an optional list of event names (as listed in the
+[Events endpoint](#tag/session/GET/events)) to filter for, and an optional secret.
+Events are delivered as a JSON object with the *event*, *delivery_id*, *timestamp* and *data* properties,
+using the POST method.
+
+If a secret is set, the *X-Bluerestd-Signature* header of each delivery holds the hex-encoded HMAC-SHA256
+signature of the request body, in the form of *sha256=&lt;signature&gt;*.
+
+Failed deliveries are retried with an exponential backoff. Deliveries that keep failing are moved to the
+dead-letter queue, which can be inspected using the [Dead Letters endpoint](#tag/webhooks/GET/admin/webhooks/dead_letters),
+and replayed using the [Replay endpoint](#tag/webhooks/POST/admin/webhooks/dead_letters/{dead_letter_id}/replay).
+`,
+
+	"Audit": `
+These set of endpoints query the audit log, which records each state-changing operation
+(for example, pairing, removing, connecting to or sending files to a device) and each reply to an authorization request.
+
+Each entry holds the time of the operation, the identity of the caller, the operation ID, the target adapter or device,
+the parameters and the outcome of the operation. For requests made over a UNIX socket, the identity of the caller
+includes the user and process IDs of the client, where supported.
+
+Use the [Audit Log endpoint](#tag/audit/GET/admin/audit) to fetch the entries, filtered by time range, address or operation.
+`,
+
+	"Health": `
+These set of endpoints report the health of the daemon and its Bluetooth session, and are meant to be used by supervisors and load balancers.
+
+- The [Liveness endpoint](#tag/health/GET/healthz) reports whether the daemon is running.
+- The [Readiness endpoint](#tag/health/GET/readyz) reports whether the session is started, and at least one adapter is present.
+- The [Status endpoint](#tag/health/GET/status) reports the session uptime, platform information, the status of each adapter and feature,
+  and the number of event subscribers.
 `,
 }
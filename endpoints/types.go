@@ -1,25 +1,91 @@
 package endpoints
 
 import (
+	"context"
+	"strconv"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/instrument"
+	"github.com/bluetuith-org/bluerestd/profiles"
 	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/sse"
+	"github.com/google/uuid"
 )
 
-// eventPublisher implements the eventbus.EventPublisher interface.
+// eventPublisher forwards events from the event hub to an event source.
 type eventPublisher struct {
 	sender sse.Sender
+	cache  *cache.Cache
 }
 
-// Publish sends the provided data to the registered event source.
-func (e *eventPublisher) Publish(id uint, _ string, data any) {
-	e.sender(sse.Message{
-		ID:    int(id),
+// Publish sends the provided event to the registered event source.
+// The data of device events is extended with the decoded class and vendor of the device.
+func (e *eventPublisher) Publish(ev events.Event) error {
+	data := ev.Data
+	if device, ok := data.(bluetooth.Event[bluetooth.DeviceEventData]); ok {
+		data = identifiedEvent(e.cache, device)
+	}
+
+	return e.sender(sse.Message{
+		ID:    int(ev.Seq),
 		Data:  data,
 		Retry: 0,
 	})
 }
 
+// resyncEvent describes a "resync" event, which is sent at the start of an event stream if the
+// events since the requested sequence number are no longer available.
+type resyncEvent struct {
+	Since uint64 "doc:\"The requested sequence number.\" json:\"since\""
+	Seq   uint64 "doc:\"The sequence number of the most recently published event. Fetch a new `/snapshot` to resynchronize the state.\" json:\"seq\""
+}
+
+// EventsInput is used as the input parameter of the "/events" endpoint.
+type EventsInput struct {
+	Since       string "doc:\"Replay the events which were published after this sequence number, usually the `seq` of a `/snapshot`, before streaming new events.\" example:\"42\" query:\"since\""
+	LastEventID string `doc:"The ID of the last received event, which is sent by EventSource clients on reconnection. The events after it are replayed." header:"Last-Event-ID"`
+
+	since  uint64
+	resume bool
+}
+
+// Resolve validates the sequence number to resume the event stream from.
+func (e *EventsInput) Resolve(_ huma.Context) []error {
+	value, location := e.Since, "query.since"
+	if value == "" {
+		value, location = e.LastEventID, "headers.Last-Event-ID"
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	since, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return []error{&huma.ErrorDetail{
+			Message:  "invalid sequence number",
+			Location: location,
+			Value:    value,
+		}}
+	}
+
+	e.since, e.resume = since, true
+
+	return nil
+}
+
+// sessionFor returns the session with the request's context attached to it,
+// so that the session calls can be traced and logged as part of the request.
+func sessionFor(ctx context.Context, session bluetooth.Session) bluetooth.Session {
+	if s, ok := session.(*instrument.Session); ok {
+		return s.WithContext(ctx)
+	}
+
+	return session
+}
+
 // AddressInput is used as the general input parameter for a Bluetooth address
 // while registering paths that require it.
 type AddressInput struct {
@@ -42,3 +108,30 @@ func (a *AddressInput) Resolve(_ huma.Context) []error {
 
 	return nil
 }
+
+// ProfileInput is used as the general input parameter for an optional service profile,
+// which is either a UUID or the short name of a built-in profile.
+type ProfileInput struct {
+	Input string `doc:"The Bluetooth service profile UUID, or the short name of a profile listed by the Profiles endpoint." example:"hid" query:"profile_uuid"`
+	UUID  uuid.UUID
+}
+
+// Resolve validates the input service profile.
+func (p *ProfileInput) Resolve(_ huma.Context) []error {
+	if p.Input == "" {
+		return nil
+	}
+
+	id, err := profiles.Resolve(p.Input)
+	if err != nil {
+		return []error{&huma.ErrorDetail{
+			Message:  err.Error(),
+			Location: "query.profile_uuid",
+			Value:    p.Input,
+		}}
+	}
+
+	p.UUID = id
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/webhooks"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// webhookEndpoints registers the endpoints for the "Webhooks" tagged endpoints.
+func webhookEndpoints(api huma.API, manager *webhooks.Manager) {
+	webhooksEndpoint(api, manager)
+	addWebhookEndpoint(api, manager)
+	removeWebhookEndpoint(api, manager)
+	deadLettersEndpoint(api, manager)
+	replayDeadLetterEndpoint(api, manager)
+	removeDeadLetterEndpoint(api, manager)
+}
+
+// webhooksEndpoint registers the path "/admin/webhooks".
+func webhooksEndpoint(api huma.API, manager *webhooks.Manager) {
+	type WebhooksOutput struct {
+		Body []webhooks.Subscription
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "webhooks",
+		Method:      http.MethodGet,
+		Path:        "/admin/webhooks",
+		Summary:     "Subscriptions",
+		Description: "Fetches all webhook subscriptions. The secrets of the subscriptions are not included.",
+		Tags:        []string{"Webhooks"},
+	}, func(_ context.Context, _ *struct{}) (*WebhooksOutput, error) {
+		return &WebhooksOutput{manager.Subscriptions()}, nil
+	})
+}
+
+// addWebhookEndpoint registers the path "/admin/webhooks" (POST).
+func addWebhookEndpoint(api huma.API, manager *webhooks.Manager) {
+	type AddWebhookInput struct {
+		Body struct {
+			URL    string   `doc:"The URL to deliver the events to." example:"http://127.0.0.1:9000/hook" json:"url" required:"true"`
+			Secret string   `doc:"The secret used to sign the deliveries with HMAC-SHA256." json:"secret,omitempty"`
+			Events []string "doc:\"The names of the events to deliver, as listed in the `/events` endpoint. If empty, all events are delivered.\" example:\"device,auth\" json:\"events,omitempty\""
+		}
+	}
+
+	type AddWebhookOutput struct {
+		Body webhooks.Subscription
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "webhook-add",
+		Method:        http.MethodPost,
+		Path:          "/admin/webhooks",
+		Summary:       "Subscribe",
+		Description:   "Creates a new webhook subscription.",
+		Tags:          []string{"Webhooks"},
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *AddWebhookInput) (*AddWebhookOutput, error) {
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["url"] = input.Body.URL
+			entry.Params["events"] = input.Body.Events
+		})
+
+		for _, name := range input.Body.Events {
+			if _, ok := eventTypes[name]; !ok {
+				return nil, huma.Error422UnprocessableEntity("Unknown event name: " + name)
+			}
+		}
+
+		sub, err := manager.Subscribe(webhooks.Subscription{
+			URL:    input.Body.URL,
+			Secret: input.Body.Secret,
+			Events: input.Body.Events,
+		})
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+
+		annotateAudit(ctx, func(entry *audit.Entry) {
+			entry.Params["webhook_id"] = sub.ID
+		})
+
+		return &AddWebhookOutput{sub}, nil
+	})
+}
+
+// removeWebhookEndpoint registers the path "/admin/webhooks/{webhook_id}".
+func removeWebhookEndpoint(api huma.API, manager *webhooks.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "webhook-remove",
+		Method:      http.MethodDelete,
+		Path:        "/admin/webhooks/{webhook_id}",
+		Summary:     "Unsubscribe",
+		Description: "Removes a webhook subscription. Its dead letters are retained until they are removed.",
+		Tags:        []string{"Webhooks"},
+	}, func(_ context.Context, input *struct {
+		ID string `doc:"The ID of the webhook subscription." path:"webhook_id"`
+	},
+	) (*struct{}, error) {
+		return nil, webhookError(manager.Unsubscribe(input.ID))
+	})
+}
+
+// deadLettersEndpoint registers the path "/admin/webhooks/dead_letters".
+func deadLettersEndpoint(api huma.API, manager *webhooks.Manager) {
+	type DeadLettersOutput struct {
+		Body []webhooks.DeadLetter
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "webhook-dead-letters",
+		Method:      http.MethodGet,
+		Path:        "/admin/webhooks/dead_letters",
+		Summary:     "Dead Letters",
+		Description: "Fetches the deliveries that could not be delivered after all retries.",
+		Tags:        []string{"Webhooks"},
+	}, func(_ context.Context, input *struct {
+		ID string `doc:"Only fetch the dead letters of this webhook subscription." query:"webhook_id"`
+	},
+	) (*DeadLettersOutput, error) {
+		letters, err := manager.DeadLetters(input.ID)
+
+		return &DeadLettersOutput{letters}, err
+	})
+}
+
+// replayDeadLetterEndpoint registers the path "/admin/webhooks/dead_letters/{dead_letter_id}/replay".
+func replayDeadLetterEndpoint(api huma.API, manager *webhooks.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "webhook-dead-letter-replay",
+		Method:      http.MethodPost,
+		Path:        "/admin/webhooks/dead_letters/{dead_letter_id}/replay",
+		Summary:     "Replay",
+		Description: "Attempts to deliver a dead letter once more. If the delivery succeeds, the dead letter is removed from the queue.",
+		Tags:        []string{"Webhooks"},
+	}, func(ctx context.Context, input *struct {
+		ID string `doc:"The ID of the dead letter." path:"dead_letter_id"`
+	},
+	) (*struct{}, error) {
+		_, err := manager.Replay(ctx, input.ID)
+		if errors.Is(err, webhooks.ErrDeliveryFailed) {
+			return nil, huma.Error502BadGateway(err.Error())
+		}
+
+		return nil, webhookError(err)
+	})
+}
+
+// removeDeadLetterEndpoint registers the path "/admin/webhooks/dead_letters/{dead_letter_id}".
+func removeDeadLetterEndpoint(api huma.API, manager *webhooks.Manager) {
+	huma.Register(api, huma.Operation{
+		OperationID: "webhook-dead-letter-remove",
+		Method:      http.MethodDelete,
+		Path:        "/admin/webhooks/dead_letters/{dead_letter_id}",
+		Summary:     "Remove Dead Letter",
+		Description: "Removes a dead letter from the queue.",
+		Tags:        []string{"Webhooks"},
+	}, func(_ context.Context, input *struct {
+		ID string `doc:"The ID of the dead letter." path:"dead_letter_id"`
+	},
+	) (*struct{}, error) {
+		return nil, webhookError(manager.DeleteDeadLetter(input.ID))
+	})
+}
+
+// webhookError converts the "not found" webhook errors to a 404 status error.
+func webhookError(err error) error {
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) || errors.Is(err, webhooks.ErrDeadLetterNotFound) {
+		return huma.Error404NotFound(err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,4 @@
+/*
+Package events provides an event hub, which fans out all published Bluetooth and daemon events to multiple subscribers.
+*/
+package events
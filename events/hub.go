@@ -0,0 +1,218 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// subscriberBufferSize is the size of the event buffer of each subscriber.
+// Events are dropped for a subscriber if its buffer is full.
+const subscriberBufferSize = 64
+
+// historySize is the number of the most recently published events that are retained,
+// so that they can be replayed to new subscribers.
+const historySize = 1024
+
+// Event describes a single event that was published to the hub.
+type Event struct {
+	// Seq holds the sequence number of the event, which is assigned by the hub.
+	// The sequence numbers of the published events start at 1, and increase by 1 for each event.
+	Seq uint64
+
+	// ID holds the event ID that was assigned by the publisher.
+	ID uint
+
+	// Name holds the name of the event, as listed in the "/events" endpoint.
+	Name string
+
+	// Data holds the actual event data.
+	Data any
+}
+
+// Hub implements the eventbus.EventPublisher interface, and
+// distributes each published event to all of its subscribers.
+type Hub struct {
+	subscribers map[*Subscriber]struct{}
+	observers   map[*observer]struct{}
+
+	seq     uint64
+	history [historySize]Event
+
+	mu sync.RWMutex
+}
+
+// observer holds a function which is called with each published event.
+type observer struct {
+	fn func(Event)
+}
+
+// Subscriber describes a subscription to the hub.
+type Subscriber struct {
+	// C receives the subscribed events. It is closed when the subscriber unsubscribes.
+	C <-chan Event
+
+	ch    chan Event
+	names map[string]struct{}
+	hub   *Hub
+	once  sync.Once
+}
+
+// eventNames maps the Bluetooth event IDs to the names used by the "/events" endpoint.
+var eventNames = map[uint]string{
+	bluetooth.EventError.Value():        "error",
+	bluetooth.EventAdapter.Value():      "adapter",
+	bluetooth.EventDevice.Value():       "device",
+	bluetooth.EventFileTransfer.Value(): "filetransfer",
+	bluetooth.EventMediaPlayer.Value():  "mediaplayer",
+}
+
+// NewHub returns a new event hub.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscriber]struct{}),
+		observers:   make(map[*observer]struct{}),
+	}
+}
+
+// Publish sends the provided event data to all subscribers of the event.
+func (h *Hub) Publish(id uint, name string, data any) {
+	if n, ok := eventNames[id]; ok {
+		name = n
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+
+	ev := Event{Seq: h.seq, ID: id, Name: name, Data: data}
+	h.history[h.seq%historySize] = ev
+
+	for o := range h.observers {
+		o.fn(ev)
+	}
+
+	for s := range h.subscribers {
+		if !s.wants(name) {
+			continue
+		}
+
+		select {
+		case s.ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a new subscription to the events with the provided names.
+// If no names are provided, all events are subscribed to.
+func (h *Hub) Subscribe(names ...string) *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, _ := h.subscribe(h.seq, names)
+
+	return s
+}
+
+// SubscribeSince returns a new subscription to the events with the provided names, and replays
+// the retained events which were published after the provided sequence number to it.
+// If some of these events are no longer retained, or the sequence number is ahead of the hub
+// (for example, after the daemon has restarted), no events are replayed and false is returned.
+func (h *Hub) SubscribeSince(seq uint64, names ...string) (*Subscriber, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.subscribe(seq, names)
+}
+
+// Seq returns the sequence number of the most recently published event.
+func (h *Hub) Seq() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.seq
+}
+
+// subscribe adds a new subscriber, and replays the retained events which were published after
+// the provided sequence number to it. The hub must be locked by the caller.
+func (h *Hub) subscribe(seq uint64, names []string) (*Subscriber, bool) {
+	s := &Subscriber{hub: h}
+
+	if len(names) > 0 {
+		s.names = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			s.names[name] = struct{}{}
+		}
+	}
+
+	var replay []Event
+
+	retained := seq <= h.seq && (h.seq < historySize || seq >= h.seq-historySize)
+	if retained {
+		for n := seq + 1; n <= h.seq; n++ {
+			if ev := h.history[n%historySize]; s.wants(ev.Name) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	s.ch = make(chan Event, subscriberBufferSize+len(replay))
+	s.C = s.ch
+
+	for _, ev := range replay {
+		s.ch <- ev
+	}
+
+	h.subscribers[s] = struct{}{}
+
+	return s, retained
+}
+
+// Observe registers a function, which is called with each published event before it is sent to the subscribers.
+// Unlike subscribers, observers never miss an event, but they are called while the hub is locked, so they must
+// return quickly and must not use the hub. The returned function removes the observer.
+func (h *Hub) Observe(fn func(Event)) func() {
+	o := &observer{fn}
+
+	h.mu.Lock()
+	h.observers[o] = struct{}{}
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		delete(h.observers, o)
+		h.mu.Unlock()
+	}
+}
+
+// Subscribers returns the number of subscribers of the hub.
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subscribers)
+}
+
+// Unsubscribe removes the subscription from the hub and closes its event channel.
+func (s *Subscriber) Unsubscribe() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subscribers, s)
+		s.hub.mu.Unlock()
+
+		close(s.ch)
+	})
+}
+
+// wants returns whether the subscriber has subscribed to the named event.
+func (s *Subscriber) wants(name string) bool {
+	if s.names == nil {
+		return true
+	}
+
+	_, ok := s.names[name]
+
+	return ok
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubscribeSince(t *testing.T) {
+	tests := []struct {
+		name      string
+		published int
+		since     uint64
+		names     []string
+		replayed  []uint64
+		retained  bool
+	}{
+		{name: "up to date", published: 5, since: 5, retained: true},
+		{name: "from the start", published: 3, since: 0, replayed: []uint64{1, 2, 3}, retained: true},
+		{name: "partial", published: 5, since: 3, replayed: []uint64{4, 5}, retained: true},
+		{name: "filtered", published: 6, since: 0, names: []string{"even"}, replayed: []uint64{2, 4, 6}, retained: true},
+		{name: "oldest retained", published: historySize + 10, since: 10, replayed: seqs(11, historySize+10), retained: true},
+		{name: "no longer retained", published: historySize + 10, since: 9, retained: false},
+		{name: "ahead of the hub", published: 5, since: 8, retained: false},
+		{name: "ahead of an empty hub", published: 0, since: 1, retained: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHub()
+
+			for n := 1; n <= test.published; n++ {
+				name := "odd"
+				if n%2 == 0 {
+					name = "even"
+				}
+
+				h.Publish(0, name, n)
+			}
+
+			s, retained := h.SubscribeSince(test.since, test.names...)
+			defer s.Unsubscribe()
+
+			if retained != test.retained {
+				t.Fatalf("retained = %v, want %v", retained, test.retained)
+			}
+
+			var replayed []uint64
+
+			for len(s.C) > 0 {
+				replayed = append(replayed, (<-s.C).Seq)
+			}
+
+			if !slices.Equal(replayed, test.replayed) {
+				t.Fatalf("replayed %v, want %v", replayed, test.replayed)
+			}
+		})
+	}
+}
+
+// seqs returns the sequence numbers from the first to the last, inclusive.
+func seqs(first, last uint64) []uint64 {
+	var numbers []uint64
+	for n := first; n <= last; n++ {
+		numbers = append(numbers, n)
+	}
+
+	return numbers
+}
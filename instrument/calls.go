@@ -0,0 +1,178 @@
+package instrument
+
+import (
+	"context"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/google/uuid"
+)
+
+// adapter wraps a bluetooth.Adapter.
+type adapter struct {
+	a bluetooth.Adapter
+	s *Session
+}
+
+func (a *adapter) StartDiscovery() error {
+	return a.s.invoke("adapter.start_discovery", a.a.StartDiscovery)
+}
+
+func (a *adapter) StopDiscovery() error {
+	return a.s.invoke("adapter.stop_discovery", a.a.StopDiscovery)
+}
+
+func (a *adapter) SetPoweredState(enable bool) error {
+	return a.s.invoke("adapter.set_powered_state", func() error { return a.a.SetPoweredState(enable) })
+}
+
+func (a *adapter) SetDiscoverableState(enable bool) error {
+	return a.s.invoke("adapter.set_discoverable_state", func() error { return a.a.SetDiscoverableState(enable) })
+}
+
+func (a *adapter) SetPairableState(enable bool) error {
+	return a.s.invoke("adapter.set_pairable_state", func() error { return a.a.SetPairableState(enable) })
+}
+
+func (a *adapter) Properties() (bluetooth.AdapterData, error) {
+	return invokeValue(a.s, "adapter.properties", a.a.Properties)
+}
+
+func (a *adapter) Devices() ([]bluetooth.DeviceData, error) {
+	return invokeValue(a.s, "adapter.devices", a.a.Devices)
+}
+
+// device wraps a bluetooth.Device.
+type device struct {
+	d bluetooth.Device
+	s *Session
+}
+
+func (d *device) Pair() error {
+	return d.s.invoke("device.pair", d.d.Pair)
+}
+
+func (d *device) CancelPairing() error {
+	return d.s.invoke("device.cancel_pairing", d.d.CancelPairing)
+}
+
+func (d *device) Connect() error {
+	return d.s.invoke("device.connect", d.d.Connect)
+}
+
+func (d *device) Disconnect() error {
+	return d.s.invoke("device.disconnect", d.d.Disconnect)
+}
+
+func (d *device) ConnectProfile(profileUUID uuid.UUID) error {
+	return d.s.invoke("device.connect_profile", func() error { return d.d.ConnectProfile(profileUUID) })
+}
+
+func (d *device) DisconnectProfile(profileUUID uuid.UUID) error {
+	return d.s.invoke("device.disconnect_profile", func() error { return d.d.DisconnectProfile(profileUUID) })
+}
+
+func (d *device) Remove() error {
+	return d.s.invoke("device.remove", d.d.Remove)
+}
+
+func (d *device) Properties() (bluetooth.DeviceData, error) {
+	return invokeValue(d.s, "device.properties", d.d.Properties)
+}
+
+// obex wraps a bluetooth.Obex.
+type obex struct {
+	o bluetooth.Obex
+	s *Session
+}
+
+func (o *obex) FileTransfer() bluetooth.ObexFileTransfer {
+	return &fileTransfer{o.o.FileTransfer(), o.s}
+}
+
+// fileTransfer wraps a bluetooth.ObexFileTransfer.
+type fileTransfer struct {
+	f bluetooth.ObexFileTransfer
+	s *Session
+}
+
+func (f *fileTransfer) CreateSession(ctx context.Context) error {
+	return f.s.invoke("obex.create_session", func() error { return f.f.CreateSession(ctx) })
+}
+
+func (f *fileTransfer) RemoveSession() error {
+	return f.s.invoke("obex.remove_session", f.f.RemoveSession)
+}
+
+func (f *fileTransfer) SendFile(filepath string) (bluetooth.FileTransferData, error) {
+	return invokeValue(f.s, "obex.send_file", func() (bluetooth.FileTransferData, error) {
+		return f.f.SendFile(filepath)
+	})
+}
+
+func (f *fileTransfer) CancelTransfer() error {
+	return f.s.invoke("obex.cancel_transfer", f.f.CancelTransfer)
+}
+
+func (f *fileTransfer) SuspendTransfer() error {
+	return f.s.invoke("obex.suspend_transfer", f.f.SuspendTransfer)
+}
+
+func (f *fileTransfer) ResumeTransfer() error {
+	return f.s.invoke("obex.resume_transfer", f.f.ResumeTransfer)
+}
+
+// network wraps a bluetooth.Network.
+type network struct {
+	n bluetooth.Network
+	s *Session
+}
+
+func (n *network) Connect(name string, nt bluetooth.NetworkType) error {
+	return n.s.invoke("network.connect", func() error { return n.n.Connect(name, nt) })
+}
+
+func (n *network) Disconnect() error {
+	return n.s.invoke("network.disconnect", n.n.Disconnect)
+}
+
+// mediaPlayer wraps a bluetooth.MediaPlayer.
+type mediaPlayer struct {
+	m bluetooth.MediaPlayer
+	s *Session
+}
+
+func (m *mediaPlayer) Properties() (bluetooth.MediaData, error) {
+	return invokeValue(m.s, "media_player.properties", m.m.Properties)
+}
+
+func (m *mediaPlayer) Play() error {
+	return m.s.invoke("media_player.play", m.m.Play)
+}
+
+func (m *mediaPlayer) Pause() error {
+	return m.s.invoke("media_player.pause", m.m.Pause)
+}
+
+func (m *mediaPlayer) TogglePlayPause() error {
+	return m.s.invoke("media_player.toggle_play_pause", m.m.TogglePlayPause)
+}
+
+func (m *mediaPlayer) Next() error {
+	return m.s.invoke("media_player.next", m.m.Next)
+}
+
+func (m *mediaPlayer) Previous() error {
+	return m.s.invoke("media_player.previous", m.m.Previous)
+}
+
+func (m *mediaPlayer) FastForward() error {
+	return m.s.invoke("media_player.fast_forward", m.m.FastForward)
+}
+
+func (m *mediaPlayer) Rewind() error {
+	return m.s.invoke("media_player.rewind", m.m.Rewind)
+}
+
+func (m *mediaPlayer) Stop() error {
+	return m.s.invoke("media_player.stop", m.m.Stop)
+}
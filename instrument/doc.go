@@ -0,0 +1,4 @@
+/*
+Package instrument provides a wrapper for a Bluetooth session, which invokes a set of hooks around each call to the session's adapters, devices, media players, networks and file transfers.
+*/
+package instrument
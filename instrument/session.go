@@ -0,0 +1,101 @@
+package instrument
+
+import (
+	"context"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// Hook is called before a session call is invoked. The returned function,
+// if not nil, is called with the result of the call once it completes.
+//
+// The call name is of the form "<component>.<method>", for example "device.connect".
+type Hook func(ctx context.Context, call string) func(err error)
+
+// Session wraps a bluetooth.Session, and invokes the hooks around each session call.
+type Session struct {
+	bluetooth.Session
+
+	ctx   context.Context
+	hooks []Hook
+}
+
+// Wrap returns a new session wrapper with the provided hooks.
+func Wrap(session bluetooth.Session, hooks ...Hook) *Session {
+	return &Session{
+		Session: session,
+		ctx:     context.Background(),
+		hooks:   hooks,
+	}
+}
+
+// WithContext returns a copy of the session wrapper, which passes the provided context to the hooks.
+func (s *Session) WithContext(ctx context.Context) *Session {
+	c := *s
+	c.ctx = ctx
+
+	return &c
+}
+
+// Adapter returns a function call interface to invoke adapter related functions.
+func (s *Session) Adapter(adapterAddress bluetooth.MacAddress) bluetooth.Adapter {
+	return &adapter{s.Session.Adapter(adapterAddress), s}
+}
+
+// Device returns a function call interface to invoke device related functions.
+func (s *Session) Device(deviceAddress bluetooth.MacAddress) bluetooth.Device {
+	return &device{s.Session.Device(deviceAddress), s}
+}
+
+// Obex returns a function call interface to invoke obex related functions.
+func (s *Session) Obex(deviceAddress bluetooth.MacAddress) bluetooth.Obex {
+	return &obex{s.Session.Obex(deviceAddress), s}
+}
+
+// Network returns a function call interface to invoke network related functions.
+func (s *Session) Network(deviceAddress bluetooth.MacAddress) bluetooth.Network {
+	return &network{s.Session.Network(deviceAddress), s}
+}
+
+// MediaPlayer returns a function call interface to invoke media player/control
+// related functions on a device.
+func (s *Session) MediaPlayer(deviceAddress bluetooth.MacAddress) bluetooth.MediaPlayer {
+	return &mediaPlayer{s.Session.MediaPlayer(deviceAddress), s}
+}
+
+// invoke calls the function within the hooks.
+func (s *Session) invoke(call string, fn func() error) error {
+	if len(s.hooks) == 0 {
+		return fn()
+	}
+
+	done := make([]func(error), 0, len(s.hooks))
+	for _, hook := range s.hooks {
+		if d := hook(s.ctx, call); d != nil {
+			done = append(done, d)
+		}
+	}
+
+	err := fn()
+
+	for i := len(done) - 1; i >= 0; i-- {
+		done[i](err)
+	}
+
+	return err
+}
+
+// invokeValue calls the function, which returns a value, within the hooks.
+func invokeValue[T any](s *Session, call string, fn func() (T, error)) (T, error) {
+	var value T
+
+	err := s.invoke(call, func() error {
+		var err error
+
+		value, err = fn()
+
+		return err
+	})
+
+	return value, err
+}
@@ -0,0 +1,4 @@
+/*
+Package logging provides structured logging for the daemon, with request IDs attached to the logs of each API request and its session calls.
+*/
+package logging
@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/instrument"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header which holds the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// QuietMetadata is the operation metadata key, which when set to true,
+// logs the successful requests of the operation at the debug level.
+// This is meant for operations that are polled frequently, like health checks.
+const QuietMetadata = "quiet"
+
+// maxRequestIDLength is the maximum length of an incoming request ID.
+const maxRequestIDLength = 128
+
+// Config describes the logger's configuration.
+type Config struct {
+	// Format holds the log format, which is either "text" or "json".
+	Format string
+
+	// Level holds the minimum log level, which is one of "debug", "info", "warn" or "error".
+	Level string
+
+	// File holds the path to a file to append the logs to. If empty, logs are written to the standard error.
+	File string
+}
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// contextHandler adds the request ID of the context to each log record.
+type contextHandler struct {
+	slog.Handler
+}
+
+// New returns a new logger, and a function to close the log file.
+func New(cfg Config) (*slog.Logger, func() error, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, nil, fmt.Errorf("invalid log level: %s", cfg.Level)
+	}
+
+	var (
+		w     io.Writer = os.Stderr
+		close           = func() error { return nil }
+	)
+
+	if cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot open log file: %w", err)
+		}
+
+		w, close = file, file.Close
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		close()
+
+		return nil, nil, fmt.Errorf("invalid log format: %s", cfg.Format)
+	}
+
+	return slog.New(contextHandler{handler}), close, nil
+}
+
+// WithRequestID returns a copy of the context with the request ID attached to it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of the context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// Middleware assigns an ID to each request, and logs the request once it completes.
+// If the request has an "X-Request-ID" header, its value is used as the request ID.
+func Middleware(ctx huma.Context, next func(huma.Context)) {
+	id := ctx.Header(RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+
+	ctx.SetHeader(RequestIDHeader, id)
+
+	reqCtx := WithRequestID(ctx.Context(), id)
+	start := time.Now()
+
+	next(huma.WithContext(ctx, reqCtx))
+
+	level := slog.LevelInfo
+
+	switch status := ctx.Status(); {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	default:
+		if quiet, _ := ctx.Operation().Metadata[QuietMetadata].(bool); quiet {
+			level = slog.LevelDebug
+		}
+	}
+
+	slog.Log(reqCtx, level, "Request completed",
+		"operation", ctx.Operation().OperationID,
+		"method", ctx.Method(),
+		"path", ctx.URL().Path,
+		"status", ctx.Status(),
+		"duration", time.Since(start),
+		"remote_addr", ctx.RemoteAddr(),
+	)
+}
+
+// Hook returns a session hook, which logs each session call.
+// Successful calls are logged at the debug level, and failed calls at the warn level.
+func Hook() instrument.Hook {
+	return func(ctx context.Context, call string) func(error) {
+		start := time.Now()
+
+		return func(err error) {
+			if err != nil {
+				slog.WarnContext(ctx, "Session call failed",
+					"call", call, "duration", time.Since(start), "error", err,
+				)
+
+				return
+			}
+
+			slog.DebugContext(ctx, "Session call completed",
+				"call", call, "duration", time.Since(start),
+			)
+		}
+	}
+}
+
+// Handle adds the request ID of the context to the log record.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a new handler with the provided attributes.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new handler with the provided group.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// validRequestID returns whether an incoming request ID can be used.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
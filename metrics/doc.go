@@ -0,0 +1,4 @@
+/*
+Package metrics provides the daemon's Prometheus metrics, which are exposed in the Prometheus text format.
+*/
+package metrics
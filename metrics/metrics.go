@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/instrument"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the prefix of all metric names.
+const namespace = "bluerestd"
+
+// The different outcomes of an authorization request.
+const (
+	AuthAccepted = "accepted"
+	AuthRejected = "rejected"
+	AuthExpired  = "expired"
+	AuthBlocked  = "blocked"
+)
+
+// errorClasses maps the session errors to their error class labels.
+var errorClasses = []struct {
+	err   error
+	class string
+}{
+	{errorkinds.ErrSessionNotExist, "session_not_exist"},
+	{errorkinds.ErrMethodTimeout, "method_timeout"},
+	{errorkinds.ErrMethodCanceled, "method_canceled"},
+	{errorkinds.ErrMethodCall, "method_call"},
+	{errorkinds.ErrInvalidAddress, "invalid_address"},
+	{errorkinds.ErrAdapterNotFound, "adapter_not_found"},
+	{errorkinds.ErrDeviceNotFound, "device_not_found"},
+	{errorkinds.ErrObexInitSession, "obex_session"},
+	{errorkinds.ErrNetworkInitSession, "network_session"},
+	{errorkinds.ErrNetworkAlreadyActive, "network_already_active"},
+	{errorkinds.ErrNetworkEstablishError, "network_establish"},
+	{errorkinds.ErrMediaPlayerNotConnected, "media_player_not_connected"},
+	{errorkinds.ErrPropertyDataParse, "property_data_parse"},
+	{errorkinds.ErrNotSupported, "not_supported"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+	{context.Canceled, "canceled"},
+}
+
+// Metrics holds all the daemon's metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	latency          *prometheus.HistogramVec
+	sseSubscribers   prometheus.Gauge
+	events           *prometheus.CounterVec
+	authRequests     *prometheus.CounterVec
+	authPending      prometheus.Gauge
+	transferBytes    prometheus.Counter
+	transfersStarted prometheus.Counter
+	transfers        *prometheus.CounterVec
+	backendErrors    *prometheus.CounterVec
+
+	// transferred holds the last reported number of transferred bytes of each device's file transfer,
+	// and cancelling holds the number of cancelled file transfers which have not been removed yet.
+	transferred map[bluetooth.MacAddress]uint64
+	cancelling  int
+	mu          sync.Mutex
+
+	// unobserve stops counting the published events.
+	unobserve func()
+}
+
+// New returns a new set of metrics. Use (*Metrics).Start() to start counting the published events.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "The total number of HTTP requests, by operation ID and status code.",
+		}, []string{"operation", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "The latency of HTTP requests, by operation ID.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"operation"}),
+		sseSubscribers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sse_subscribers",
+			Help:      "The number of clients subscribed to the event stream.",
+		}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_published_total",
+			Help:      "The total number of published events, by event name.",
+		}, []string{"event"}),
+		authRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_requests_total",
+			Help:      "The total number of authorization requests that required a reply, by outcome.",
+		}, []string{"outcome"}),
+		authPending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "auth_requests_pending",
+			Help:      "The number of authorization requests waiting for a reply.",
+		}),
+		transferBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "file_transfer_bytes_total",
+			Help:      "The total number of bytes transferred by file transfers.",
+		}),
+		transfersStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "file_transfers_started_total",
+			Help:      "The total number of started file transfers.",
+		}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "file_transfers_total",
+			Help:      "The total number of finished file transfers, by outcome (complete, cancelled or error).",
+		}, []string{"outcome"}),
+		backendErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backend_errors_total",
+			Help:      "The total number of failed session calls, by call and error class.",
+		}, []string{"call", "class"}),
+
+		transferred: make(map[bluetooth.MacAddress]uint64),
+	}
+
+	for _, outcome := range []string{AuthAccepted, AuthRejected, AuthExpired, AuthBlocked} {
+		m.authRequests.WithLabelValues(outcome)
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.latency, m.sseSubscribers, m.events,
+		m.authRequests, m.authPending, m.transferBytes, m.transfersStarted, m.transfers, m.backendErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Start starts counting the events published to the hub, and collecting
+// the adapter and device metrics from the session.
+func (m *Metrics) Start(session bluetooth.Session, hub *events.Hub) {
+	m.registry.MustRegister(&sessionCollector{session})
+
+	m.unobserve = hub.Observe(m.observe)
+}
+
+// Stop stops counting the published events.
+func (m *Metrics) Stop() {
+	if m.unobserve != nil {
+		m.unobserve()
+	}
+}
+
+// Handler returns the HTTP handler which serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a completed HTTP request.
+func (m *Metrics) ObserveRequest(operation string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.requests.WithLabelValues(operation, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// SubscriberAdded records a new event stream client.
+func (m *Metrics) SubscriberAdded() {
+	if m != nil {
+		m.sseSubscribers.Inc()
+	}
+}
+
+// SubscriberRemoved records a disconnected event stream client.
+func (m *Metrics) SubscriberRemoved() {
+	if m != nil {
+		m.sseSubscribers.Dec()
+	}
+}
+
+// AuthRequested records a new authorization request that requires a reply.
+func (m *Metrics) AuthRequested() {
+	if m != nil {
+		m.authPending.Inc()
+	}
+}
+
+// AuthCompleted records the outcome of an authorization request.
+func (m *Metrics) AuthCompleted(outcome string) {
+	if m == nil {
+		return
+	}
+
+	m.authPending.Dec()
+	m.authRequests.WithLabelValues(outcome).Inc()
+}
+
+// Hook returns a session hook, which counts the failed session calls by their error class,
+// the started file transfers, and the file transfers which failed to start. Cancelled file transfers are
+// counted once they are removed.
+func (m *Metrics) Hook() instrument.Hook {
+	return func(_ context.Context, call string) func(error) {
+		return func(err error) {
+			switch call {
+			case "obex.send_file":
+				if err != nil {
+					m.transfers.WithLabelValues("error").Inc()
+				} else {
+					m.transfersStarted.Inc()
+				}
+
+			case "obex.cancel_transfer":
+				if err == nil {
+					m.mu.Lock()
+					m.cancelling++
+					m.mu.Unlock()
+				}
+			}
+
+			if err != nil {
+				m.backendErrors.WithLabelValues(call, ErrorClass(err)).Inc()
+			}
+		}
+	}
+}
+
+// ErrorClass returns the class of a session error.
+func ErrorClass(err error) string {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c.class
+		}
+	}
+
+	return "other"
+}
+
+// observe counts a published event, and the file transfer progress.
+// It is called by the hub for each event, so no events are missed.
+func (m *Metrics) observe(ev events.Event) {
+	m.events.WithLabelValues(ev.Name).Inc()
+
+	if transfer, ok := ev.Data.(bluetooth.Event[bluetooth.FileTransferEventData]); ok {
+		m.observeTransfer(transfer.Action, transfer.Data)
+	}
+}
+
+// observeTransfer counts the transferred bytes and the outcome of a file transfer.
+// A file transfer which is removed before it is complete was either cancelled, if a cancellation
+// is pending, or has failed.
+func (m *Metrics) observeTransfer(action bluetooth.EventAction, data bluetooth.FileTransferEventData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if action == bluetooth.EventActionRemoved {
+		if _, ok := m.transferred[data.Address]; !ok {
+			return
+		}
+
+		delete(m.transferred, data.Address)
+
+		switch {
+		case data.Size > 0 && data.Transferred >= data.Size:
+			m.transfers.WithLabelValues("complete").Inc()
+
+			return
+
+		case m.cancelling > 0:
+			m.cancelling--
+			m.transfers.WithLabelValues("cancelled").Inc()
+
+			return
+		}
+
+		m.transfers.WithLabelValues("error").Inc()
+
+		return
+	}
+
+	last, ok := m.transferred[data.Address]
+	if !ok || data.Transferred < last {
+		last = 0
+	}
+
+	m.transferBytes.Add(float64(data.Transferred - last))
+	m.transferred[data.Address] = data.Transferred
+
+	if data.Size > 0 && data.Transferred >= data.Size {
+		m.transfers.WithLabelValues("complete").Inc()
+		delete(m.transferred, data.Address)
+	}
+}
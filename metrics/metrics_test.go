@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTransferOutcomes(t *testing.T) {
+	m := New()
+	hub := events.NewHub()
+
+	m.unobserve = hub.Observe(m.observe)
+	defer m.Stop()
+
+	hook := m.Hook()
+	transfer := func(action bluetooth.EventAction, address string, transferred, size uint64) {
+		mac, _ := bluetooth.ParseMAC(address)
+
+		hub.Publish(bluetooth.EventFileTransfer.Value(), "filetransfer", bluetooth.Event[bluetooth.FileTransferEventData]{
+			Action: action,
+			Data:   bluetooth.FileTransferEventData{Address: mac, Size: size, Transferred: transferred},
+		})
+	}
+
+	// A completed transfer.
+	hook(context.Background(), "obex.send_file")(nil)
+	transfer(bluetooth.EventActionAdded, "00:00:00:00:00:01", 0, 100)
+	transfer(bluetooth.EventActionUpdated, "00:00:00:00:00:01", 60, 100)
+	transfer(bluetooth.EventActionUpdated, "00:00:00:00:00:01", 100, 100)
+	transfer(bluetooth.EventActionRemoved, "00:00:00:00:00:01", 100, 100)
+
+	// A transfer which fails midway, without a final event.
+	hook(context.Background(), "obex.send_file")(nil)
+	transfer(bluetooth.EventActionAdded, "00:00:00:00:00:02", 0, 100)
+	transfer(bluetooth.EventActionUpdated, "00:00:00:00:00:02", 30, 100)
+	transfer(bluetooth.EventActionRemoved, "00:00:00:00:00:02", 30, 100)
+
+	// A cancelled transfer.
+	hook(context.Background(), "obex.send_file")(nil)
+	transfer(bluetooth.EventActionAdded, "00:00:00:00:00:03", 0, 100)
+	hook(context.Background(), "obex.cancel_transfer")(nil)
+	transfer(bluetooth.EventActionRemoved, "00:00:00:00:00:03", 0, 100)
+
+	// A transfer which fails to start.
+	hook(context.Background(), "obex.send_file")(errors.New("failed"))
+
+	if got := testutil.ToFloat64(m.transfersStarted); got != 3 {
+		t.Fatalf("started transfers = %v, want 3", got)
+	}
+
+	for outcome, want := range map[string]float64{"complete": 1, "error": 2, "cancelled": 1} {
+		if got := testutil.ToFloat64(m.transfers.WithLabelValues(outcome)); got != want {
+			t.Fatalf("%s transfers = %v, want %v", outcome, got, want)
+		}
+	}
+
+	if got := testutil.ToFloat64(m.transferBytes); got != 130 {
+		t.Fatalf("transferred bytes = %v, want 130", got)
+	}
+}
+
+func TestEventsAreNotDropped(t *testing.T) {
+	m := New()
+	hub := events.NewHub()
+
+	m.unobserve = hub.Observe(m.observe)
+	defer m.Stop()
+
+	// Publish more events than a subscriber can buffer, without reading them.
+	s := hub.Subscribe()
+	defer s.Unsubscribe()
+
+	for range 1000 {
+		hub.Publish(0, "device", nil)
+	}
+
+	if got := testutil.ToFloat64(m.events.WithLabelValues("device")); got != 1000 {
+		t.Fatalf("counted events = %v, want 1000", got)
+	}
+}
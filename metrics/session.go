@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The descriptions of the adapter metrics.
+var (
+	adapterPoweredDesc = prometheus.NewDesc(
+		namespace+"_adapter_powered",
+		"Whether the adapter is powered on (1) or off (0).",
+		[]string{"adapter"}, nil,
+	)
+	adapterDiscoveringDesc = prometheus.NewDesc(
+		namespace+"_adapter_discovering",
+		"Whether the adapter is discovering devices (1) or not (0).",
+		[]string{"adapter"}, nil,
+	)
+	connectedDevicesDesc = prometheus.NewDesc(
+		namespace+"_adapter_connected_devices",
+		"The number of devices connected to the adapter.",
+		[]string{"adapter"}, nil,
+	)
+)
+
+// sessionCollector collects the adapter metrics from the session on each scrape.
+type sessionCollector struct {
+	session bluetooth.Session
+}
+
+// Describe sends the descriptions of the adapter metrics.
+func (c *sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- adapterPoweredDesc
+	ch <- adapterDiscoveringDesc
+	ch <- connectedDevicesDesc
+}
+
+// Collect sends the current adapter metrics.
+func (c *sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, adapter := range c.session.Adapters() {
+		address := adapter.Address.String()
+
+		ch <- prometheus.MustNewConstMetric(adapterPoweredDesc, prometheus.GaugeValue, gaugeValue(adapter.Powered), address)
+		ch <- prometheus.MustNewConstMetric(adapterDiscoveringDesc, prometheus.GaugeValue, gaugeValue(adapter.Discovering), address)
+
+		devices, err := c.session.Adapter(adapter.Address).Devices()
+		if err != nil {
+			continue
+		}
+
+		var connected int
+
+		for _, device := range devices {
+			if device.Connected {
+				connected++
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(connectedDevicesDesc, prometheus.GaugeValue, float64(connected), address)
+	}
+}
+
+// gaugeValue converts a boolean to a gauge value.
+func gaugeValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,248 @@
+package mqttbridge
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/reconnect"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/puzpuzpuz/xsync/v3"
+)
+
+const (
+	// DefaultTopicPrefix is the default prefix of all topics published and subscribed to by the bridge.
+	DefaultTopicPrefix = "bluerestd"
+
+	// DefaultClientID is the default MQTT client ID of the bridge.
+	DefaultClientID = "bluerestd"
+
+	// stateOnline and stateOffline are the payloads of the status topic.
+	stateOnline  = "online"
+	stateOffline = "offline"
+
+	// publishTimeout is the maximum time to wait for the broker to acknowledge
+	// a message while stopping the bridge.
+	publishTimeout = 5 * time.Second
+)
+
+// Config describes the configuration of the MQTT bridge.
+type Config struct {
+	// Broker holds the URL of the MQTT broker, for example "tcp://127.0.0.1:1883".
+	// The "ssl://", "tls://", "ws://" and "wss://" schemes are also supported.
+	Broker string
+
+	// ClientID holds the MQTT client ID.
+	ClientID string
+
+	// Username and Password hold the credentials used to connect to the broker.
+	Username string
+	Password string
+
+	// TopicPrefix holds the prefix of all published and subscribed topics.
+	TopicPrefix string
+
+	// QoS holds the quality of service level (0, 1 or 2) used for all messages.
+	QoS byte
+
+	// TLSCACert holds the path to a PEM encoded CA certificate to verify the broker with.
+	TLSCACert string
+
+	// TLSCert and TLSKey hold the paths to a PEM encoded client certificate and key.
+	TLSCert string
+	TLSKey  string
+
+	// TLSInsecure disables the verification of the broker's certificate.
+	TLSInsecure bool
+
+	// HomeAssistant enables publishing Home Assistant MQTT discovery payloads.
+	HomeAssistant bool
+
+	// DiscoveryPrefix holds the Home Assistant MQTT discovery prefix.
+	DiscoveryPrefix string
+
+	// Reconnect is notified of the disconnections requested by commands, so that they do not trigger reconnections.
+	Reconnect *reconnect.Manager
+
+	// Discovery holds the discovery sessions, whose device discovery cannot be stopped with commands.
+	Discovery *discovery.Manager
+
+	// Audit records the handled commands, if it is set.
+	Audit *audit.Log
+}
+
+// Bridge publishes session events to an MQTT broker, and handles
+// commands received from the broker.
+type Bridge struct {
+	cfg     Config
+	client  mqtt.Client
+	session bluetooth.Session
+	hub     *events.Hub
+
+	subscriber *events.Subscriber
+	done       chan struct{}
+
+	// devices maps the address of each known device to the address of its adapter.
+	devices *xsync.MapOf[bluetooth.MacAddress, bluetooth.MacAddress]
+
+	// entities holds the published Home Assistant entities.
+	entities haEntities
+}
+
+// New returns a new MQTT bridge. Use (*Bridge).Start() to connect to the broker.
+func New(cfg Config, session bluetooth.Session, hub *events.Hub) (*Bridge, error) {
+	if cfg.Broker == "" {
+		return nil, errors.New("no MQTT broker specified")
+	}
+
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("invalid MQTT QoS level: %d", cfg.QoS)
+	}
+
+	if cfg.ClientID == "" {
+		cfg.ClientID = DefaultClientID
+	}
+
+	cfg.TopicPrefix = strings.Trim(cfg.TopicPrefix, "/")
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = DefaultTopicPrefix
+	}
+
+	cfg.DiscoveryPrefix = strings.Trim(cfg.DiscoveryPrefix, "/")
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = DefaultDiscoveryPrefix
+	}
+
+	b := &Bridge{
+		cfg:     cfg,
+		session: session,
+		hub:     hub,
+		done:    make(chan struct{}),
+		devices: xsync.NewMapOf[bluetooth.MacAddress, bluetooth.MacAddress](),
+		entities: haEntities{
+			topics: make(map[bluetooth.MacAddress]map[string]string),
+		},
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(false).
+		SetWill(b.topic("status"), stateOffline, cfg.QoS, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			slog.Warn("MQTT broker connection lost", "broker", cfg.Broker, "error", err)
+		})
+
+	if cfg.TLSCACert != "" || cfg.TLSCert != "" || cfg.TLSInsecure {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	b.client = mqtt.NewClient(opts)
+
+	return b, nil
+}
+
+// Start connects to the broker and starts publishing events.
+// If the broker is unreachable, the connection is retried in the background.
+func (b *Bridge) Start() error {
+	b.subscriber = b.hub.Subscribe("adapter", "device", "mediaplayer", "filetransfer", "error")
+	go b.watch()
+
+	token := b.client.Connect()
+	if token.WaitTimeout(publishTimeout) && token.Error() != nil {
+		b.subscriber.Unsubscribe()
+
+		return fmt.Errorf("cannot connect to MQTT broker '%s': %w", b.cfg.Broker, token.Error())
+	}
+
+	return nil
+}
+
+// Stop publishes the offline status of the bridge and disconnects from the broker.
+func (b *Bridge) Stop() {
+	b.subscriber.Unsubscribe()
+	<-b.done
+
+	if b.client.IsConnected() {
+		b.client.Publish(b.topic("status"), b.cfg.QoS, true, stateOffline).WaitTimeout(publishTimeout)
+	}
+
+	b.client.Disconnect(uint(publishTimeout.Milliseconds()))
+}
+
+// onConnect subscribes to the command topics and publishes the current state
+// of the session each time the bridge (re)connects to the broker.
+func (b *Bridge) onConnect(client mqtt.Client) {
+	slog.Info("MQTT broker connected", "broker", b.cfg.Broker)
+
+	filters := map[string]byte{
+		b.topic("+", "command", "+"):                b.cfg.QoS,
+		b.topic("+", "device", "+", "command", "+"): b.cfg.QoS,
+	}
+
+	client.SubscribeMultiple(filters, b.handleCommand)
+	client.Publish(b.topic("status"), b.cfg.QoS, true, stateOnline)
+
+	if b.cfg.HomeAssistant {
+		b.watchHomeAssistant(client)
+	}
+
+	b.publishSessionState()
+}
+
+// topic returns a topic name with the topic prefix and the provided levels.
+func (b *Bridge) topic(levels ...string) string {
+	return b.cfg.TopicPrefix + "/" + strings.Join(levels, "/")
+}
+
+// newTLSConfig returns a TLS configuration from the provided configuration.
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure, //nolint:gosec
+	}
+
+	if cfg.TLSCACert != "" {
+		pem, err := os.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read MQTT CA certificate: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in '%s'", cfg.TLSCACert)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load MQTT client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,283 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// testTimeout is the maximum time to wait for a message from the bridge.
+const testTimeout = 5 * time.Second
+
+// fakeSession is a session with a single adapter and device, which records the calls made to them.
+// Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	adapter bluetooth.AdapterData
+	device  bluetooth.DeviceData
+
+	calls []string
+	mu    sync.Mutex
+}
+
+// fakeAdapter records the calls made to the adapter of a fakeSession.
+type fakeAdapter struct {
+	bluetooth.Adapter
+
+	session *fakeSession
+}
+
+// fakeDevice records the calls made to the device of a fakeSession.
+type fakeDevice struct {
+	bluetooth.Device
+
+	session *fakeSession
+}
+
+func (s *fakeSession) Adapters() []bluetooth.AdapterData {
+	return []bluetooth.AdapterData{s.adapter}
+}
+
+func (s *fakeSession) Adapter(bluetooth.MacAddress) bluetooth.Adapter {
+	return fakeAdapter{session: s}
+}
+
+func (s *fakeSession) Device(bluetooth.MacAddress) bluetooth.Device {
+	return fakeDevice{session: s}
+}
+
+// record records a call.
+func (s *fakeSession) record(call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+}
+
+// recorded returns the recorded calls.
+func (s *fakeSession) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.calls...)
+}
+
+func (a fakeAdapter) SetPoweredState(enable bool) error {
+	if enable {
+		a.session.record("powered on")
+	} else {
+		a.session.record("powered off")
+	}
+
+	return nil
+}
+
+func (a fakeAdapter) StartDiscovery() error {
+	return errors.New("discovery is not supported")
+}
+
+func (a fakeAdapter) Devices() ([]bluetooth.DeviceData, error) {
+	return []bluetooth.DeviceData{a.session.device}, nil
+}
+
+func (d fakeDevice) Properties() (bluetooth.DeviceData, error) {
+	return d.session.device, nil
+}
+
+func (d fakeDevice) Connect() error {
+	d.session.record("connect")
+
+	return nil
+}
+
+func (d fakeDevice) DisconnectProfile(profile uuid.UUID) error {
+	d.session.record("disconnect " + profile.String())
+
+	return nil
+}
+
+// testObserver is an MQTT client which receives all messages published to the topic prefix.
+type testObserver struct {
+	client   mqtt.Client
+	messages chan mqtt.Message
+}
+
+// newTestObserver connects a new observer to the broker.
+func newTestObserver(t *testing.T, broker *testBroker) *testObserver {
+	t.Helper()
+
+	o := &testObserver{messages: make(chan mqtt.Message, 256)}
+	o.client = mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker.url()).SetClientID("observer"))
+
+	if token := o.client.Connect(); !token.WaitTimeout(testTimeout) || token.Error() != nil {
+		t.Fatalf("cannot connect observer: %v", token.Error())
+	}
+
+	token := o.client.Subscribe(DefaultTopicPrefix+"/#", 0, func(_ mqtt.Client, msg mqtt.Message) {
+		o.messages <- msg
+	})
+	if !token.WaitTimeout(testTimeout) || token.Error() != nil {
+		t.Fatalf("cannot subscribe observer: %v", token.Error())
+	}
+
+	t.Cleanup(func() { o.client.Disconnect(0) })
+
+	return o
+}
+
+// wait returns the payload of the next message published to the topic.
+func (o *testObserver) wait(t *testing.T, topic string) []byte {
+	t.Helper()
+
+	timeout := time.After(testTimeout)
+
+	for {
+		select {
+		case msg := <-o.messages:
+			if msg.Topic() == topic {
+				return msg.Payload()
+			}
+
+		case <-timeout:
+			t.Fatalf("no message was published to %s", topic)
+		}
+	}
+}
+
+// command publishes a command, and returns the result of the command.
+func (o *testObserver) command(t *testing.T, topic, payload string) commandResult {
+	t.Helper()
+
+	if token := o.client.Publish(topic, 0, false, payload); !token.WaitTimeout(testTimeout) || token.Error() != nil {
+		t.Fatalf("cannot publish command: %v", token.Error())
+	}
+
+	var result commandResult
+	if err := json.Unmarshal(o.wait(t, topic+"/result"), &result); err != nil {
+		t.Fatalf("invalid command result: %v", err)
+	}
+
+	return result
+}
+
+func TestBridge(t *testing.T) {
+	adapterAddress, _ := bluetooth.ParseMAC("00:1A:7D:DA:71:13")
+	deviceAddress, _ := bluetooth.ParseMAC("00:1B:66:01:02:03")
+
+	session := &fakeSession{
+		adapter: bluetooth.AdapterData{
+			Name:             "test",
+			AdapterEventData: bluetooth.AdapterEventData{Address: adapterAddress, Powered: true},
+		},
+		device: bluetooth.DeviceData{
+			Name: "headphones",
+			DeviceEventData: bluetooth.DeviceEventData{
+				Address: deviceAddress, AssociatedAdapter: adapterAddress, Connected: true,
+			},
+		},
+	}
+
+	broker := newTestBroker(t)
+	observer := newTestObserver(t, broker)
+	hub := events.NewHub()
+
+	bridge, err := New(Config{Broker: broker.url()}, session, hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bridge.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Stop()
+
+	if status := string(observer.wait(t, bridge.topic("status"))); status != stateOnline {
+		t.Fatalf("status = %q, want %q", status, stateOnline)
+	}
+
+	t.Run("session state", func(t *testing.T) {
+		var device bluetooth.DeviceEventData
+		if err := json.Unmarshal(observer.wait(t, bridge.deviceTopic(adapterAddress, deviceAddress, "state")), &device); err != nil {
+			t.Fatal(err)
+		}
+
+		if device.Address != deviceAddress || !device.Connected {
+			t.Fatalf("device state = %+v", device)
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		hub.Publish(bluetooth.EventAdapter.Value(), "adapter", bluetooth.Event[bluetooth.AdapterEventData]{
+			Action: bluetooth.EventActionUpdated,
+			Data:   bluetooth.AdapterEventData{Address: adapterAddress, Powered: true, Discovering: true},
+		})
+
+		var adapter bluetooth.AdapterEventData
+		if err := json.Unmarshal(observer.wait(t, bridge.adapterTopic(adapterAddress, "state")), &adapter); err != nil {
+			t.Fatal(err)
+		}
+
+		if !adapter.Discovering {
+			t.Fatalf("adapter state = %+v, want discovering", adapter)
+		}
+	})
+
+	commands := []struct {
+		name    string
+		topic   string
+		payload string
+		ok      bool
+		call    string
+	}{
+		{name: "power off", topic: bridge.adapterTopic(adapterAddress, "command/powered"), payload: "disable", ok: true, call: "powered off"},
+		{name: "invalid state", topic: bridge.adapterTopic(adapterAddress, "command/powered"), payload: "maybe"},
+		{name: "failed call", topic: bridge.adapterTopic(adapterAddress, "command/discovery"), payload: "enable"},
+		{name: "unknown adapter command", topic: bridge.adapterTopic(adapterAddress, "command/visible"), payload: "enable"},
+		{name: "connect", topic: bridge.deviceTopic(adapterAddress, deviceAddress, "command/connect"), ok: true, call: "connect"},
+		{
+			name:    "disconnect profile",
+			topic:   bridge.deviceTopic(adapterAddress, deviceAddress, "command/disconnect"),
+			payload: "0000110b-0000-1000-8000-00805f9b34fb",
+			ok:      true,
+			call:    "disconnect 0000110b-0000-1000-8000-00805f9b34fb",
+		},
+		{
+			name:    "disconnect profile by name",
+			topic:   bridge.deviceTopic(adapterAddress, deviceAddress, "command/disconnect"),
+			payload: "a2dp-sink",
+			ok:      true,
+			call:    "disconnect 0000110b-0000-1000-8000-00805f9b34fb",
+		},
+		{name: "unknown profile", topic: bridge.deviceTopic(adapterAddress, deviceAddress, "command/disconnect"), payload: "walkie-talkie"},
+		{name: "invalid address", topic: bridge.topic("adapter", "command", "powered"), payload: "enable"},
+	}
+
+	for _, command := range commands {
+		t.Run(command.name, func(t *testing.T) {
+			calls := len(session.recorded())
+
+			result := observer.command(t, command.topic, command.payload)
+			if result.OK != command.ok || (result.Error == "") != command.ok {
+				t.Fatalf("result = %+v, want ok = %v", result, command.ok)
+			}
+
+			recorded := session.recorded()[calls:]
+
+			switch {
+			case command.call == "" && len(recorded) > 0:
+				t.Fatalf("calls = %v, want none", recorded)
+
+			case command.call != "" && (len(recorded) != 1 || recorded[0] != command.call):
+				t.Fatalf("calls = %v, want [%s]", recorded, command.call)
+			}
+		})
+	}
+}
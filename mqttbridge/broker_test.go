@@ -0,0 +1,316 @@
+package mqttbridge
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The MQTT control packet types handled by the test broker.
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker, which supports subscriptions with wildcards,
+// retained messages, and publishing with QoS 0 and 1. All messages are delivered with QoS 0.
+type testBroker struct {
+	listener net.Listener
+
+	clients  map[*brokerClient]struct{}
+	retained map[string][]byte
+	mu       sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+// brokerClient holds a connection to the test broker, and its subscriptions.
+type brokerClient struct {
+	conn    net.Conn
+	filters []string
+	mu      sync.Mutex
+}
+
+// newTestBroker starts a test broker on a local port, which is stopped when the test finishes.
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start test broker: %v", err)
+	}
+
+	b := &testBroker{
+		listener: listener,
+		clients:  make(map[*brokerClient]struct{}),
+		retained: make(map[string][]byte),
+	}
+
+	b.wg.Add(1)
+
+	go b.accept()
+
+	t.Cleanup(b.close)
+
+	return b
+}
+
+// url returns the URL of the broker.
+func (b *testBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// close stops the broker, and closes all connections.
+func (b *testBroker) close() {
+	b.listener.Close()
+
+	b.mu.Lock()
+	for c := range b.clients {
+		c.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// accept accepts new connections, until the broker is closed.
+func (b *testBroker) accept() {
+	defer b.wg.Done()
+
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &brokerClient{conn: conn}
+
+		b.mu.Lock()
+		b.clients[c] = struct{}{}
+		b.mu.Unlock()
+
+		b.wg.Add(1)
+
+		go b.serve(c)
+	}
+}
+
+// serve handles the packets of a connection, until it is closed.
+func (b *testBroker) serve(c *brokerClient) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+
+		c.conn.Close()
+	}()
+
+	reader := bufio.NewReader(c.conn)
+
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			c.write(packetConnack<<4, []byte{0, 0})
+
+		case packetPublish:
+			b.publish(c, header, body)
+
+		case packetSubscribe:
+			b.subscribe(c, body)
+
+		case packetUnsubscribe:
+			c.write(packetUnsuback<<4, body[:2])
+
+		case packetPingreq:
+			c.write(packetPingresp<<4, nil)
+
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// publish handles a PUBLISH packet, and forwards the message to the matching subscriptions.
+func (b *testBroker) publish(c *brokerClient, header byte, body []byte) {
+	topic, rest := readString(body)
+	qos, retain := (header>>1)&0x03, header&0x01 != 0
+
+	if qos > 0 {
+		c.write(packetPuback<<4, rest[:2])
+		rest = rest[2:]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if retain {
+		if len(rest) == 0 {
+			delete(b.retained, topic)
+		} else {
+			b.retained[topic] = rest
+		}
+	}
+
+	for client := range b.clients {
+		if client.subscribed(topic) {
+			client.write(packetPublish<<4, publishBody(topic, rest))
+		}
+	}
+}
+
+// subscribe handles a SUBSCRIBE packet, and sends the matching retained messages to the client.
+func (b *testBroker) subscribe(c *brokerClient, body []byte) {
+	id, rest := body[:2], body[2:]
+
+	var filters []string
+
+	for len(rest) > 0 {
+		var filter string
+
+		filter, rest = readString(rest)
+		filters = append(filters, filter)
+		rest = rest[1:]
+	}
+
+	c.mu.Lock()
+	c.filters = append(c.filters, filters...)
+	c.mu.Unlock()
+
+	c.write(packetSuback<<4, append(id, make([]byte, len(filters))...))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for topic, payload := range b.retained {
+		for _, filter := range filters {
+			if matchTopic(filter, topic) {
+				c.write(packetPublish<<4|0x01, publishBody(topic, payload))
+
+				break
+			}
+		}
+	}
+}
+
+// subscribed returns whether the client is subscribed to the topic.
+func (c *brokerClient) subscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, filter := range c.filters {
+		if matchTopic(filter, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// write sends a packet to the client.
+func (c *brokerClient) write(header byte, body []byte) {
+	packet := []byte{header}
+
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		if length /= 128; length > 0 {
+			digit |= 0x80
+		}
+
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, _ = c.conn.Write(append(packet, body...))
+}
+
+// readPacket reads the fixed header and the body of a packet.
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var length, multiplier int = 0, 1
+
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+
+		if multiplier *= 128; multiplier > 128*128*128 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+
+	return header, body, err
+}
+
+// readString reads a length-prefixed string, and returns the rest of the data.
+func readString(data []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(data))
+
+	return string(data[2 : 2+length]), data[2+length:]
+}
+
+// publishBody returns the body of a QoS 0 PUBLISH packet.
+func publishBody(topic string, payload []byte) []byte {
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(topic)))
+	body = append(body, topic...)
+
+	return append(body, payload...)
+}
+
+// matchTopic returns whether a topic matches a topic filter with the "+" and "#" wildcards.
+func matchTopic(filter, topic string) bool {
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		switch {
+		case level == "#":
+			return true
+
+		case i >= len(topicLevels):
+			return false
+
+		case level != "+" && level != topicLevels[i]:
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
@@ -0,0 +1,181 @@
+package mqttbridge
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/profiles"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// auditCaller is the caller of the audit log entries of the commands.
+const auditCaller = "mqtt"
+
+// commandResult describes the result of a command, which is published
+// to the "result" sub-topic of the command topic.
+type commandResult struct {
+	Error string `json:"error,omitempty"`
+	OK    bool   `json:"ok"`
+}
+
+// handleCommand handles a message received on a command topic.
+//
+// The following command topics are supported:
+//   - <prefix>/<adapter>/command/{powered,pairable,discoverable,discovery} with the payload "enable" or "disable".
+//   - <prefix>/<adapter>/device/<device>/command/{connect,disconnect} with an optional profile UUID or short profile name as the payload.
+//   - <prefix>/<adapter>/device/<device>/command/media_player with a media control command as the payload.
+//
+// Each handled command is recorded in the audit log, with the topic as the operation.
+func (b *Bridge) handleCommand(_ mqtt.Client, msg mqtt.Message) {
+	if msg.Retained() {
+		return
+	}
+
+	levels := strings.Split(strings.TrimPrefix(msg.Topic(), b.cfg.TopicPrefix+"/"), "/")
+	payload := strings.TrimSpace(string(msg.Payload()))
+
+	var err error
+
+	entry := audit.Entry{
+		Caller:    auditCaller,
+		Operation: msg.Topic(),
+		Adapter:   levels[0],
+		Outcome:   audit.OutcomeSuccess,
+	}
+
+	switch len(levels) {
+	case 3:
+		err = b.adapterCommand(levels[0], levels[2], payload)
+
+	case 5:
+		entry.Device = levels[2]
+		err = b.deviceCommand(levels[2], levels[4], payload)
+
+	default:
+		return
+	}
+
+	result := commandResult{OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		entry.Outcome = audit.OutcomeFailure
+	}
+
+	if payload != "" {
+		entry.Params = map[string]any{"payload": payload}
+	}
+
+	if err := b.cfg.Audit.Record(entry); err != nil {
+		slog.Error("Cannot record audit log entry", "operation", entry.Operation, "error", err)
+	}
+
+	b.publish(msg.Topic()+"/result", false, result)
+}
+
+// adapterCommand sets the state of an adapter.
+func (b *Bridge) adapterCommand(address, command, payload string) error {
+	mac, err := bluetooth.ParseMAC(address)
+	if err != nil {
+		return err
+	}
+
+	var enable bool
+
+	switch payload {
+	case "enable":
+		enable = true
+	case "disable":
+	default:
+		return fmt.Errorf("invalid state '%s', expected 'enable' or 'disable'", payload)
+	}
+
+	adapterCall := b.session.Adapter(mac)
+
+	switch command {
+	case "powered":
+		return adapterCall.SetPoweredState(enable)
+
+	case "pairable":
+		return adapterCall.SetPairableState(enable)
+
+	case "discoverable":
+		return adapterCall.SetDiscoverableState(enable)
+
+	case "discovery":
+		if enable {
+			return adapterCall.StartDiscovery()
+		}
+
+		if b.cfg.Discovery.Active(mac) {
+			return errors.New("the adapter has active discovery sessions, end them with the API instead")
+		}
+
+		return adapterCall.StopDiscovery()
+	}
+
+	return fmt.Errorf("unknown adapter command '%s'", command)
+}
+
+// deviceCommand invokes a device or media player operation.
+func (b *Bridge) deviceCommand(address, command, payload string) error {
+	mac, err := bluetooth.ParseMAC(address)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "connect", "disconnect":
+		deviceCall := b.session.Device(mac)
+
+		profile := uuid.Nil
+		if payload != "" {
+			if profile, err = profiles.Resolve(payload); err != nil {
+				return fmt.Errorf("invalid profile: %w", err)
+			}
+		}
+
+		switch {
+		case command == "connect" && profile != uuid.Nil:
+			return deviceCall.ConnectProfile(profile)
+		case command == "connect":
+			return deviceCall.Connect()
+		}
+
+		b.cfg.Reconnect.Disconnecting(mac)
+
+		if profile != uuid.Nil {
+			return deviceCall.DisconnectProfile(profile)
+		}
+
+		return deviceCall.Disconnect()
+
+	case "media_player":
+		mediaCall := b.session.MediaPlayer(mac)
+
+		switch payload {
+		case "play":
+			return mediaCall.Play()
+		case "pause":
+			return mediaCall.Pause()
+		case "next":
+			return mediaCall.Next()
+		case "previous":
+			return mediaCall.Previous()
+		case "fast-forward":
+			return mediaCall.FastForward()
+		case "rewind":
+			return mediaCall.Rewind()
+		case "stop":
+			return mediaCall.Stop()
+		}
+
+		return fmt.Errorf("unknown media control '%s'", payload)
+	}
+
+	return fmt.Errorf("unknown device command '%s'", command)
+}
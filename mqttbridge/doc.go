@@ -0,0 +1,4 @@
+/*
+Package mqttbridge provides a bridge between a Bluetooth session and an MQTT broker. It publishes adapter, device, media player and file transfer events to MQTT topics, and maps commands received on MQTT topics to session operations.
+*/
+package mqttbridge
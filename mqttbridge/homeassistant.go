@@ -0,0 +1,282 @@
+package mqttbridge
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DefaultDiscoveryPrefix is the default Home Assistant MQTT discovery prefix.
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// haEntities stores the Home Assistant discovery topics of the published entities,
+// grouped by the address of the adapter or device which they belong to.
+type haEntities struct {
+	topics map[bluetooth.MacAddress]map[string]string
+
+	mu sync.Mutex
+}
+
+// haEntity describes a single Home Assistant entity.
+type haEntity struct {
+	component string
+	key       string
+	config    map[string]any
+}
+
+// mediaControls holds the media player controls, which are published as buttons.
+var mediaControls = []struct{ control, name, icon string }{
+	{"play", "Play", "mdi:play"},
+	{"pause", "Pause", "mdi:pause"},
+	{"stop", "Stop", "mdi:stop"},
+	{"next", "Next", "mdi:skip-next"},
+	{"previous", "Previous", "mdi:skip-previous"},
+}
+
+// watchHomeAssistant republishes all discovery payloads when Home Assistant comes online.
+func (b *Bridge) watchHomeAssistant(client mqtt.Client) {
+	client.Subscribe(b.cfg.DiscoveryPrefix+"/status", b.cfg.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+		if string(msg.Payload()) != stateOnline {
+			return
+		}
+
+		b.entities.mu.Lock()
+		b.entities.topics = make(map[bluetooth.MacAddress]map[string]string)
+		b.entities.mu.Unlock()
+
+		b.publishSessionState()
+	})
+}
+
+// syncAdapter publishes the entities of an adapter, if they are not published yet.
+func (b *Bridge) syncAdapter(address bluetooth.MacAddress) {
+	if !b.cfg.HomeAssistant || b.announced(address, "powered") {
+		return
+	}
+
+	adapter, err := b.session.Adapter(address).Properties()
+	if err != nil {
+		return
+	}
+
+	b.announceAdapter(adapter)
+}
+
+// syncDevice publishes the entities of a device once it is paired,
+// and removes them once it is unpaired.
+func (b *Bridge) syncDevice(data bluetooth.DeviceEventData) {
+	if !b.cfg.HomeAssistant {
+		return
+	}
+
+	announced := b.announced(data.Address, "connected")
+
+	switch {
+	case !data.Paired && announced:
+		b.withdraw(data.Address)
+
+	case data.Paired && !announced:
+		device, err := b.session.Device(data.Address).Properties()
+		if err != nil {
+			return
+		}
+
+		b.announceDevice(device)
+	}
+}
+
+// syncMediaPlayer publishes the media player entities of a paired device,
+// if they are not published yet.
+func (b *Bridge) syncMediaPlayer(address bluetooth.MacAddress) {
+	if !b.cfg.HomeAssistant || !b.announced(address, "connected") || b.announced(address, "media_player") {
+		return
+	}
+
+	device, err := b.session.Device(address).Properties()
+	if err != nil {
+		return
+	}
+
+	b.announceMediaPlayer(device)
+}
+
+// announceAdapter publishes the switches of an adapter's states.
+func (b *Bridge) announceAdapter(adapter bluetooth.AdapterData) {
+	device := map[string]any{
+		"identifiers": []string{haNodeID(adapter.Address)},
+		"name":        nameOf(adapter.Alias, adapter.Name, adapter.Address),
+		"model":       "Bluetooth Adapter",
+		"connections": [][]string{{"bluetooth", adapter.Address.String()}},
+	}
+
+	states := []struct{ key, name, property string }{
+		{"powered", "Powered", "powered"},
+		{"discoverable", "Discoverable", "discoverable"},
+		{"pairable", "Pairable", "pairable"},
+		{"discovery", "Discovery", "discovering"},
+	}
+
+	entities := make([]haEntity, 0, len(states))
+	for _, state := range states {
+		entities = append(entities, haEntity{
+			component: "switch",
+			key:       state.key,
+			config: map[string]any{
+				"name":           state.name,
+				"state_topic":    b.adapterTopic(adapter.Address, "state"),
+				"value_template": "{{ 'ON' if value_json." + state.property + " else 'OFF' }}",
+				"command_topic":  b.adapterTopic(adapter.Address, "command/"+state.key),
+				"payload_on":     "enable",
+				"payload_off":    "disable",
+				"state_on":       "ON",
+				"state_off":      "OFF",
+				"icon":           "mdi:bluetooth-settings",
+			},
+		})
+	}
+
+	b.announce(adapter.Address, device, entities)
+}
+
+// announceDevice publishes the connectivity sensor and connect button of a paired device.
+func (b *Bridge) announceDevice(device bluetooth.DeviceData) {
+	address, adapter := device.Address, device.AssociatedAdapter
+
+	b.announce(address, haDevice(device), []haEntity{
+		{
+			component: "binary_sensor",
+			key:       "connected",
+			config: map[string]any{
+				"name":           "Connected",
+				"device_class":   "connectivity",
+				"state_topic":    b.deviceTopic(adapter, address, "state"),
+				"value_template": "{{ 'ON' if value_json.connected else 'OFF' }}",
+			},
+		},
+		{
+			component: "button",
+			key:       "connect",
+			config: map[string]any{
+				"name":          "Connect",
+				"command_topic": b.deviceTopic(adapter, address, "command/connect"),
+				"payload_press": "",
+				"icon":          "mdi:bluetooth-connect",
+			},
+		},
+	})
+}
+
+// announceMediaPlayer publishes the media player entities of a paired device.
+// Since Home Assistant does not provide an MQTT media player platform, the media player
+// is published as a status sensor (with the track properties as attributes),
+// and a set of control buttons.
+func (b *Bridge) announceMediaPlayer(device bluetooth.DeviceData) {
+	address, adapter := device.Address, device.AssociatedAdapter
+	mediaTopic := b.deviceTopic(adapter, address, "media_player")
+
+	entities := []haEntity{
+		{
+			component: "sensor",
+			key:       "media_player",
+			config: map[string]any{
+				"name":                  "Media Player",
+				"state_topic":           mediaTopic,
+				"value_template":        "{{ value_json.status | default('stopped') }}",
+				"json_attributes_topic": mediaTopic,
+				"icon":                  "mdi:music",
+			},
+		},
+	}
+
+	for _, media := range mediaControls {
+		entities = append(entities, haEntity{
+			component: "button",
+			key:       "media_" + media.control,
+			config: map[string]any{
+				"name":          "Media " + media.name,
+				"command_topic": b.deviceTopic(adapter, address, "command/media_player"),
+				"payload_press": media.control,
+				"icon":          media.icon,
+			},
+		})
+	}
+
+	b.announce(address, haDevice(device), entities)
+}
+
+// announced returns whether the entity of an adapter or device is published.
+func (b *Bridge) announced(address bluetooth.MacAddress, key string) bool {
+	b.entities.mu.Lock()
+	defer b.entities.mu.Unlock()
+
+	_, ok := b.entities.topics[address][key]
+
+	return ok
+}
+
+// announce publishes the discovery payloads of the provided entities.
+func (b *Bridge) announce(address bluetooth.MacAddress, device map[string]any, entities []haEntity) {
+	nodeID := haNodeID(address)
+
+	b.entities.mu.Lock()
+	defer b.entities.mu.Unlock()
+
+	topics, ok := b.entities.topics[address]
+	if !ok {
+		topics = make(map[string]string, len(entities))
+		b.entities.topics[address] = topics
+	}
+
+	for _, entity := range entities {
+		topic := b.cfg.DiscoveryPrefix + "/" + entity.component + "/" + nodeID + "/" + entity.key + "/config"
+
+		entity.config["unique_id"] = nodeID + "_" + entity.key
+		entity.config["availability_topic"] = b.topic("status")
+		entity.config["device"] = device
+
+		topics[entity.key] = topic
+		b.publishRetained(topic, entity.config)
+	}
+}
+
+// withdraw removes all published entities of an adapter or device.
+func (b *Bridge) withdraw(address bluetooth.MacAddress) {
+	b.entities.mu.Lock()
+	defer b.entities.mu.Unlock()
+
+	for _, topic := range b.entities.topics[address] {
+		b.clear(topic)
+	}
+
+	delete(b.entities.topics, address)
+}
+
+// haDevice returns the Home Assistant device description of a Bluetooth device.
+func haDevice(device bluetooth.DeviceData) map[string]any {
+	return map[string]any{
+		"identifiers": []string{haNodeID(device.Address)},
+		"name":        nameOf(device.Alias, device.Name, device.Address),
+		"model":       device.Type,
+		"connections": [][]string{{"bluetooth", device.Address.String()}},
+		"via_device":  haNodeID(device.AssociatedAdapter),
+	}
+}
+
+// haNodeID returns the Home Assistant node ID of an adapter or device.
+func haNodeID(address bluetooth.MacAddress) string {
+	return "bluerestd_" + strings.ToLower(strings.ReplaceAll(address.String(), ":", ""))
+}
+
+// nameOf returns the first non-empty name, or the address if no names are present.
+func nameOf(alias, name string, address bluetooth.MacAddress) string {
+	switch {
+	case alias != "":
+		return alias
+	case name != "":
+		return name
+	}
+
+	return address.String()
+}
@@ -0,0 +1,158 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+)
+
+// watch publishes all subscribed events to the broker, until the bridge is stopped.
+func (b *Bridge) watch() {
+	defer close(b.done)
+
+	for ev := range b.subscriber.C {
+		b.publishEvent(ev)
+	}
+}
+
+// publishEvent publishes a single event to its respective topic.
+func (b *Bridge) publishEvent(ev events.Event) {
+	switch data := ev.Data.(type) {
+	case bluetooth.Event[bluetooth.AdapterEventData]:
+		if data.Action == bluetooth.EventActionRemoved {
+			b.withdraw(data.Data.Address)
+			b.clear(b.adapterTopic(data.Data.Address, "state"))
+
+			return
+		}
+
+		b.publishRetained(b.adapterTopic(data.Data.Address, "state"), data.Data)
+		b.syncAdapter(data.Data.Address)
+
+	case bluetooth.Event[bluetooth.DeviceEventData]:
+		address, adapter := data.Data.Address, data.Data.AssociatedAdapter
+
+		if data.Action == bluetooth.EventActionRemoved {
+			b.devices.Delete(address)
+			b.withdraw(address)
+			b.clear(b.deviceTopic(adapter, address, "state"))
+			b.clear(b.deviceTopic(adapter, address, "media_player"))
+
+			return
+		}
+
+		b.devices.Store(address, adapter)
+		b.publishRetained(b.deviceTopic(adapter, address, "state"), data.Data)
+		b.syncDevice(data.Data)
+
+	case bluetooth.Event[bluetooth.MediaEventData]:
+		adapter, ok := b.adapterOf(data.Data.Address)
+		if !ok {
+			return
+		}
+
+		b.publishRetained(b.deviceTopic(adapter, data.Data.Address, "media_player"), data.Data.MediaData)
+		b.syncMediaPlayer(data.Data.Address)
+
+	case bluetooth.Event[bluetooth.FileTransferEventData]:
+		adapter, ok := b.adapterOf(data.Data.Address)
+		if !ok {
+			return
+		}
+
+		b.publish(b.deviceTopic(adapter, data.Data.Address, "file_transfer"), false, data.Data)
+
+	case bluetooth.Event[errorkinds.GenericError]:
+		if data.Data.Errors == nil {
+			return
+		}
+
+		b.publish(b.topic("error"), false, struct {
+			Error string `json:"error"`
+		}{data.Data.Error()})
+	}
+}
+
+// publishSessionState publishes the current state of all adapters and devices.
+func (b *Bridge) publishSessionState() {
+	if b.session == nil {
+		return
+	}
+
+	for _, adapter := range b.session.Adapters() {
+		b.publishRetained(b.adapterTopic(adapter.Address, "state"), adapter.AdapterEventData)
+
+		if b.cfg.HomeAssistant {
+			b.announceAdapter(adapter)
+		}
+
+		devices, err := b.session.Adapter(adapter.Address).Devices()
+		if err != nil {
+			continue
+		}
+
+		for _, device := range devices {
+			b.devices.Store(device.Address, adapter.Address)
+			b.publishRetained(b.deviceTopic(adapter.Address, device.Address, "state"), device.DeviceEventData)
+
+			if !b.cfg.HomeAssistant || !device.Paired {
+				continue
+			}
+
+			b.announceDevice(device)
+
+			if media, err := b.session.MediaPlayer(device.Address).Properties(); err == nil {
+				b.publishRetained(b.deviceTopic(adapter.Address, device.Address, "media_player"), media)
+				b.announceMediaPlayer(device)
+			}
+		}
+	}
+}
+
+// adapterOf returns the address of the adapter which the device is associated with.
+func (b *Bridge) adapterOf(address bluetooth.MacAddress) (bluetooth.MacAddress, bool) {
+	if adapter, ok := b.devices.Load(address); ok {
+		return adapter, true
+	}
+
+	properties, err := b.session.Device(address).Properties()
+	if err != nil {
+		return bluetooth.MacAddress{}, false
+	}
+
+	b.devices.Store(address, properties.AssociatedAdapter)
+
+	return properties.AssociatedAdapter, true
+}
+
+// adapterTopic returns the name of an adapter's topic.
+func (b *Bridge) adapterTopic(adapter bluetooth.MacAddress, name string) string {
+	return b.topic(adapter.String(), name)
+}
+
+// deviceTopic returns the name of a device's topic.
+func (b *Bridge) deviceTopic(adapter, device bluetooth.MacAddress, name string) string {
+	return b.topic(adapter.String(), "device", device.String(), name)
+}
+
+// publishRetained publishes the JSON encoded data as a retained message.
+func (b *Bridge) publishRetained(topic string, data any) {
+	b.publish(topic, true, data)
+}
+
+// publish publishes the JSON encoded data to the topic.
+func (b *Bridge) publish(topic string, retained bool, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	b.client.Publish(topic, b.cfg.QoS, retained, payload)
+}
+
+// clear removes the retained message of a topic.
+func (b *Bridge) clear(topic string) {
+	b.client.Publish(topic, b.cfg.QoS, true, []byte{})
+}
@@ -0,0 +1,6 @@
+/*
+Package presence tracks the presence of devices from their sightings, and publishes a "presence" event
+when a device arrives or departs. Arrivals and departures use separate signal strength thresholds,
+so that a device with a fluctuating signal is not reported as arriving and departing repeatedly.
+*/
+package presence
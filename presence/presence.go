@@ -0,0 +1,357 @@
+package presence
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// EventID is the ID of the "presence" event, which is published when a device arrives or departs.
+const EventID uint = 102
+
+// EventName is the name of the "presence" event.
+const EventName = "presence"
+
+// The default presence settings.
+const (
+	DefaultAwayTimeout  = 2 * time.Minute
+	DefaultArriveRSSI   = -80
+	DefaultDepartRSSI   = -90
+	DefaultScanDuration = 10 * time.Second
+)
+
+// scanOwner is the owner of the background discovery sessions.
+const scanOwner = "presence"
+
+// The states of a "presence" event.
+const (
+	StateArrived  = "arrived"
+	StateDeparted = "departed"
+)
+
+// Config describes the presence settings.
+type Config struct {
+	// Devices holds the addresses of the tracked devices. If it is empty, all devices are tracked.
+	Devices []bluetooth.MacAddress
+
+	// AwayTimeout holds the time after the last sighting of a device, after which it departs.
+	// Connected devices do not depart.
+	AwayTimeout time.Duration
+
+	// ArriveRSSI holds the minimum signal strength of a sighting for a device to arrive,
+	// and DepartRSSI holds the minimum signal strength of a sighting to keep a device present.
+	// DepartRSSI is lowered to ArriveRSSI if it is higher.
+	ArriveRSSI, DepartRSSI int16
+
+	// ScanInterval holds the interval between each background discovery of all powered adapters,
+	// which lasts for ScanDuration. If it is zero, no background discovery is performed.
+	ScanInterval, ScanDuration time.Duration
+}
+
+// Presence describes the presence of a device.
+type Presence struct {
+	Address  bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Adapter  bluetooth.MacAddress `doc:"The address of the adapter which last saw the device." json:"adapter"`
+	Name     string               `doc:"The name or alias of the device." json:"name,omitempty"`
+	Present  bool                 `doc:"Whether the device is present." json:"present"`
+	Since    time.Time            `doc:"The time at which the device last arrived or departed." json:"since"`
+	LastSeen time.Time            `doc:"The time at which the device was last seen with a signal strong enough to keep it present." json:"last_seen"`
+	RSSI     int16                `doc:"The signal strength of the last sighting of the device." json:"rssi,omitempty"`
+}
+
+// Event describes a "presence" event.
+type Event struct {
+	State string `doc:"Whether the device arrived or departed." enum:"arrived,departed" json:"state"`
+
+	Presence
+}
+
+// Tracker tracks the presence of devices.
+type Tracker struct {
+	cfg       Config
+	hub       *events.Hub
+	cache     *cache.Cache
+	discovery *discovery.Manager
+
+	subscriber *events.Subscriber
+	stop       chan struct{}
+	done       chan struct{}
+
+	devices map[bluetooth.MacAddress]*device
+	mu      sync.Mutex
+}
+
+// device holds the presence of a tracked device.
+type device struct {
+	Presence
+
+	connected bool
+}
+
+// New returns a new presence tracker. The cache is used to resolve the names and connection states of devices,
+// and the discovery manager is used to perform the background discovery. Use (*Tracker).Start() to start tracking.
+func New(cfg Config, hub *events.Hub, c *cache.Cache, manager *discovery.Manager) *Tracker {
+	if cfg.AwayTimeout <= 0 {
+		cfg.AwayTimeout = DefaultAwayTimeout
+	}
+
+	if cfg.ArriveRSSI == 0 {
+		cfg.ArriveRSSI = DefaultArriveRSSI
+	}
+
+	if cfg.DepartRSSI == 0 {
+		cfg.DepartRSSI = DefaultDepartRSSI
+	}
+
+	cfg.DepartRSSI = min(cfg.DepartRSSI, cfg.ArriveRSSI)
+
+	if cfg.ScanDuration <= 0 {
+		cfg.ScanDuration = DefaultScanDuration
+	}
+
+	return &Tracker{
+		cfg:       cfg,
+		hub:       hub,
+		cache:     c,
+		discovery: manager,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		devices:   make(map[bluetooth.MacAddress]*device),
+	}
+}
+
+// Start marks the connected devices as present, and starts tracking the sightings of devices.
+func (t *Tracker) Start() {
+	t.subscriber = t.hub.Subscribe("device")
+
+	for _, d := range t.cache.Snapshot().Devices {
+		if d.Connected {
+			t.sighted(d.DeviceEventData, true)
+		}
+	}
+
+	go t.watch()
+}
+
+// Stop stops tracking the sightings of devices.
+func (t *Tracker) Stop() {
+	if t == nil || t.subscriber == nil {
+		return
+	}
+
+	close(t.stop)
+	t.subscriber.Unsubscribe()
+	<-t.done
+}
+
+// Devices returns the presence of all tracked devices which have been seen, sorted by address.
+func (t *Tracker) Devices() []Presence {
+	if t == nil {
+		return []Presence{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	devices := make([]Presence, 0, len(t.devices))
+	for _, d := range t.devices {
+		devices = append(devices, d.Presence)
+	}
+
+	slices.SortFunc(devices, func(a, b Presence) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return devices
+}
+
+// Device returns the presence of a tracked device, if it has been seen.
+func (t *Tracker) Device(address bluetooth.MacAddress) (Presence, bool) {
+	if t == nil {
+		return Presence{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.devices[address]
+	if !ok {
+		return Presence{}, false
+	}
+
+	return d.Presence, true
+}
+
+// watch records the sightings of devices, checks for departed devices and performs
+// the background discovery, until the tracker is stopped.
+func (t *Tracker) watch() {
+	defer close(t.done)
+
+	check := time.NewTicker(max(time.Second, min(t.cfg.AwayTimeout/4, 10*time.Second)))
+	defer check.Stop()
+
+	var scan <-chan time.Time
+	if t.cfg.ScanInterval > 0 {
+		ticker := time.NewTicker(t.cfg.ScanInterval)
+		defer ticker.Stop()
+
+		scan = ticker.C
+		t.scan()
+	}
+
+	for {
+		select {
+		case <-t.stop:
+			return
+
+		case ev, ok := <-t.subscriber.C:
+			if !ok {
+				return
+			}
+
+			if data, ok := ev.Data.(bluetooth.Event[bluetooth.DeviceEventData]); ok && data.Action != bluetooth.EventActionRemoved {
+				t.sighted(data.Data, data.Action == bluetooth.EventActionAdded)
+			}
+
+		case <-check.C:
+			t.departed()
+
+		case <-scan:
+			t.scan()
+		}
+	}
+}
+
+// sighted records a sighting of a device, and publishes its arrival if its signal is strong enough.
+// Connected devices without a signal strength are considered to be sighted with a strong signal.
+// Since the device updates carry the cached signal strength, an unchanged signal strength of a tracked device
+// is only a sighting if the device was added.
+func (t *Tracker) sighted(data bluetooth.DeviceEventData, added bool) {
+	if len(t.cfg.Devices) > 0 && !slices.Contains(t.cfg.Devices, data.Address) {
+		return
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+
+	d, ok := t.devices[data.Address]
+
+	rssi := data.RSSI
+	if ok && !added && rssi == d.RSSI {
+		rssi = 0
+	}
+
+	strong := rssi >= t.cfg.DepartRSSI
+	arrives := rssi >= t.cfg.ArriveRSSI
+
+	if rssi == 0 {
+		if !data.Connected {
+			if ok {
+				d.connected = false
+			}
+
+			t.mu.Unlock()
+
+			return
+		}
+
+		strong, arrives = true, true
+	}
+
+	if !ok {
+		if !arrives {
+			t.mu.Unlock()
+
+			return
+		}
+
+		d = &device{Presence: Presence{Address: data.Address}}
+		t.devices[data.Address] = d
+	}
+
+	d.Adapter, d.connected = data.AssociatedAdapter, data.Connected
+	if data.RSSI != 0 {
+		d.RSSI = data.RSSI
+	}
+
+	if strong && (d.Present || arrives) {
+		d.LastSeen = now
+	}
+
+	var ev *Event
+	if !d.Present && arrives {
+		d.Present, d.Since = true, now
+		d.Name = t.name(data.Address)
+		ev = &Event{StateArrived, d.Presence}
+	}
+
+	t.mu.Unlock()
+
+	t.publish(ev)
+}
+
+// departed publishes the departure of each present device which is not connected,
+// and has not been seen with a strong enough signal within the away timeout.
+func (t *Tracker) departed() {
+	now := time.Now()
+	departed := []*Event{}
+
+	t.mu.Lock()
+	for _, d := range t.devices {
+		if d.connected {
+			d.LastSeen = now
+		}
+
+		if !d.Present || now.Sub(d.LastSeen) < t.cfg.AwayTimeout {
+			continue
+		}
+
+		d.Present, d.Since = false, now
+		departed = append(departed, &Event{StateDeparted, d.Presence})
+	}
+	t.mu.Unlock()
+
+	for _, ev := range departed {
+		t.publish(ev)
+	}
+}
+
+// scan creates a discovery session on each powered adapter, which lasts for the scan duration.
+func (t *Tracker) scan() {
+	adapters, _, _ := t.cache.Adapters()
+
+	for _, adapter := range adapters {
+		if !adapter.Powered {
+			continue
+		}
+
+		if _, err := t.discovery.Create(adapter.Address, t.cfg.ScanDuration, discovery.Filter{}, scanOwner); err != nil {
+			slog.Warn("Cannot start presence scan", "adapter", adapter.Address.String(), "error", err)
+		}
+	}
+}
+
+// publish publishes a "presence" event, if any.
+func (t *Tracker) publish(ev *Event) {
+	if ev == nil {
+		return
+	}
+
+	slog.Info("Device "+ev.State, "address", ev.Address.String(), "name", ev.Name)
+	t.hub.Publish(EventID, EventName, *ev)
+}
+
+// name returns the alias or name of a device from the cache.
+func (t *Tracker) name(address bluetooth.MacAddress) string {
+	d, _, _ := t.cache.Device(address)
+
+	return cmp.Or(d.Alias, d.Name)
+}
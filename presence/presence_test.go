@@ -0,0 +1,187 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// testAwayTimeout is the away timeout of the trackers in the tests.
+const testAwayTimeout = 100 * time.Millisecond
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// newTestTracker returns a tracker whose sightings are recorded by the test, and a subscriber of its events.
+// The tracker is not started, so that the departures are only checked by the test.
+func newTestTracker(t *testing.T, cfg Config) (*Tracker, *events.Subscriber) {
+	t.Helper()
+
+	hub := events.NewHub()
+	c := cache.New(nil, hub)
+	c.StoreDevice(bluetooth.DeviceData{Name: "headphones", DeviceEventData: bluetooth.DeviceEventData{Address: mac("00:1B:66:01:02:03")}})
+
+	subscriber := hub.Subscribe(EventName)
+	t.Cleanup(subscriber.Unsubscribe)
+
+	cfg.AwayTimeout = testAwayTimeout
+
+	return New(cfg, hub, c, nil), subscriber
+}
+
+// states returns the states and names of the published events, without waiting for more events.
+func states(subscriber *events.Subscriber) []string {
+	var published []string
+
+	for {
+		select {
+		case ev := <-subscriber.C:
+			data := ev.Data.(Event)
+			published = append(published, data.State+" "+data.Name)
+
+		default:
+			return published
+		}
+	}
+}
+
+// expectStates checks the states of the published events.
+func expectStates(t *testing.T, subscriber *events.Subscriber, want ...string) {
+	t.Helper()
+
+	published := states(subscriber)
+	if len(published) != len(want) {
+		t.Fatalf("events = %v, want %v", published, want)
+	}
+
+	for i := range want {
+		if published[i] != want[i] {
+			t.Fatalf("events = %v, want %v", published, want)
+		}
+	}
+}
+
+// away waits for the away timeout, and checks for departed devices.
+func away(tracker *Tracker) {
+	time.Sleep(2 * testAwayTimeout)
+	tracker.departed()
+}
+
+func TestArrival(t *testing.T) {
+	tracker, subscriber := newTestTracker(t, Config{})
+	address := mac("00:1B:66:01:02:03")
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -85}, true)
+
+	if _, ok := tracker.Device(address); ok {
+		t.Fatal("a device seen with a weak signal is tracked")
+	}
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -70}, false)
+	expectStates(t, subscriber, "arrived headphones")
+
+	// A signal between the departure and arrival strengths keeps the device present.
+	time.Sleep(testAwayTimeout / 2)
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -85}, false)
+	time.Sleep(testAwayTimeout / 2)
+	tracker.departed()
+
+	if presence, ok := tracker.Device(address); !ok || !presence.Present || presence.RSSI != -85 {
+		t.Fatalf("Device() = %+v, %v, want the device to be present", presence, ok)
+	}
+
+	away(tracker)
+	expectStates(t, subscriber, "departed headphones")
+
+	if devices := tracker.Devices(); len(devices) != 1 || devices[0].Present {
+		t.Fatalf("Devices() = %+v, want the departed device", devices)
+	}
+}
+
+func TestUnchangedRSSI(t *testing.T) {
+	tracker, subscriber := newTestTracker(t, Config{})
+	address := mac("00:1B:66:04:05:06")
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -70}, true)
+	expectStates(t, subscriber, "arrived ")
+
+	// The updates of other properties carry the cached signal strength, so they are not sightings.
+	time.Sleep(testAwayTimeout / 2)
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -70, Paired: true}, false)
+
+	away(tracker)
+	expectStates(t, subscriber, "departed ")
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -70}, false)
+	expectStates(t, subscriber)
+
+	// A device which is added again with the same signal strength is sighted.
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -70}, true)
+	expectStates(t, subscriber, "arrived ")
+}
+
+func TestConnectedDevices(t *testing.T) {
+	tracker, subscriber := newTestTracker(t, Config{})
+	address := mac("00:1B:66:01:02:03")
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, Connected: true}, false)
+	expectStates(t, subscriber, "arrived headphones")
+
+	away(tracker)
+	expectStates(t, subscriber)
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: address}, false)
+
+	away(tracker)
+	expectStates(t, subscriber, "departed headphones")
+
+	// A connection is a sighting, even if the device reports its cached signal strength.
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -95}, true)
+	tracker.sighted(bluetooth.DeviceEventData{Address: address, RSSI: -95, Connected: true}, false)
+	expectStates(t, subscriber, "arrived headphones")
+}
+
+func TestTrackedDevices(t *testing.T) {
+	tracked := mac("00:1B:66:01:02:03")
+	tracker, subscriber := newTestTracker(t, Config{Devices: []bluetooth.MacAddress{tracked}})
+
+	tracker.sighted(bluetooth.DeviceEventData{Address: mac("00:1B:66:04:05:06"), RSSI: -50}, true)
+	tracker.sighted(bluetooth.DeviceEventData{Address: tracked, RSSI: -50}, true)
+	expectStates(t, subscriber, "arrived headphones")
+
+	if devices := tracker.Devices(); len(devices) != 1 || devices[0].Address != tracked {
+		t.Fatalf("Devices() = %+v, want only the tracked device", devices)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	hub := events.NewHub()
+	tracker := New(Config{}, hub, cache.New(nil, hub), nil)
+	tracker.Start()
+	defer tracker.Stop()
+
+	subscriber := hub.Subscribe(EventName)
+	defer subscriber.Unsubscribe()
+
+	hub.Publish(bluetooth.EventDevice.Value(), "device", bluetooth.Event[bluetooth.DeviceEventData]{
+		Action: bluetooth.EventActionAdded,
+		Data:   bluetooth.DeviceEventData{Address: mac("00:1B:66:01:02:03"), RSSI: -60},
+	})
+
+	select {
+	case ev := <-subscriber.C:
+		if data := ev.Data.(Event); data.State != StateArrived || data.RSSI != -60 {
+			t.Fatalf("event = %+v, want an arrival", data)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("no presence event was published")
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package profiles provides a built-in registry of the Bluetooth SIG service UUIDs, with short names
+(like "a2dp-sink" or "hfp"), which can be used in place of the UUIDs of the service profiles.
+*/
+package profiles
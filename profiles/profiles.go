@@ -0,0 +1,113 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownProfile is returned if a profile is neither a UUID nor a known short name.
+var ErrUnknownProfile = errors.New("unknown service profile")
+
+// Profile describes a Bluetooth SIG service profile.
+type Profile struct {
+	Name        string    `doc:"The short name of the profile." example:"a2dp-sink" json:"name"`
+	UUID        uuid.UUID `doc:"The service UUID of the profile." example:"0000110b-0000-1000-8000-00805f9b34fb" format:"uuid" json:"uuid"`
+	Description string    `doc:"The full name of the profile." example:"Advanced Audio Distribution (Sink)" json:"description"`
+}
+
+// known holds the built-in profiles, in the order of their UUIDs.
+var known = []Profile{
+	sig(0x1101, "spp", "Serial Port"),
+	sig(0x1103, "dun", "Dial-up Networking"),
+	sig(0x1105, "obex-opp", "OBEX Object Push"),
+	sig(0x1106, "obex-ftp", "OBEX File Transfer"),
+	sig(0x1108, "hsp", "Headset"),
+	sig(0x110a, "a2dp-source", "Advanced Audio Distribution (Source)"),
+	sig(0x110b, "a2dp-sink", "Advanced Audio Distribution (Sink)"),
+	sig(0x110c, "avrcp-target", "A/V Remote Control (Target)"),
+	sig(0x110d, "a2dp", "Advanced Audio Distribution"),
+	sig(0x110e, "avrcp", "A/V Remote Control"),
+	sig(0x110f, "avrcp-controller", "A/V Remote Control (Controller)"),
+	sig(0x1112, "hsp-ag", "Headset (Audio Gateway)"),
+	sig(0x1115, "pan-panu", "Personal Area Network (User)"),
+	sig(0x1116, "pan-nap", "Personal Area Network (Network Access Point)"),
+	sig(0x1117, "pan-gn", "Personal Area Network (Group Ad-hoc Network)"),
+	sig(0x111e, "hfp", "Hands-Free"),
+	sig(0x111f, "hfp-ag", "Hands-Free (Audio Gateway)"),
+	sig(0x1124, "hid", "Human Interface Device"),
+	sig(0x112d, "sap", "SIM Access"),
+	sig(0x112e, "pbap-pce", "Phonebook Access (Client)"),
+	sig(0x112f, "pbap-pse", "Phonebook Access (Server)"),
+	sig(0x1130, "pbap", "Phonebook Access"),
+	sig(0x1132, "map-mse", "Message Access (Server)"),
+	sig(0x1133, "map-mns", "Message Access (Notification Server)"),
+	sig(0x1134, "map", "Message Access"),
+	sig(0x1200, "pnp", "PnP Information"),
+	sig(0x1203, "generic-audio", "Generic Audio"),
+	sig(0x1800, "gap", "Generic Access"),
+	sig(0x1801, "gatt", "Generic Attribute"),
+	sig(0x180a, "device-info", "Device Information"),
+	sig(0x180f, "battery", "Battery"),
+	sig(0x1812, "hogp", "HID over GATT"),
+}
+
+// All returns the built-in profiles.
+func All() []Profile {
+	return append([]Profile(nil), known...)
+}
+
+// Resolve returns the UUID of a profile, which is either a UUID or the short name of a built-in profile.
+func Resolve(profile string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(profile); err == nil {
+		return id, nil
+	}
+
+	for _, p := range known {
+		if strings.EqualFold(p.Name, profile) {
+			return p.UUID, nil
+		}
+	}
+
+	return uuid.Nil, fmt.Errorf("%w: %q", ErrUnknownProfile, profile)
+}
+
+// Lookup returns the built-in profile with the UUID, if it exists.
+func Lookup(id string) (Profile, bool) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return Profile{}, false
+	}
+
+	for _, p := range known {
+		if p.UUID == parsed {
+			return p, true
+		}
+	}
+
+	return Profile{}, false
+}
+
+// Names returns the built-in profiles of the UUIDs, skipping any unknown UUIDs.
+func Names(ids []string) []Profile {
+	var profiles []Profile
+
+	for _, id := range ids {
+		if p, ok := Lookup(id); ok {
+			profiles = append(profiles, p)
+		}
+	}
+
+	return profiles
+}
+
+// sig returns a profile with a 16-bit UUID, which is expanded using the Bluetooth base UUID.
+func sig(short uint16, name, description string) Profile {
+	return Profile{
+		Name:        name,
+		UUID:        uuid.MustParse(fmt.Sprintf("%08x-0000-1000-8000-00805f9b34fb", short)),
+		Description: description,
+	}
+}
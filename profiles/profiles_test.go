@@ -0,0 +1,37 @@
+package profiles
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    uuid.UUID
+		wantErr error
+	}{
+		{profile: "a2dp-sink", want: uuid.MustParse("0000110b-0000-1000-8000-00805f9b34fb")},
+		{profile: "HFP", want: uuid.MustParse("0000111e-0000-1000-8000-00805f9b34fb")},
+		{profile: "pan-nap", want: uuid.MustParse("00001116-0000-1000-8000-00805f9b34fb")},
+		{profile: "0000110A-0000-1000-8000-00805F9B34FB", want: uuid.MustParse("0000110a-0000-1000-8000-00805f9b34fb")},
+		{profile: "12345678-1234-5678-1234-567812345678", want: uuid.MustParse("12345678-1234-5678-1234-567812345678")},
+		{profile: "walkie-talkie", wantErr: ErrUnknownProfile},
+		{profile: "", wantErr: ErrUnknownProfile},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			got, err := Resolve(test.profile)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Resolve(%q) error = %v, want %v", test.profile, err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Fatalf("Resolve(%q) = %s, want %s", test.profile, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Package proximity keeps a history of the signal strength (RSSI) samples of each device, and estimates the
+proximity of each device from its smoothed signal strength. A "proximity" event is published when the
+estimated proximity of a device changes.
+*/
+package proximity
@@ -0,0 +1,50 @@
+package proximity
+
+import "time"
+
+// Sample describes a single signal strength sample of a device.
+type Sample struct {
+	Time     time.Time `doc:"The time at which the sample was received." json:"time"`
+	RSSI     int16     `doc:"The signal strength of the sample." json:"rssi"`
+	Smoothed float64   `doc:"The smoothed signal strength after the sample." json:"smoothed"`
+}
+
+// history holds the most recent samples of a device in a ring buffer.
+type history struct {
+	samples []Sample
+	next    int
+	full    bool
+}
+
+// newHistory returns a new history, which holds up to size samples.
+func newHistory(size int) *history {
+	return &history{samples: make([]Sample, size)}
+}
+
+// add adds a sample to the history, replacing the oldest sample if the history is full.
+func (h *history) add(sample Sample) {
+	h.samples[h.next] = sample
+
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// last returns the most recent sample, if any.
+func (h *history) last() (Sample, bool) {
+	if !h.full && h.next == 0 {
+		return Sample{}, false
+	}
+
+	return h.samples[(h.next+len(h.samples)-1)%len(h.samples)], true
+}
+
+// list returns the samples from the oldest to the most recent.
+func (h *history) list() []Sample {
+	if !h.full {
+		return append([]Sample{}, h.samples[:h.next]...)
+	}
+
+	return append(append([]Sample{}, h.samples[h.next:]...), h.samples[:h.next]...)
+}
@@ -0,0 +1,331 @@
+package proximity
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// EventID is the ID of the "proximity" event, which is published when the proximity of a device changes.
+const EventID uint = 103
+
+// EventName is the name of the "proximity" event.
+const EventName = "proximity"
+
+// The default proximity settings.
+const (
+	DefaultHistorySize   = 64
+	DefaultSmoothing     = 0.3
+	DefaultImmediateRSSI = -55
+	DefaultNearRSSI      = -70
+	DefaultGoneTimeout   = time.Minute
+)
+
+// Proximity describes the estimated proximity of a device.
+type Proximity string
+
+// The different proximity classes.
+const (
+	Immediate Proximity = "immediate"
+	Near      Proximity = "near"
+	Far       Proximity = "far"
+	Gone      Proximity = "gone"
+)
+
+// Config describes the proximity settings.
+type Config struct {
+	// HistorySize holds the number of samples that are kept for each device.
+	HistorySize int
+
+	// Smoothing holds the weight (between 0 and 1) of each new sample in the exponential moving average
+	// of the signal strength. Lower values smooth the signal strength more, but react slower to changes.
+	Smoothing float64
+
+	// ImmediateRSSI and NearRSSI hold the minimum smoothed signal strengths of the "immediate" and "near"
+	// proximities. Devices with a weaker signal are "far". NearRSSI is lowered to ImmediateRSSI if it is higher.
+	ImmediateRSSI, NearRSSI int16
+
+	// GoneTimeout holds the time after the last sighting of a device, after which it is "gone".
+	GoneTimeout time.Duration
+}
+
+// Device describes the signal strength history and proximity of a device.
+type Device struct {
+	Address   bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Adapter   bluetooth.MacAddress `doc:"The address of the adapter which received the last sample." json:"adapter"`
+	Proximity Proximity            `doc:"The estimated proximity of the device." enum:"immediate,near,far,gone" json:"proximity"`
+	Smoothed  float64              `doc:"The smoothed signal strength of the device." json:"smoothed"`
+	Samples   []Sample             `doc:"The signal strength samples of the device, from the oldest to the most recent. They are only included when a single device is fetched." json:"samples,omitempty"`
+}
+
+// Change describes a "proximity" event.
+type Change struct {
+	Address   bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Adapter   bluetooth.MacAddress `doc:"The address of the adapter which received the last sample." json:"adapter"`
+	Proximity Proximity            `doc:"The estimated proximity of the device." enum:"immediate,near,far,gone" json:"proximity"`
+	Previous  Proximity            `doc:"The previous proximity of the device." enum:"immediate,near,far,gone" json:"previous"`
+	Smoothed  float64              `doc:"The smoothed signal strength of the device." json:"smoothed"`
+}
+
+// Tracker tracks the signal strength and proximity of devices.
+type Tracker struct {
+	cfg Config
+	hub *events.Hub
+
+	subscriber *events.Subscriber
+	stop       chan struct{}
+	done       chan struct{}
+
+	devices map[bluetooth.MacAddress]*device
+	mu      sync.Mutex
+}
+
+// device holds the signal strength history and proximity of a tracked device.
+type device struct {
+	adapter   bluetooth.MacAddress
+	proximity Proximity
+	history   *history
+
+	// rssi holds the last signal strength reported for the device. Device update events
+	// carry the cached signal strength, so an unchanged value is not a new sample.
+	rssi int16
+
+	// seen holds the time of the last update of the device with a signal strength,
+	// including the updates with an unchanged signal strength.
+	seen time.Time
+}
+
+// New returns a new proximity tracker. Use (*Tracker).Start() to start tracking.
+func New(cfg Config, hub *events.Hub) *Tracker {
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHistorySize
+	}
+
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = DefaultSmoothing
+	}
+
+	if cfg.ImmediateRSSI == 0 {
+		cfg.ImmediateRSSI = DefaultImmediateRSSI
+	}
+
+	if cfg.NearRSSI == 0 {
+		cfg.NearRSSI = DefaultNearRSSI
+	}
+
+	cfg.NearRSSI = min(cfg.NearRSSI, cfg.ImmediateRSSI)
+
+	if cfg.GoneTimeout <= 0 {
+		cfg.GoneTimeout = DefaultGoneTimeout
+	}
+
+	return &Tracker{
+		cfg:     cfg,
+		hub:     hub,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		devices: make(map[bluetooth.MacAddress]*device),
+	}
+}
+
+// Start starts tracking the signal strength samples of devices.
+func (t *Tracker) Start() {
+	t.subscriber = t.hub.Subscribe("device")
+
+	go t.watch()
+}
+
+// Stop stops tracking the signal strength samples of devices.
+func (t *Tracker) Stop() {
+	if t == nil || t.subscriber == nil {
+		return
+	}
+
+	close(t.stop)
+	t.subscriber.Unsubscribe()
+	<-t.done
+}
+
+// Device returns the signal strength history and proximity of a device, if it has any samples.
+func (t *Tracker) Device(address bluetooth.MacAddress) (Device, bool) {
+	if t == nil {
+		return Device{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.devices[address]
+	if !ok {
+		return Device{}, false
+	}
+
+	return d.data(address), true
+}
+
+// Devices returns the proximity of all devices with samples, sorted by address.
+// The samples of each device are not included.
+func (t *Tracker) Devices() []Device {
+	if t == nil {
+		return []Device{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	devices := make([]Device, 0, len(t.devices))
+	for address, d := range t.devices {
+		data := d.data(address)
+		data.Samples = nil
+
+		devices = append(devices, data)
+	}
+
+	slices.SortFunc(devices, func(a, b Device) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return devices
+}
+
+// watch records the signal strength samples of devices, and checks for gone devices, until the tracker is stopped.
+func (t *Tracker) watch() {
+	defer close(t.done)
+
+	check := time.NewTicker(max(time.Second, min(t.cfg.GoneTimeout/4, 10*time.Second)))
+	defer check.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+
+		case ev, ok := <-t.subscriber.C:
+			if !ok {
+				return
+			}
+
+			data, ok := ev.Data.(bluetooth.Event[bluetooth.DeviceEventData])
+			if ok && data.Action != bluetooth.EventActionRemoved && data.Data.RSSI != 0 {
+				t.sample(data.Data, data.Action == bluetooth.EventActionAdded)
+			}
+
+		case <-check.C:
+			t.gone()
+		}
+	}
+}
+
+// sample adds a signal strength sample of a device, and publishes the change of its proximity, if any.
+// Unless the device was added, the sample is only recorded if the signal strength differs from the
+// previously reported one, otherwise only the time of the sighting is recorded.
+// If the device was gone, the smoothed signal strength starts again from the sample.
+func (t *Tracker) sample(data bluetooth.DeviceEventData, added bool) {
+	now := time.Now()
+
+	t.mu.Lock()
+
+	d, ok := t.devices[data.Address]
+	switch {
+	case !ok:
+		d = &device{proximity: Gone, history: newHistory(t.cfg.HistorySize)}
+		t.devices[data.Address] = d
+
+	case !added && d.rssi == data.RSSI:
+		d.seen = now
+		t.mu.Unlock()
+
+		return
+	}
+
+	d.rssi, d.seen = data.RSSI, now
+
+	smoothed := float64(data.RSSI)
+	if last, ok := d.history.last(); ok && d.proximity != Gone {
+		smoothed = last.Smoothed + t.cfg.Smoothing*(float64(data.RSSI)-last.Smoothed)
+	}
+
+	d.adapter = data.AssociatedAdapter
+	d.history.add(Sample{Time: now, RSSI: data.RSSI, Smoothed: smoothed})
+
+	ev := d.update(data.Address, t.classify(smoothed))
+
+	t.mu.Unlock()
+
+	t.publish(ev)
+}
+
+// gone marks the devices which were not seen within the gone timeout as "gone".
+func (t *Tracker) gone() {
+	changed := []*Change{}
+
+	t.mu.Lock()
+	for address, d := range t.devices {
+		if time.Since(d.seen) >= t.cfg.GoneTimeout {
+			if ev := d.update(address, Gone); ev != nil {
+				changed = append(changed, ev)
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	for _, ev := range changed {
+		t.publish(ev)
+	}
+}
+
+// classify returns the proximity of a smoothed signal strength.
+func (t *Tracker) classify(smoothed float64) Proximity {
+	switch {
+	case smoothed >= float64(t.cfg.ImmediateRSSI):
+		return Immediate
+
+	case smoothed >= float64(t.cfg.NearRSSI):
+		return Near
+	}
+
+	return Far
+}
+
+// publish publishes a "proximity" event, if any.
+func (t *Tracker) publish(ev *Change) {
+	if ev != nil {
+		t.hub.Publish(EventID, EventName, *ev)
+	}
+}
+
+// update sets the proximity of the device, and returns the "proximity" event if it has changed.
+func (d *device) update(address bluetooth.MacAddress, proximity Proximity) *Change {
+	if d.proximity == proximity {
+		return nil
+	}
+
+	previous := d.proximity
+	d.proximity = proximity
+
+	last, _ := d.history.last()
+
+	return &Change{
+		Address:   address,
+		Adapter:   d.adapter,
+		Proximity: proximity,
+		Previous:  previous,
+		Smoothed:  last.Smoothed,
+	}
+}
+
+// data returns the signal strength history and proximity of the device.
+func (d *device) data(address bluetooth.MacAddress) Device {
+	last, _ := d.history.last()
+
+	return Device{
+		Address:   address,
+		Adapter:   d.adapter,
+		Proximity: d.proximity,
+		Smoothed:  last.Smoothed,
+		Samples:   d.history.list(),
+	}
+}
@@ -0,0 +1,173 @@
+package proximity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// testGoneTimeout is the gone timeout of the trackers in the tests.
+const testGoneTimeout = 100 * time.Millisecond
+
+// testAddress is the address of the device in the tests.
+var testAddress, _ = bluetooth.ParseMAC("00:1B:66:01:02:03")
+
+// newTestTracker returns a tracker whose samples are recorded by the test, and a subscriber of its events.
+// The tracker is not started, so that the gone devices are only checked by the test.
+func newTestTracker(t *testing.T, cfg Config) (*Tracker, *events.Subscriber) {
+	t.Helper()
+
+	hub := events.NewHub()
+
+	subscriber := hub.Subscribe(EventName)
+	t.Cleanup(subscriber.Unsubscribe)
+
+	cfg.GoneTimeout = testGoneTimeout
+
+	return New(cfg, hub), subscriber
+}
+
+// changes returns the published proximity changes, without waiting for more events.
+func changes(subscriber *events.Subscriber) []Proximity {
+	var published []Proximity
+
+	for {
+		select {
+		case ev := <-subscriber.C:
+			published = append(published, ev.Data.(Change).Proximity)
+
+		default:
+			return published
+		}
+	}
+}
+
+// expectChanges checks the published proximity changes.
+func expectChanges(t *testing.T, subscriber *events.Subscriber, want ...Proximity) {
+	t.Helper()
+
+	published := changes(subscriber)
+	if len(published) != len(want) {
+		t.Fatalf("changes = %v, want %v", published, want)
+	}
+
+	for i := range want {
+		if published[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", published, want)
+		}
+	}
+}
+
+// sample records a sample of the test device.
+func sample(tracker *Tracker, rssi int16, added bool) {
+	tracker.sample(bluetooth.DeviceEventData{Address: testAddress, RSSI: rssi}, added)
+}
+
+func TestSmoothing(t *testing.T) {
+	tracker, subscriber := newTestTracker(t, Config{Smoothing: 0.5})
+
+	sample(tracker, -50, true)
+	expectChanges(t, subscriber, Immediate)
+
+	// -50 + 0.5 * (-90 - -50) = -70
+	sample(tracker, -90, false)
+	expectChanges(t, subscriber, Near)
+
+	// -70 + 0.5 * (-90 - -70) = -80
+	sample(tracker, -90, true)
+	expectChanges(t, subscriber, Far)
+
+	device, ok := tracker.Device(testAddress)
+	if !ok || device.Smoothed != -80 || device.Proximity != Far {
+		t.Fatalf("Device() = %+v, %v", device, ok)
+	}
+
+	want := []float64{-50, -70, -80}
+	if len(device.Samples) != len(want) {
+		t.Fatalf("samples = %+v, want %v smoothed", device.Samples, want)
+	}
+
+	for i, s := range device.Samples {
+		if s.Smoothed != want[i] {
+			t.Fatalf("samples = %+v, want %v smoothed", device.Samples, want)
+		}
+	}
+
+	if devices := tracker.Devices(); len(devices) != 1 || devices[0].Samples != nil {
+		t.Fatalf("Devices() = %+v, want the device without samples", devices)
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	tracker, _ := newTestTracker(t, Config{HistorySize: 3})
+
+	for rssi := int16(-60); rssi > -65; rssi-- {
+		sample(tracker, rssi, false)
+	}
+
+	device, _ := tracker.Device(testAddress)
+	if len(device.Samples) != 3 || device.Samples[0].RSSI != -62 || device.Samples[2].RSSI != -64 {
+		t.Fatalf("samples = %+v, want the last 3 samples", device.Samples)
+	}
+}
+
+func TestUnchangedRSSI(t *testing.T) {
+	tracker, subscriber := newTestTracker(t, Config{})
+
+	sample(tracker, -60, true)
+	expectChanges(t, subscriber, Near)
+
+	// An unchanged signal strength is a sighting, which keeps the device from being gone, but not a sample.
+	for range 3 {
+		time.Sleep(testGoneTimeout / 2)
+		sample(tracker, -60, false)
+		tracker.gone()
+	}
+
+	expectChanges(t, subscriber)
+
+	if device, _ := tracker.Device(testAddress); len(device.Samples) != 1 {
+		t.Fatalf("samples = %+v, want a single sample", device.Samples)
+	}
+
+	time.Sleep(testGoneTimeout)
+	tracker.gone()
+	expectChanges(t, subscriber, Gone)
+
+	// The smoothed signal strength of a gone device starts again from the next sample.
+	sample(tracker, -50, false)
+	expectChanges(t, subscriber, Immediate)
+
+	if device, _ := tracker.Device(testAddress); device.Smoothed != -50 {
+		t.Fatalf("smoothed = %v, want -50", device.Smoothed)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	hub := events.NewHub()
+	tracker := New(Config{}, hub)
+	tracker.Start()
+	defer tracker.Stop()
+
+	subscriber := hub.Subscribe(EventName)
+	defer subscriber.Unsubscribe()
+
+	for _, rssi := range []int16{0, -40} {
+		hub.Publish(bluetooth.EventDevice.Value(), "device", bluetooth.Event[bluetooth.DeviceEventData]{
+			Action: bluetooth.EventActionUpdated,
+			Data:   bluetooth.DeviceEventData{Address: testAddress, RSSI: rssi},
+		})
+	}
+
+	select {
+	case ev := <-subscriber.C:
+		if change := ev.Data.(Change); change.Proximity != Immediate || change.Previous != Gone || change.Smoothed != -40 {
+			t.Fatalf("change = %+v", change)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("no proximity event was published")
+	}
+}
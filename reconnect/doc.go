@@ -0,0 +1,7 @@
+/*
+Package reconnect provides the per-device reconnection policies of the daemon, which are stored persistently.
+When a device with an enabled policy disconnects, its adapter is powered on or the daemon starts, the device
+is reconnected to with the backoff schedule of its policy, within its active hours. The reconnection status
+of each device is published as a "reconnect" event.
+*/
+package reconnect
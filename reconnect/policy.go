@@ -0,0 +1,88 @@
+package reconnect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// DefaultBackoff holds the number of seconds to wait before each reconnection attempt,
+// if the policy has no backoff schedule.
+var DefaultBackoff = []uint32{5, 15, 30, 60, 300}
+
+// Policy describes the reconnection policy of a device.
+type Policy struct {
+	UpdatedAt   time.Time            `doc:"The time at which the policy was last updated." json:"updated_at"`
+	Address     bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Enabled     bool                 `doc:"Whether the device is reconnected to automatically." json:"enabled"`
+	Profile     string               `doc:"The service profile UUID to reconnect to. If it is empty, a profile is chosen automatically." json:"profile_uuid,omitempty"`
+	Backoff     []uint32             `doc:"The number of seconds to wait before each reconnection attempt. The last value is used for all further attempts." json:"backoff"`
+	MaxAttempts int                  `doc:"The maximum number of reconnection attempts. If it is zero, the attempts are not limited." json:"max_attempts"`
+	ActiveHours *Hours               `doc:"The hours of the day within which the reconnection attempts are made. If it is not set, the attempts are made at any time." json:"active_hours,omitempty"`
+}
+
+// Hours describes a daily time window in local time. If the end is before the start, the window spans midnight.
+type Hours struct {
+	Start string `doc:"The start of the window, in the 24-hour HH:MM format." example:"08:00" json:"start" pattern:"^([01][0-9]|2[0-3]):[0-5][0-9]$"`
+	End   string `doc:"The end of the window, in the 24-hour HH:MM format." example:"22:00" json:"end" pattern:"^([01][0-9]|2[0-3]):[0-5][0-9]$"`
+}
+
+// delay returns the time to wait before an attempt, starting from 1.
+func (p Policy) delay(attempt int) time.Duration {
+	backoff := p.Backoff
+	if len(backoff) == 0 {
+		backoff = DefaultBackoff
+	}
+
+	return time.Duration(backoff[min(attempt, len(backoff))-1]) * time.Second
+}
+
+// Validate returns an error if the start or the end of the window is invalid.
+func (h *Hours) Validate() error {
+	if _, err := parseClock(h.Start); err != nil {
+		return err
+	}
+
+	_, err := parseClock(h.End)
+
+	return err
+}
+
+// Next returns the earliest time, from the provided time on, which is within the window.
+func (h *Hours) Next(t time.Time) time.Time {
+	if h == nil {
+		return t
+	}
+
+	start, errStart := parseClock(h.Start)
+	end, errEnd := parseClock(h.End)
+
+	if errStart != nil || errEnd != nil || start == end {
+		return t
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	clock := t.Sub(midnight)
+
+	switch {
+	case start < end && clock >= start && clock < end,
+		start > end && (clock >= start || clock < end):
+		return t
+
+	case clock < start:
+		return midnight.Add(start)
+	}
+
+	return midnight.AddDate(0, 0, 1).Add(start)
+}
+
+// parseClock parses a HH:MM time of day, and returns the time since midnight.
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", clock, err)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
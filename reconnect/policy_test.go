@@ -0,0 +1,39 @@
+package reconnect
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoursNext(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		hours *Hours
+		at    time.Time
+		want  time.Time
+	}{
+		{name: "no window", hours: nil, at: day(3, 0), want: day(3, 0)},
+		{name: "within", hours: &Hours{"08:00", "22:00"}, at: day(12, 30), want: day(12, 30)},
+		{name: "at the start", hours: &Hours{"08:00", "22:00"}, at: day(8, 0), want: day(8, 0)},
+		{name: "before the start", hours: &Hours{"08:00", "22:00"}, at: day(6, 15), want: day(8, 0)},
+		{name: "at the end", hours: &Hours{"08:00", "22:00"}, at: day(22, 0), want: day(8, 0).AddDate(0, 0, 1)},
+		{name: "after the end", hours: &Hours{"08:00", "22:00"}, at: day(23, 0), want: day(8, 0).AddDate(0, 0, 1)},
+		{name: "overnight, before midnight", hours: &Hours{"22:00", "06:00"}, at: day(23, 0), want: day(23, 0)},
+		{name: "overnight, after midnight", hours: &Hours{"22:00", "06:00"}, at: day(2, 0), want: day(2, 0)},
+		{name: "overnight, outside", hours: &Hours{"22:00", "06:00"}, at: day(12, 0), want: day(22, 0)},
+		{name: "empty window", hours: &Hours{"08:00", "08:00"}, at: day(3, 0), want: day(3, 0)},
+		{name: "invalid window", hours: &Hours{"8am", "22:00"}, at: day(3, 0), want: day(3, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.hours.Next(test.at); !got.Equal(test.want) {
+				t.Fatalf("Next(%s) = %s, want %s", test.at, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/cache"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/profiles"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// EventID is the ID of the "reconnect" event, which is published when the reconnection status of a device changes.
+const EventID uint = 105
+
+// EventName is the name of the "reconnect" event.
+const EventName = "reconnect"
+
+// policiesBucket is the store bucket of the reconnection policies.
+const policiesBucket = "reconnect_policies"
+
+// requestedDisconnectTimeout is the time after a requested disconnection, within which
+// the disconnection of the device does not trigger a reconnection.
+const requestedDisconnectTimeout = 30 * time.Second
+
+var (
+	// ErrPolicyNotFound is returned when a device has no reconnection policy.
+	ErrPolicyNotFound = errors.New("device has no reconnection policy")
+
+	// ErrInvalidPolicy is returned when the active hours, the backoff schedule or the profile UUID of a reconnection policy are invalid.
+	ErrInvalidPolicy = errors.New("invalid reconnection policy")
+)
+
+// State describes the reconnection state of a device.
+type State string
+
+// The reconnection states of a device.
+const (
+	StateIdle       State = "idle"
+	StateWaiting    State = "waiting"
+	StateInactive   State = "inactive"
+	StateConnecting State = "connecting"
+	StateConnected  State = "connected"
+	StateExhausted  State = "exhausted"
+)
+
+// The triggers of a reconnection.
+const (
+	TriggerDisconnected = "disconnected"
+	TriggerPoweredOn    = "powered_on"
+	TriggerStartup      = "startup"
+)
+
+// Reconnection describes the reconnection status of a device, and is published as a "reconnect" event.
+type Reconnection struct {
+	UpdatedAt   time.Time            `doc:"The time at which the status last changed." json:"updated_at"`
+	Address     bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	State       State                `doc:"The reconnection state of the device. Outside of the active hours of the policy, the state is 'inactive'." enum:"idle,waiting,inactive,connecting,connected,exhausted" json:"state"`
+	Trigger     string               `doc:"The event which triggered the reconnection." enum:"disconnected,powered_on,startup" json:"trigger,omitempty"`
+	Attempt     int                  `doc:"The number of the current or last reconnection attempt." json:"attempt,omitempty"`
+	NextAttempt *time.Time           `doc:"The time of the next reconnection attempt, if it is waiting." json:"next_attempt,omitempty"`
+	LastError   string               `doc:"The error of the last failed reconnection attempt." json:"last_error,omitempty"`
+}
+
+// Manager manages the reconnection policies of all devices.
+type Manager struct {
+	session bluetooth.Session
+	hub     *events.Hub
+	store   *store.Store
+	cache   *cache.Cache
+
+	subscriber *events.Subscriber
+	done       chan struct{}
+	wg         sync.WaitGroup
+
+	policies  map[bluetooth.MacAddress]Policy
+	statuses  map[bluetooth.MacAddress]*status
+	connected map[bluetooth.MacAddress]bool
+	powered   map[bluetooth.MacAddress]bool
+	requested map[bluetooth.MacAddress]time.Time
+	mu        sync.Mutex
+}
+
+// status holds the reconnection status of a device, and cancels its running reconnection.
+type status struct {
+	Reconnection
+
+	cancel context.CancelFunc
+}
+
+// New loads the reconnection policies from the store, and returns a new reconnection manager.
+// Use (*Manager).Start() to start reconnecting to the devices.
+func New(st *store.Store, hub *events.Hub) (*Manager, error) {
+	policies, err := store.List[Policy](st, policiesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load reconnection policies: %w", err)
+	}
+
+	m := &Manager{
+		hub:       hub,
+		store:     st,
+		done:      make(chan struct{}),
+		policies:  make(map[bluetooth.MacAddress]Policy, len(policies)),
+		statuses:  make(map[bluetooth.MacAddress]*status),
+		connected: make(map[bluetooth.MacAddress]bool),
+		powered:   make(map[bluetooth.MacAddress]bool),
+		requested: make(map[bluetooth.MacAddress]time.Time),
+	}
+
+	for _, p := range policies {
+		m.policies[p.Address] = p
+	}
+
+	return m, nil
+}
+
+// Start attaches the manager to the session, whose devices are reconnected to, and the cache, which is used
+// to resolve the connection states of the devices. The devices with an enabled policy which are not connected
+// are reconnected to, and the disconnections of devices and the power-on of adapters are watched.
+func (m *Manager) Start(session bluetooth.Session, c *cache.Cache) {
+	m.session, m.cache = session, c
+	m.subscriber = m.hub.Subscribe("device", "adapter")
+
+	snapshot := c.Snapshot()
+
+	m.mu.Lock()
+	for _, adapter := range snapshot.Adapters {
+		m.powered[adapter.Address] = adapter.Powered
+	}
+
+	for _, device := range snapshot.Devices {
+		m.connected[device.Address] = device.Connected
+	}
+
+	for address := range m.policies {
+		if !m.connected[address] {
+			m.trigger(address, TriggerStartup)
+		}
+	}
+	m.mu.Unlock()
+
+	go m.watch()
+}
+
+// Stop stops watching the devices and adapters, and cancels all running reconnections.
+func (m *Manager) Stop() {
+	if m == nil || m.subscriber == nil {
+		return
+	}
+
+	m.subscriber.Unsubscribe()
+	<-m.done
+
+	m.mu.Lock()
+	for _, s := range m.statuses {
+		if s.cancel != nil {
+			s.cancel()
+		}
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Policies returns the reconnection policies of all devices, sorted by address.
+func (m *Manager) Policies() []Policy {
+	policies := []Policy{}
+	if m == nil {
+		return policies
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.policies {
+		policies = append(policies, p)
+	}
+
+	slices.SortFunc(policies, func(a, b Policy) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return policies
+}
+
+// Policy returns the reconnection policy of a device.
+func (m *Manager) Policy(address bluetooth.MacAddress) (Policy, error) {
+	if m == nil {
+		return Policy{}, ErrPolicyNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.policies[address]
+	if !ok {
+		return Policy{}, ErrPolicyNotFound
+	}
+
+	return p, nil
+}
+
+// Status returns the reconnection status of a device.
+func (m *Manager) Status(address bluetooth.MacAddress) Reconnection {
+	if m == nil {
+		return Reconnection{Address: address, State: StateIdle}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.status(address)
+}
+
+// Put creates or replaces the reconnection policy of a device, and stores it. If the policy is disabled,
+// the running reconnection of the device is cancelled. The new policy applies from the next reconnection.
+func (m *Manager) Put(p Policy) (Policy, error) {
+	if p.ActiveHours != nil {
+		if err := p.ActiveHours.Validate(); err != nil {
+			return Policy{}, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+		}
+	}
+
+	if slices.Contains(p.Backoff, 0) {
+		return Policy{}, fmt.Errorf("%w: the backoff delays must be at least one second", ErrInvalidPolicy)
+	}
+
+	if p.Profile != "" {
+		profile, err := profiles.Resolve(p.Profile)
+		if err != nil {
+			return Policy{}, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+		}
+
+		p.Profile = profile.String()
+	}
+
+	if len(p.Backoff) == 0 {
+		p.Backoff = slices.Clone(DefaultBackoff)
+	}
+
+	p.UpdatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.store.Put(policiesBucket, p.Address.String(), p); err != nil {
+		return Policy{}, err
+	}
+
+	m.policies[p.Address] = p
+
+	if !p.Enabled {
+		m.reset(p.Address, StateIdle)
+	}
+
+	return p, nil
+}
+
+// Delete removes the reconnection policy of a device, and cancels its running reconnection.
+func (m *Manager) Delete(address bluetooth.MacAddress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.policies[address]; !ok {
+		return ErrPolicyNotFound
+	}
+
+	if err := m.store.Delete(policiesBucket, address.String()); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	delete(m.policies, address)
+
+	if s, ok := m.statuses[address]; ok && s.cancel != nil {
+		s.cancel()
+	}
+
+	delete(m.statuses, address)
+
+	return nil
+}
+
+// Disconnecting marks the next disconnection of a device as requested, so that it does not trigger a reconnection.
+// It must be called before a device is disconnected on behalf of a client.
+func (m *Manager) Disconnecting(address bluetooth.MacAddress) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requested[address] = time.Now()
+}
+
+// watch triggers the reconnections on the disconnection of devices and the power-on of adapters,
+// until the manager is stopped.
+func (m *Manager) watch() {
+	defer close(m.done)
+
+	for ev := range m.subscriber.C {
+		switch data := ev.Data.(type) {
+		case bluetooth.Event[bluetooth.DeviceEventData]:
+			m.device(data)
+
+		case bluetooth.Event[bluetooth.AdapterEventData]:
+			m.adapter(data)
+		}
+	}
+}
+
+// device tracks the connection state of a device, and triggers its reconnection when it disconnects.
+// If the device connects, its running reconnection is cancelled.
+func (m *Manager) device(ev bluetooth.Event[bluetooth.DeviceEventData]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address := ev.Data.Address
+	wasConnected := m.connected[address]
+
+	switch {
+	case ev.Action == bluetooth.EventActionRemoved:
+		delete(m.connected, address)
+		m.reset(address, StateIdle)
+
+	case ev.Data.Connected:
+		m.connected[address] = true
+		if _, ok := m.policies[address]; ok && !wasConnected {
+			m.reset(address, StateConnected)
+		}
+
+	default:
+		m.connected[address] = false
+		if !wasConnected {
+			return
+		}
+
+		if at, ok := m.requested[address]; ok && time.Since(at) < requestedDisconnectTimeout {
+			delete(m.requested, address)
+			slog.Debug("Device disconnection was requested, not reconnecting", "address", address.String())
+
+			return
+		}
+
+		m.trigger(address, TriggerDisconnected)
+	}
+}
+
+// adapter tracks the powered state of an adapter. When it is powered off, the running reconnections
+// of its devices are cancelled, and when it is powered on, its disconnected devices are reconnected to
+// with a new backoff schedule.
+func (m *Manager) adapter(ev bluetooth.Event[bluetooth.AdapterEventData]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address := ev.Data.Address
+	wasPowered, known := m.powered[address]
+
+	if ev.Action == bluetooth.EventActionRemoved {
+		delete(m.powered, address)
+
+		return
+	}
+
+	m.powered[address] = ev.Data.Powered
+	if ev.Data.Powered == wasPowered && known {
+		return
+	}
+
+	for device := range m.policies {
+		if adapter, ok := m.adapterOf(device); !ok || adapter != address {
+			continue
+		}
+
+		switch {
+		case !ev.Data.Powered:
+			if s, ok := m.statuses[device]; ok && s.cancel != nil {
+				m.reset(device, StateIdle)
+			}
+
+		case !m.connected[device]:
+			m.trigger(device, TriggerPoweredOn)
+		}
+	}
+}
+
+// adapterOf returns the address of the adapter a device is associated with, if the device is cached.
+func (m *Manager) adapterOf(device bluetooth.MacAddress) (bluetooth.MacAddress, bool) {
+	d, _, ok := m.cache.Device(device)
+
+	return d.AssociatedAdapter, ok
+}
+
+// trigger starts the reconnection of a device, if its policy is enabled, it is not already reconnecting,
+// and its adapter is not powered off. The manager must be locked by the caller.
+func (m *Manager) trigger(address bluetooth.MacAddress, trigger string) {
+	p, ok := m.policies[address]
+	if !ok || !p.Enabled {
+		return
+	}
+
+	if adapter, ok := m.adapterOf(address); ok {
+		if powered, known := m.powered[adapter]; known && !powered {
+			return
+		}
+	}
+
+	if s, ok := m.statuses[address]; ok && s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.statuses[address] = &status{
+		Reconnection: Reconnection{Address: address, Trigger: trigger},
+		cancel:       cancel,
+	}
+
+	slog.Info("Device reconnection started", "address", address.String(), "trigger", trigger)
+
+	m.wg.Add(1)
+	go m.reconnect(ctx, p)
+}
+
+// reset cancels the running reconnection of a device, if any, and sets its state if it has changed.
+// The manager must be locked by the caller.
+func (m *Manager) reset(address bluetooth.MacAddress, state State) {
+	s, ok := m.statuses[address]
+	if !ok {
+		if state == StateIdle {
+			return
+		}
+
+		s = &status{Reconnection: Reconnection{Address: address}}
+		m.statuses[address] = s
+	}
+
+	if s.cancel == nil && s.State == state {
+		return
+	}
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+
+	m.set(s, state, s.Attempt, nil, nil)
+}
+
+// reconnect makes the reconnection attempts of a device with the backoff schedule of its policy,
+// until it is connected, the attempts are exhausted or the reconnection is cancelled.
+func (m *Manager) reconnect(ctx context.Context, p Policy) {
+	defer m.wg.Done()
+
+	var lastErr error
+
+	for attempt := 1; p.MaxAttempts <= 0 || attempt <= p.MaxAttempts; attempt++ {
+		now := time.Now()
+		at := p.ActiveHours.Next(now.Add(p.delay(attempt)))
+
+		state := StateWaiting
+		if at.Sub(now) > p.delay(attempt) {
+			state = StateInactive
+		}
+
+		if !m.update(ctx, p.Address, state, attempt, &at, lastErr) {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(at))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+
+		case <-timer.C:
+		}
+
+		if !m.update(ctx, p.Address, StateConnecting, attempt, nil, lastErr) {
+			return
+		}
+
+		if lastErr = m.connect(p); lastErr == nil {
+			m.finish(ctx, p.Address, StateConnected, attempt, nil)
+
+			return
+		}
+
+		slog.Warn("Device reconnection attempt failed", "address", p.Address.String(), "attempt", attempt, "error", lastErr)
+	}
+
+	m.finish(ctx, p.Address, StateExhausted, p.MaxAttempts, lastErr)
+}
+
+// connect connects to the device of a policy, with the profile of the policy, if any.
+func (m *Manager) connect(p Policy) error {
+	deviceCall := m.session.Device(p.Address)
+
+	if p.Profile != "" {
+		profile, err := profiles.Resolve(p.Profile)
+		if err != nil {
+			return err
+		}
+
+		return deviceCall.ConnectProfile(profile)
+	}
+
+	return deviceCall.Connect()
+}
+
+// update sets the reconnection status of a device, if its reconnection is not cancelled,
+// and returns whether it was set.
+func (m *Manager) update(ctx context.Context, address bluetooth.MacAddress, state State, attempt int, next *time.Time, err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.statuses[address]
+	if !ok || ctx.Err() != nil {
+		return false
+	}
+
+	m.set(s, state, attempt, next, err)
+
+	return true
+}
+
+// finish sets the final reconnection status of a device, if its reconnection is not cancelled.
+func (m *Manager) finish(ctx context.Context, address bluetooth.MacAddress, state State, attempt int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.statuses[address]
+	if !ok || ctx.Err() != nil {
+		return
+	}
+
+	s.cancel()
+	s.cancel = nil
+
+	m.set(s, state, attempt, nil, err)
+
+	slog.Info("Device reconnection finished", "address", address.String(), "state", string(state), "attempts", attempt)
+}
+
+// set sets the reconnection status of a device, and publishes a "reconnect" event.
+// The manager must be locked by the caller.
+func (m *Manager) set(s *status, state State, attempt int, next *time.Time, err error) {
+	s.State, s.Attempt, s.NextAttempt, s.UpdatedAt = state, attempt, next, time.Now()
+
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+
+	m.hub.Publish(EventID, EventName, s.Reconnection)
+}
+
+// status returns the reconnection status of a device. The manager must be locked by the caller.
+func (m *Manager) status(address bluetooth.MacAddress) Reconnection {
+	if s, ok := m.statuses[address]; ok {
+		return s.Reconnection
+	}
+
+	return Reconnection{Address: address, State: StateIdle}
+}
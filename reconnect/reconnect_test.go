@@ -0,0 +1,63 @@
+package reconnect
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+func TestPutProfile(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	m, err := New(st, events.NewHub())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, _ := bluetooth.ParseMAC("00:1B:66:01:02:03")
+
+	tests := []struct {
+		profile string
+		want    string
+		invalid bool
+	}{
+		{profile: "", want: ""},
+		{profile: "0000110B-0000-1000-8000-00805F9B34FB", want: "0000110b-0000-1000-8000-00805f9b34fb"},
+		{profile: "a2dp-sink", want: "0000110b-0000-1000-8000-00805f9b34fb"},
+		{profile: "HID", want: "00001124-0000-1000-8000-00805f9b34fb"},
+		{profile: "walkie-talkie", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			p, err := m.Put(Policy{Address: address, Enabled: true, Profile: test.profile})
+			if test.invalid {
+				if !errors.Is(err, ErrInvalidPolicy) {
+					t.Fatalf("Put() = %v, want ErrInvalidPolicy", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if p.Profile != test.want {
+				t.Fatalf("profile = %q, want %q", p.Profile, test.want)
+			}
+
+			if stored, _ := m.Policy(address); stored.Profile != test.want {
+				t.Fatalf("stored profile = %q, want %q", stored.Profile, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package registry provides a persistent registry of device metadata, like labels, notes, tags, owners and groups,
+which is kept across daemon restarts.
+*/
+package registry
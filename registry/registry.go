@@ -0,0 +1,181 @@
+package registry
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// entriesBucket is the store bucket of the registry entries.
+const entriesBucket = "device_registry"
+
+// The different registry errors.
+var (
+	ErrEntryNotFound = errors.New("registry entry not found")
+	ErrGroupNotFound = errors.New("registry group not found")
+)
+
+// Metadata describes the registry entry of a device.
+type Metadata struct {
+	UpdatedAt time.Time            `doc:"The time at which the entry was last updated." json:"updated_at"`
+	Address   bluetooth.MacAddress `doc:"The address of the device." json:"address"`
+	Label     string               `doc:"The friendly label of the device." json:"label,omitempty"`
+	Notes     string               `doc:"Free-form notes about the device." json:"notes,omitempty"`
+	Owner     string               `doc:"The owner of the device." json:"owner,omitempty"`
+	Tags      []string             `doc:"The tags of the device." json:"tags,omitempty"`
+	Groups    []string             `doc:"The groups which the device is a member of." json:"groups,omitempty"`
+}
+
+// Group describes a group of devices.
+type Group struct {
+	Name    string                 `doc:"The name of the group." json:"name"`
+	Devices []bluetooth.MacAddress `doc:"The addresses of the member devices." json:"devices"`
+}
+
+// Registry holds the registry entries of all devices.
+type Registry struct {
+	store *store.Store
+
+	entries map[bluetooth.MacAddress]Metadata
+	mu      sync.RWMutex
+}
+
+// New loads and returns the registry from the store.
+func New(st *store.Store) (*Registry, error) {
+	entries, err := store.List[Metadata](st, entriesBucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load registry entries: %w", err)
+	}
+
+	r := &Registry{store: st, entries: make(map[bluetooth.MacAddress]Metadata, len(entries))}
+	for _, entry := range entries {
+		r.entries[entry.Address] = entry
+	}
+
+	return r, nil
+}
+
+// Entries returns the registry entries, sorted by address. If a group or a tag
+// is provided, only the entries of the devices in the group or with the tag are returned.
+func (r *Registry) Entries(group, tag string) []Metadata {
+	entries := []Metadata{}
+	if r == nil {
+		return entries
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, entry := range r.entries {
+		if (group == "" || slices.Contains(entry.Groups, group)) && (tag == "" || slices.Contains(entry.Tags, tag)) {
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b Metadata) int {
+		return slices.Compare(a.Address[:], b.Address[:])
+	})
+
+	return entries
+}
+
+// Entry returns the registry entry of a device, if it exists.
+func (r *Registry) Entry(address bluetooth.MacAddress) (Metadata, bool) {
+	if r == nil {
+		return Metadata{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[address]
+
+	return entry, ok
+}
+
+// Put creates or replaces the registry entry of a device.
+func (r *Registry) Put(entry Metadata) (Metadata, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.UpdatedAt = time.Now()
+	entry.Tags = compact(entry.Tags)
+	entry.Groups = compact(entry.Groups)
+
+	if err := r.store.Put(entriesBucket, entry.Address.String(), entry); err != nil {
+		return Metadata{}, err
+	}
+
+	r.entries[entry.Address] = entry
+
+	return entry, nil
+}
+
+// Delete removes the registry entry of a device.
+func (r *Registry) Delete(address bluetooth.MacAddress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.store.Delete(entriesBucket, address.String()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrEntryNotFound
+		}
+
+		return err
+	}
+
+	delete(r.entries, address)
+
+	return nil
+}
+
+// Groups returns all groups with their member devices, sorted by name.
+func (r *Registry) Groups() []Group {
+	groups := []Group{}
+	for _, entry := range r.Entries("", "") {
+		for _, name := range entry.Groups {
+			i := slices.IndexFunc(groups, func(g Group) bool { return g.Name == name })
+			if i < 0 {
+				groups = append(groups, Group{Name: name})
+				i = len(groups) - 1
+			}
+
+			groups[i].Devices = append(groups[i].Devices, entry.Address)
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b Group) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return groups
+}
+
+// Members returns the addresses of the devices in a group, sorted by address.
+// If the group has no members, ErrGroupNotFound is returned.
+func (r *Registry) Members(group string) ([]bluetooth.MacAddress, error) {
+	members := []bluetooth.MacAddress{}
+	for _, entry := range r.Entries(group, "") {
+		members = append(members, entry.Address)
+	}
+
+	if len(members) == 0 {
+		return nil, ErrGroupNotFound
+	}
+
+	return members, nil
+}
+
+// compact returns the sorted values without duplicates and empty values.
+func compact(values []string) []string {
+	values = slices.DeleteFunc(slices.Clone(values), func(v string) bool { return v == "" })
+	slices.Sort(values)
+
+	return slices.Compact(values)
+}
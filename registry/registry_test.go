@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// openTestStore opens a store in a temporary directory, which is closed when the test finishes.
+func openTestStore(t *testing.T, path string) *store.Store {
+	t.Helper()
+
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// addresses returns the addresses of the entries.
+func addresses(entries []Metadata) []bluetooth.MacAddress {
+	list := make([]bluetooth.MacAddress, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry.Address)
+	}
+
+	return list
+}
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+
+	r, err := New(openTestStore(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headphones, speaker, phone := mac("00:1B:66:01:02:03"), mac("00:1B:66:04:05:06"), mac("00:1B:66:07:08:09")
+
+	for _, entry := range []Metadata{
+		{Address: speaker, Label: "Speaker", Groups: []string{"living room"}, Tags: []string{"audio"}},
+		{Address: headphones, Label: "Headphones", Groups: []string{"office", "", "living room", "office"}, Tags: []string{"audio"}},
+		{Address: phone, Label: "Phone", Groups: []string{"office"}},
+	} {
+		if _, err := r.Put(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry, ok := r.Entry(headphones)
+	if !ok || entry.Label != "Headphones" || entry.UpdatedAt.IsZero() || !slices.Equal(entry.Groups, []string{"living room", "office"}) {
+		t.Fatalf("Entry() = %+v, %v, want the groups to be sorted and compacted", entry, ok)
+	}
+
+	tests := []struct {
+		name       string
+		group, tag string
+		want       []bluetooth.MacAddress
+	}{
+		{name: "all", want: []bluetooth.MacAddress{headphones, speaker, phone}},
+		{name: "group", group: "office", want: []bluetooth.MacAddress{headphones, phone}},
+		{name: "tag", tag: "audio", want: []bluetooth.MacAddress{headphones, speaker}},
+		{name: "group and tag", group: "office", tag: "audio", want: []bluetooth.MacAddress{headphones}},
+		{name: "unknown group", group: "kitchen", want: []bluetooth.MacAddress{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := addresses(r.Entries(test.group, test.tag)); !slices.Equal(got, test.want) {
+				t.Fatalf("Entries() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	groups := r.Groups()
+	if len(groups) != 2 || groups[0].Name != "living room" || groups[1].Name != "office" ||
+		!slices.Equal(groups[0].Devices, []bluetooth.MacAddress{headphones, speaker}) {
+		t.Fatalf("Groups() = %+v", groups)
+	}
+
+	if members, err := r.Members("office"); err != nil || !slices.Equal(members, []bluetooth.MacAddress{headphones, phone}) {
+		t.Fatalf("Members() = %v, %v", members, err)
+	}
+
+	if _, err := r.Members("kitchen"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("Members() of an unknown group = %v, want ErrGroupNotFound", err)
+	}
+
+	if err := r.Delete(phone); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Delete(phone); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Delete() of a removed entry = %v, want ErrEntryNotFound", err)
+	}
+
+	if _, ok := r.Entry(phone); ok {
+		t.Fatal("the removed entry still exists")
+	}
+}
+
+func TestRegistryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	address := mac("00:1B:66:01:02:03")
+
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Put(Metadata{Address: address, Label: "Headphones", Notes: "Charging case in the drawer"}); err != nil {
+		t.Fatal(err)
+	}
+
+	st.Close()
+
+	r, err = New(openTestStore(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, ok := r.Entry(address); !ok || entry.Label != "Headphones" || entry.Notes != "Charging case in the drawer" {
+		t.Fatalf("Entry() after reopening = %+v, %v", entry, ok)
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+
+	if entries := r.Entries("", ""); entries == nil || len(entries) != 0 {
+		t.Fatalf("Entries() = %v, want an empty list", entries)
+	}
+
+	if _, ok := r.Entry(mac("00:1B:66:01:02:03")); ok {
+		t.Fatal("Entry() of a nil registry succeeded")
+	}
+}
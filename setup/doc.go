@@ -0,0 +1,7 @@
+/*
+Package setup provides the one-shot device setup workflow, which discovers a device, pairs with it,
+optionally trusts it and connects to it, and publishes a "setup" event on each step. The authorization
+requests of the device are answered by the authorization policy of the setup, and if any step fails,
+the completed steps are rolled back.
+*/
+package setup
@@ -0,0 +1,538 @@
+package setup
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluerestd/audit"
+	"github.com/bluetuith-org/bluerestd/discovery"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/google/uuid"
+)
+
+// EventID is the ID of the "setup" event, which is published on each step of a setup.
+const EventID uint = 104
+
+// EventName is the name of the "setup" event.
+const EventName = "setup"
+
+// retention is the time for which finished setups are kept.
+const retention = 10 * time.Minute
+
+// discoveryOwner is the owner of the discovery sessions of the setups.
+const discoveryOwner = "setup"
+
+var (
+	// ErrSetupNotFound is returned when a setup does not exist.
+	ErrSetupNotFound = errors.New("setup not found")
+
+	// ErrSetupRunning is returned when a setup is created on an adapter which already has a running setup.
+	ErrSetupRunning = errors.New("the adapter already has a running setup")
+
+	// ErrDeviceNotFound is returned when no matching device was discovered within the discovery timeout.
+	ErrDeviceNotFound = errors.New("no matching device was discovered")
+
+	// ErrCancelled is returned when a setup is cancelled.
+	ErrCancelled = errors.New("the setup was cancelled")
+)
+
+// Auth describes how the authorization requests of the device are answered during a setup.
+type Auth string
+
+// The authorization policies of a setup.
+const (
+	// AuthManual sends the authorization requests to the clients as "auth" events.
+	AuthManual Auth = "manual"
+
+	// AuthConfirm accepts all authorization requests, and confirms any passkey.
+	AuthConfirm Auth = "confirm"
+
+	// AuthPIN only confirms a displayed or requested passkey, or a displayed pincode, if it matches the PIN.
+	// Since the daemon's agent does not enter pincodes, the PIN is compared with the passkey or pincode that
+	// is shown by the device. Pairing requests without a passkey ("Just Works") are rejected.
+	AuthPIN Auth = "pin"
+)
+
+// Step describes a step of a setup.
+type Step string
+
+// The steps of a setup.
+const (
+	StepDiscovering Step = "discovering"
+	StepPairing     Step = "pairing"
+	StepTrusting    Step = "trusting"
+	StepConnecting  Step = "connecting"
+	StepCompleted   Step = "completed"
+	StepRollingBack Step = "rolling_back"
+	StepFailed      Step = "failed"
+)
+
+// Request describes the parameters of a setup.
+type Request struct {
+	// Adapter holds the address of the adapter to set up the device on.
+	Adapter bluetooth.MacAddress
+
+	// Address holds the address of the device. If it is empty, the first discovered device
+	// whose name or alias contains Name (case-insensitive) is set up.
+	Address bluetooth.MacAddress
+	Name    string
+
+	// DiscoveryTimeout holds the time after which the setup fails, if no matching device was discovered.
+	DiscoveryTimeout time.Duration
+
+	// Auth holds the authorization policy, and PIN holds the expected passkey or pincode of the AuthPIN policy.
+	Auth Auth
+	PIN  string
+
+	// Trust sets whether the device is trusted in the Bluetooth service and by the daemon after it is paired.
+	Trust bool
+
+	// Profile holds the service profile UUID to connect to. If it is empty, a profile is chosen automatically.
+	Profile uuid.UUID
+
+	// Owner holds the identity of the caller which created the setup.
+	Owner string
+}
+
+// Setup describes the progress of a setup.
+type Setup struct {
+	CreatedAt  time.Time            `doc:"The time at which the setup was created." json:"created_at"`
+	UpdatedAt  time.Time            `doc:"The time at which the setup last changed its step." json:"updated_at"`
+	ID         string               `doc:"The ID of the setup." json:"setup_id"`
+	Owner      string               `doc:"The identity of the caller which created the setup." json:"owner,omitempty"`
+	Adapter    bluetooth.MacAddress `doc:"The address of the adapter." json:"adapter"`
+	Address    bluetooth.MacAddress `doc:"The address of the device. It is empty until the device is found." json:"address"`
+	Name       string               `doc:"The name or alias of the device." json:"name,omitempty"`
+	Step       Step                 `doc:"The current step of the setup." enum:"discovering,pairing,trusting,connecting,completed,rolling_back,failed" json:"step"`
+	Error      string               `doc:"The reason why the setup failed." json:"error,omitempty"`
+	RolledBack []Step               `doc:"The steps which were rolled back after the setup failed." json:"rolled_back,omitempty"`
+}
+
+// Manager manages the setups of all adapters.
+type Manager struct {
+	hub       *events.Hub
+	access    *access.Policy
+	session   bluetooth.Session
+	discovery *discovery.Manager
+
+	setups map[string]*run
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// run holds a setup and its parameters.
+type run struct {
+	Setup
+
+	req    Request
+	cancel context.CancelFunc
+	done   bool
+}
+
+// New returns a new setup manager. The devices are trusted using the provided access policy.
+// Use (*Manager).Start() to attach the manager to a session.
+func New(hub *events.Hub, policy *access.Policy) *Manager {
+	return &Manager{
+		hub:    hub,
+		access: policy,
+		setups: make(map[string]*run),
+	}
+}
+
+// Start attaches the manager to the session, whose devices are set up, and the discovery manager,
+// which is used to discover the devices.
+func (m *Manager) Start(session bluetooth.Session, manager *discovery.Manager) {
+	m.session = session
+	m.discovery = manager
+}
+
+// Stop cancels all running setups, and waits for them to be rolled back.
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	for _, r := range m.setups {
+		r.cancel()
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Create creates and starts a new setup.
+func (m *Manager) Create(req Request) (Setup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.setups {
+		if !r.done && r.Adapter == req.Adapter {
+			return Setup{}, ErrSetupRunning
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	now := time.Now()
+	r := &run{
+		Setup: Setup{
+			CreatedAt: now,
+			UpdatedAt: now,
+			ID:        uuid.NewString(),
+			Owner:     req.Owner,
+			Adapter:   req.Adapter,
+			Address:   req.Address,
+			Step:      StepDiscovering,
+		},
+		req:    req,
+		cancel: cancel,
+	}
+
+	m.setups[r.ID] = r
+
+	slog.Info("Device setup created",
+		"setup_id", r.ID, "adapter", req.Adapter.String(), "address", req.Address.String(),
+		"name", req.Name, "auth", string(req.Auth), "owner", req.Owner,
+	)
+
+	m.wg.Add(1)
+	go m.run(ctx, r)
+
+	return r.Setup, nil
+}
+
+// Setups returns the running and recently finished setups of an adapter.
+func (m *Manager) Setups(adapter bluetooth.MacAddress) []Setup {
+	setups := []Setup{}
+	if m == nil {
+		return setups
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.setups {
+		if r.Adapter == adapter {
+			setups = append(setups, r.snapshot())
+		}
+	}
+
+	slices.SortFunc(setups, func(a, b Setup) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return setups
+}
+
+// Setup returns a setup of an adapter.
+func (m *Manager) Setup(adapter bluetooth.MacAddress, id string) (Setup, error) {
+	if m == nil {
+		return Setup{}, ErrSetupNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.setups[id]
+	if !ok || r.Adapter != adapter {
+		return Setup{}, ErrSetupNotFound
+	}
+
+	return r.snapshot(), nil
+}
+
+// Cancel cancels a running setup of an adapter which was created by the owner, whose completed
+// steps are then rolled back. Cancelling a finished setup has no effect. The owners are compared
+// by their principals, so that setups can be cancelled from other connections of the same caller.
+func (m *Manager) Cancel(adapter bluetooth.MacAddress, id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.setups[id]
+	if !ok || r.Adapter != adapter || audit.Principal(r.Owner) != audit.Principal(owner) {
+		return ErrSetupNotFound
+	}
+
+	r.cancel()
+
+	return nil
+}
+
+// Authorize decides an authorization request of a device using the authorization policy of its running setup,
+// and returns whether it was decided and accepted. The passkey or pincode, if any, are checked by the AuthPIN policy.
+// Undecided requests must be sent to the clients.
+func (m *Manager) Authorize(address bluetooth.MacAddress, requestType string, passkey uint32, pincode string) (bool, bool) {
+	if m == nil {
+		return false, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var req *Request
+	for _, r := range m.setups {
+		if !r.done && r.Address == address {
+			req = &r.req
+
+			break
+		}
+	}
+
+	if req == nil || req.Auth == AuthManual {
+		return false, false
+	}
+
+	switch requestType {
+	case "confirm-passkey", "display-passkey":
+		if req.Auth == AuthPIN {
+			return true, fmt.Sprintf("%06d", passkey) == req.PIN || strconv.FormatUint(uint64(passkey), 10) == req.PIN
+		}
+
+		return requestType == "confirm-passkey", true
+
+	case "display-pincode":
+		if req.Auth == AuthPIN {
+			return true, pincode == req.PIN
+		}
+
+		return false, false
+
+	case "authorize-pairing":
+		return true, req.Auth != AuthPIN
+
+	case "authorize-service":
+		return true, true
+	}
+
+	return false, false
+}
+
+// run runs a setup, and rolls back its completed steps if any step fails.
+func (m *Manager) run(ctx context.Context, r *run) {
+	defer m.wg.Done()
+	defer r.cancel()
+
+	m.publish(r, StepDiscovering, nil)
+
+	var rollback []func() (Step, error)
+
+	err := m.steps(ctx, r, &rollback)
+	if err == nil {
+		m.finish(r, StepCompleted, nil)
+
+		return
+	}
+
+	if errors.Is(err, context.Canceled) {
+		err = ErrCancelled
+	}
+
+	m.publish(r, StepRollingBack, err)
+
+	for _, undo := range slices.Backward(rollback) {
+		step, e := undo()
+		if e != nil {
+			slog.Warn("Cannot roll back device setup step", "setup_id", r.ID, "step", string(step), "error", e)
+
+			continue
+		}
+
+		m.mu.Lock()
+		r.RolledBack = append(r.RolledBack, step)
+		m.mu.Unlock()
+	}
+
+	m.finish(r, StepFailed, err)
+}
+
+// steps runs the steps of a setup, and adds the rollback of each completed step.
+func (m *Manager) steps(ctx context.Context, r *run, rollback *[]func() (Step, error)) error {
+	device, err := m.find(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	address := device.Address
+	deviceCall := m.session.Device(address)
+
+	m.mu.Lock()
+	r.Address, r.Name = address, cmp.Or(device.Alias, device.Name)
+	m.mu.Unlock()
+
+	if !device.Paired {
+		m.publish(r, StepPairing, nil)
+
+		if err := m.pair(ctx, deviceCall); err != nil {
+			return fmt.Errorf("cannot pair with device: %w", err)
+		}
+
+		*rollback = append(*rollback, func() (Step, error) {
+			return StepPairing, deviceCall.Remove()
+		})
+	}
+
+	if trusted := m.access.Trusted(address); r.req.Trust && (!trusted || !device.Trusted) {
+		m.publish(r, StepTrusting, nil)
+
+		if _, err := m.access.SetTrusted(address, true); err != nil {
+			return fmt.Errorf("cannot trust device: %w", err)
+		}
+
+		*rollback = append(*rollback, func() (Step, error) {
+			_, err := m.access.SetTrusted(address, trusted)
+
+			return StepTrusting, err
+		})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.publish(r, StepConnecting, nil)
+
+	if r.req.Profile != uuid.Nil {
+		err = deviceCall.ConnectProfile(r.req.Profile)
+	} else {
+		err = deviceCall.Connect()
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot connect to device: %w", err)
+	}
+
+	return ctx.Err()
+}
+
+// find returns the device of a setup. If the device is not known to the adapter, a discovery session
+// is created until a matching device is discovered, or the discovery timeout expires.
+func (m *Manager) find(ctx context.Context, r *run) (bluetooth.DeviceData, error) {
+	if r.req.Address != (bluetooth.MacAddress{}) {
+		device, err := m.session.Device(r.req.Address).Properties()
+		if err == nil && device.AssociatedAdapter == r.req.Adapter {
+			return device, nil
+		}
+	}
+
+	filter := discovery.Filter{Name: r.req.Name}
+
+	session, err := m.discovery.Create(r.req.Adapter, r.req.DiscoveryTimeout, filter, discoveryOwner)
+	if err != nil {
+		return bluetooth.DeviceData{}, err
+	}
+	defer m.discovery.Remove(r.req.Adapter, session.ID, discoveryOwner)
+
+	matches := func(device bluetooth.DeviceData) bool {
+		return r.req.Address == (bluetooth.MacAddress{}) || device.Address == r.req.Address
+	}
+
+	var found *bluetooth.DeviceData
+
+	watchCtx, cancel := context.WithTimeout(ctx, r.req.DiscoveryTimeout)
+	defer cancel()
+
+	err = m.discovery.Watch(watchCtx, r.req.Adapter, session.ID, discoveryOwner, func(device bluetooth.DeviceData) bool {
+		if matches(device) {
+			found = &device
+		}
+
+		return found == nil
+	})
+
+	switch {
+	case found != nil:
+		return *found, nil
+
+	case ctx.Err() != nil:
+		return bluetooth.DeviceData{}, ctx.Err()
+
+	case err != nil && !errors.Is(err, discovery.ErrSessionNotFound):
+		return bluetooth.DeviceData{}, err
+	}
+
+	// The devices which were discovered before the session was watched are only included in its results.
+	results, _ := m.discovery.Results(r.req.Adapter, session.ID, discoveryOwner)
+	if i := slices.IndexFunc(results, matches); i >= 0 {
+		return results[i], nil
+	}
+
+	return bluetooth.DeviceData{}, ErrDeviceNotFound
+}
+
+// pair pairs with a device, and cancels the pairing if the setup is cancelled.
+// If the pairing completes regardless, the device is removed.
+func (m *Manager) pair(ctx context.Context, deviceCall bluetooth.Device) error {
+	paired := make(chan error, 1)
+	go func() {
+		paired <- deviceCall.Pair()
+	}()
+
+	select {
+	case err := <-paired:
+		return err
+
+	case <-ctx.Done():
+		deviceCall.CancelPairing()
+
+		if err := <-paired; err == nil {
+			deviceCall.Remove()
+		}
+
+		return ctx.Err()
+	}
+}
+
+// publish sets the current step of a setup, and publishes a "setup" event.
+func (m *Manager) publish(r *run, step Step, err error) {
+	m.mu.Lock()
+	r.Step, r.UpdatedAt = step, time.Now()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	setup := r.snapshot()
+	m.mu.Unlock()
+
+	m.hub.Publish(EventID, EventName, setup)
+}
+
+// finish sets the final step of a setup, publishes a "setup" event, and removes the setup after the retention time.
+func (m *Manager) finish(r *run, step Step, err error) {
+	m.mu.Lock()
+	r.done = true
+	m.mu.Unlock()
+
+	m.publish(r, step, err)
+
+	attrs := []any{"setup_id", r.ID, "adapter", r.Adapter.String(), "address", r.Address.String()}
+	if err != nil {
+		slog.Warn("Device setup failed", append(attrs, "error", err)...)
+	} else {
+		slog.Info("Device setup completed", attrs...)
+	}
+
+	time.AfterFunc(retention, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.setups, r.ID)
+	})
+}
+
+// snapshot returns a copy of the setup. The manager must be locked by the caller.
+func (r *run) snapshot() Setup {
+	setup := r.Setup
+	setup.RolledBack = slices.Clone(r.RolledBack)
+
+	return setup
+}
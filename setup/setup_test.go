@@ -0,0 +1,380 @@
+package setup
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/access"
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// testTimeout is the maximum time to wait for a setup to finish.
+const testTimeout = 5 * time.Second
+
+// The owners of the setups in the tests.
+const (
+	owner      = "127.0.0.1:50000"
+	otherOwner = "192.168.1.2:50000"
+)
+
+// fakeSession is a session with a single known device, which records the calls made to it.
+// Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	device     bluetooth.DeviceData
+	connectErr error
+	pairing    chan error
+
+	calls []string
+	mu    sync.Mutex
+}
+
+// fakeDevice is the device of a fakeSession.
+type fakeDevice struct {
+	bluetooth.Device
+
+	session *fakeSession
+}
+
+func (s *fakeSession) Device(bluetooth.MacAddress) bluetooth.Device {
+	return fakeDevice{session: s}
+}
+
+// record records a call.
+func (s *fakeSession) record(call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+}
+
+// recorded returns the recorded calls.
+func (s *fakeSession) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.calls...)
+}
+
+func (d fakeDevice) Properties() (bluetooth.DeviceData, error) {
+	return d.session.device, nil
+}
+
+func (d fakeDevice) Pair() error {
+	d.session.record("pair")
+
+	if d.session.pairing != nil {
+		return <-d.session.pairing
+	}
+
+	return nil
+}
+
+func (d fakeDevice) CancelPairing() error {
+	d.session.record("cancel pairing")
+
+	if d.session.pairing != nil {
+		d.session.pairing <- errors.New("pairing cancelled")
+	}
+
+	return nil
+}
+
+func (d fakeDevice) Remove() error {
+	d.session.record("remove")
+
+	return nil
+}
+
+func (d fakeDevice) Connect() error {
+	d.session.record("connect")
+
+	return d.session.connectErr
+}
+
+// mac parses an address.
+func mac(address string) bluetooth.MacAddress {
+	m, _ := bluetooth.ParseMAC(address)
+
+	return m
+}
+
+// The addresses of the adapter and the device in the tests.
+var (
+	testAdapter = mac("00:1A:7D:DA:71:13")
+	testDevice  = mac("00:1B:66:01:02:03")
+)
+
+// newTestManager starts a setup manager of a new session with a known, unpaired device.
+// The access policy is not attached to the session, so that the devices are only trusted by the daemon.
+func newTestManager(t *testing.T) (*Manager, *fakeSession, *access.Policy) {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := access.New(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &fakeSession{
+		device: bluetooth.DeviceData{
+			Name:            "headphones",
+			DeviceEventData: bluetooth.DeviceEventData{Address: testDevice, AssociatedAdapter: testAdapter},
+		},
+	}
+
+	m := New(events.NewHub(), policy)
+	m.Start(session, nil)
+
+	t.Cleanup(func() {
+		m.Stop()
+		st.Close()
+	})
+
+	return m, session, policy
+}
+
+// wait waits until a setup is finished, and returns it.
+func wait(t *testing.T, m *Manager, id string) Setup {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+
+	for {
+		setup, err := m.Setup(testAdapter, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if setup.Step == StepCompleted || setup.Step == StepFailed {
+			return setup
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("setup is still at the %s step", setup.Step)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// paired waits until the device is being paired with.
+func paired(t *testing.T, session *fakeSession) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !slices.Contains(session.recorded(), "pair") {
+		if time.Now().After(deadline) {
+			t.Fatal("the device was not paired with")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	m, session, policy := newTestManager(t)
+
+	steps := m.hub.Subscribe(EventName)
+	defer steps.Unsubscribe()
+
+	created, err := m.Create(Request{Adapter: testAdapter, Address: testDevice, Trust: true, Owner: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Create(Request{Adapter: testAdapter, Name: "other"}); !errors.Is(err, ErrSetupRunning) {
+		t.Fatalf("Create() of a second setup = %v, want ErrSetupRunning", err)
+	}
+
+	setup := wait(t, m, created.ID)
+	if setup.Step != StepCompleted || setup.Error != "" || setup.Name != "headphones" || len(setup.RolledBack) != 0 {
+		t.Fatalf("setup = %+v, want completed", setup)
+	}
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"pair", "connect"}) {
+		t.Fatalf("calls = %v, want [pair connect]", calls)
+	}
+
+	if !policy.Trusted(testDevice) {
+		t.Fatal("the device is not trusted")
+	}
+
+	var published []Step
+
+	for len(published) < 5 {
+		select {
+		case ev := <-steps.C:
+			published = append(published, ev.Data.(Setup).Step)
+
+		case <-time.After(testTimeout):
+			t.Fatalf("published steps = %v, want 5 steps", published)
+		}
+	}
+
+	if want := []Step{StepDiscovering, StepPairing, StepTrusting, StepConnecting, StepCompleted}; !slices.Equal(published, want) {
+		t.Fatalf("published steps = %v, want %v", published, want)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		trusted   bool
+		paired    bool
+		calls     []string
+		rolled    []Step
+		untrusted bool
+	}{
+		{
+			name:      "unpaired device",
+			calls:     []string{"pair", "connect", "remove"},
+			rolled:    []Step{StepTrusting, StepPairing},
+			untrusted: true,
+		},
+		{
+			name:      "paired device",
+			paired:    true,
+			calls:     []string{"connect"},
+			rolled:    []Step{StepTrusting},
+			untrusted: true,
+		},
+		{
+			name:    "trusted device",
+			trusted: true,
+			calls:   []string{"pair", "connect", "remove"},
+			rolled:  []Step{StepTrusting, StepPairing},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, session, policy := newTestManager(t)
+			session.device.Paired = test.paired
+			session.connectErr = errors.New("connection refused")
+
+			if test.trusted {
+				if _, err := policy.SetTrusted(testDevice, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			created, err := m.Create(Request{Adapter: testAdapter, Address: testDevice, Trust: true, Owner: owner})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			setup := wait(t, m, created.ID)
+			if setup.Step != StepFailed || setup.Error != "cannot connect to device: connection refused" {
+				t.Fatalf("setup = %+v, want failed", setup)
+			}
+
+			if !slices.Equal(setup.RolledBack, test.rolled) {
+				t.Fatalf("rolled back steps = %v, want %v", setup.RolledBack, test.rolled)
+			}
+
+			if calls := session.recorded(); !slices.Equal(calls, test.calls) {
+				t.Fatalf("calls = %v, want %v", calls, test.calls)
+			}
+
+			if policy.Trusted(testDevice) == test.untrusted {
+				t.Fatalf("trusted = %v after the rollback, want %v", !test.untrusted, !test.untrusted)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m, session, policy := newTestManager(t)
+	session.pairing = make(chan error, 1)
+
+	created, err := m.Create(Request{Adapter: testAdapter, Address: testDevice, Trust: true, Owner: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paired(t, session)
+
+	if err := m.Cancel(testAdapter, created.ID, otherOwner); !errors.Is(err, ErrSetupNotFound) {
+		t.Fatalf("Cancel() by another owner = %v, want ErrSetupNotFound", err)
+	}
+
+	if err := m.Cancel(testAdapter, "unknown", owner); !errors.Is(err, ErrSetupNotFound) {
+		t.Fatalf("Cancel() of an unknown setup = %v, want ErrSetupNotFound", err)
+	}
+
+	if err := m.Cancel(testAdapter, created.ID, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	setup := wait(t, m, created.ID)
+	if setup.Step != StepFailed || setup.Error != ErrCancelled.Error() || len(setup.RolledBack) != 0 {
+		t.Fatalf("setup = %+v, want cancelled without rollbacks", setup)
+	}
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"pair", "cancel pairing"}) {
+		t.Fatalf("calls = %v, want [pair cancel pairing]", calls)
+	}
+
+	if policy.Trusted(testDevice) {
+		t.Fatal("the device of a cancelled setup is trusted")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		auth     Auth
+		pin      string
+		request  string
+		passkey  uint32
+		pincode  string
+		decided  bool
+		accepted bool
+	}{
+		{auth: AuthManual, request: "authorize-service"},
+		{auth: AuthConfirm, request: "confirm-passkey", passkey: 1234, decided: true, accepted: true},
+		{auth: AuthConfirm, request: "display-passkey", passkey: 1234, accepted: true},
+		{auth: AuthConfirm, request: "display-pincode", pincode: "0000"},
+		{auth: AuthConfirm, request: "authorize-pairing", decided: true, accepted: true},
+		{auth: AuthPIN, pin: "001234", request: "confirm-passkey", passkey: 1234, decided: true, accepted: true},
+		{auth: AuthPIN, pin: "1234", request: "display-passkey", passkey: 1234, decided: true, accepted: true},
+		{auth: AuthPIN, pin: "123456", request: "confirm-passkey", passkey: 1234, decided: true},
+		{auth: AuthPIN, pin: "0000", request: "display-pincode", pincode: "0000", decided: true, accepted: true},
+		{auth: AuthPIN, pin: "0000", request: "display-pincode", pincode: "1111", decided: true},
+		{auth: AuthPIN, pin: "0000", request: "authorize-pairing", decided: true},
+		{auth: AuthPIN, pin: "0000", request: "authorize-service", decided: true, accepted: true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.auth)+" "+test.request, func(t *testing.T) {
+			m, session, _ := newTestManager(t)
+			session.pairing = make(chan error, 1)
+
+			if _, err := m.Create(Request{Adapter: testAdapter, Address: testDevice, Auth: test.auth, PIN: test.pin, Owner: owner}); err != nil {
+				t.Fatal(err)
+			}
+
+			paired(t, session)
+
+			decided, accepted := m.Authorize(testDevice, test.request, test.passkey, test.pincode)
+			if decided != test.decided || accepted != test.accepted {
+				t.Fatalf("Authorize() = %v, %v, want %v, %v", decided, accepted, test.decided, test.accepted)
+			}
+
+			if decided, _ := m.Authorize(mac("00:1B:66:0A:0B:0C"), test.request, test.passkey, test.pincode); decided {
+				t.Fatal("the request of another device was decided")
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package store provides a persistent key-value store for the daemon's state, backed by an embedded database file.
+*/
+package store
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned if a key does not exist in a bucket.
+var ErrNotFound = errors.New("key not found")
+
+// openTimeout is the maximum time to wait for the database file lock.
+const openTimeout = 2 * time.Second
+
+// Store is a persistent key-value store, which stores values as JSON documents
+// within named buckets.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the store at the provided path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open store '%s': %w", path, err)
+	}
+
+	return &Store{db}, nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put stores the JSON encoded value with the provided key in a bucket.
+func (s *Store) Put(bucket, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), data)
+	})
+}
+
+// Get decodes the value with the provided key in a bucket into 'value'.
+// If the key does not exist, ErrNotFound is returned.
+func (s *Store) Get(bucket, key string, value any) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		data := b.Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		return json.Unmarshal(data, value)
+	})
+}
+
+// Delete removes the key from a bucket. If the key does not exist, ErrNotFound is returned.
+func (s *Store) Delete(bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil || b.Get([]byte(key)) == nil {
+			return ErrNotFound
+		}
+
+		return b.Delete([]byte(key))
+	})
+}
+
+// List decodes and returns all values in a bucket, ordered by their keys.
+func List[T any](s *Store, bucket string) ([]T, error) {
+	var values []T
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, data []byte) error {
+			var value T
+			if err := json.Unmarshal(data, &value); err != nil {
+				return err
+			}
+
+			values = append(values, value)
+
+			return nil
+		})
+	})
+
+	return values, err
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// record is a value stored in the tests.
+type record struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// openTestStore opens a new store in a temporary directory, which is closed when the test finishes.
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "data", "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestStore(t *testing.T) {
+	s := openTestStore(t)
+
+	if err := s.Get("records", "a", &record{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() from a missing bucket = %v, want ErrNotFound", err)
+	}
+
+	for _, r := range []record{{"b", 2}, {"a", 1}, {"c", 3}} {
+		if err := s.Put("records", r.Name, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Put("records", "b", record{"b", 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got record
+	if err := s.Get("records", "b", &got); err != nil || got != (record{"b", 20}) {
+		t.Fatalf("Get() = %+v, %v, want the replaced value", got, err)
+	}
+
+	if err := s.Get("records", "d", &got); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	list, err := List[record](s, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []record{{"a", 1}, {"b", 20}, {"c", 3}}; !slices.Equal(list, want) {
+		t.Fatalf("List() = %v, want %v", list, want)
+	}
+
+	if err := s.Delete("records", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "missing"} {
+		if err := s.Delete("records", key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Delete(%q) = %v, want ErrNotFound", key, err)
+		}
+	}
+
+	if err := s.Delete("other", "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete() from a missing bucket = %v, want ErrNotFound", err)
+	}
+
+	if list, err := List[record](s, "other"); err != nil || len(list) != 0 {
+		t.Fatalf("List() of a missing bucket = %v, %v, want no values", list, err)
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put("records", "a", record{"a", 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var got record
+	if err := s.Get("records", "a", &got); err != nil || got != (record{"a", 1}) {
+		t.Fatalf("Get() after reopening = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package supervisor provides a Bluetooth session, which detects the loss of the underlying session
+and re-establishes it with an exponential backoff.
+*/
+package supervisor
@@ -0,0 +1,74 @@
+package supervisor
+
+import (
+	"context"
+
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+	"github.com/google/uuid"
+)
+
+// stoppedAdapter implements bluetooth.Adapter for a stopped supervisor, whose calls always fail.
+type stoppedAdapter struct{}
+
+func (stoppedAdapter) StartDiscovery() error           { return errorkinds.ErrSessionNotExist }
+func (stoppedAdapter) StopDiscovery() error            { return errorkinds.ErrSessionNotExist }
+func (stoppedAdapter) SetPoweredState(bool) error      { return errorkinds.ErrSessionNotExist }
+func (stoppedAdapter) SetDiscoverableState(bool) error { return errorkinds.ErrSessionNotExist }
+func (stoppedAdapter) SetPairableState(bool) error     { return errorkinds.ErrSessionNotExist }
+func (stoppedAdapter) Properties() (bluetooth.AdapterData, error) {
+	return bluetooth.AdapterData{}, errorkinds.ErrSessionNotExist
+}
+func (stoppedAdapter) Devices() ([]bluetooth.DeviceData, error) {
+	return nil, errorkinds.ErrSessionNotExist
+}
+
+// stoppedDevice implements bluetooth.Device for a stopped supervisor, whose calls always fail.
+type stoppedDevice struct{}
+
+func (stoppedDevice) Pair() error                       { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) CancelPairing() error              { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) Connect() error                    { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) Disconnect() error                 { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) ConnectProfile(uuid.UUID) error    { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) DisconnectProfile(uuid.UUID) error { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) Remove() error                     { return errorkinds.ErrSessionNotExist }
+func (stoppedDevice) Properties() (bluetooth.DeviceData, error) {
+	return bluetooth.DeviceData{}, errorkinds.ErrSessionNotExist
+}
+
+// stoppedObex implements bluetooth.Obex and bluetooth.ObexFileTransfer for a stopped supervisor, whose calls always fail.
+type stoppedObex struct{}
+
+func (stoppedObex) FileTransfer() bluetooth.ObexFileTransfer { return stoppedObex{} }
+func (stoppedObex) CreateSession(context.Context) error      { return errorkinds.ErrSessionNotExist }
+func (stoppedObex) RemoveSession() error                     { return errorkinds.ErrSessionNotExist }
+func (stoppedObex) SendFile(string) (bluetooth.FileTransferData, error) {
+	return bluetooth.FileTransferData{}, errorkinds.ErrSessionNotExist
+}
+func (stoppedObex) CancelTransfer() error  { return errorkinds.ErrSessionNotExist }
+func (stoppedObex) SuspendTransfer() error { return errorkinds.ErrSessionNotExist }
+func (stoppedObex) ResumeTransfer() error  { return errorkinds.ErrSessionNotExist }
+
+// stoppedNetwork implements bluetooth.Network for a stopped supervisor, whose calls always fail.
+type stoppedNetwork struct{}
+
+func (stoppedNetwork) Connect(string, bluetooth.NetworkType) error {
+	return errorkinds.ErrSessionNotExist
+}
+func (stoppedNetwork) Disconnect() error { return errorkinds.ErrSessionNotExist }
+
+// stoppedMediaPlayer implements bluetooth.MediaPlayer for a stopped supervisor, whose calls always fail.
+type stoppedMediaPlayer struct{}
+
+func (stoppedMediaPlayer) Properties() (bluetooth.MediaData, error) {
+	return bluetooth.MediaData{}, errorkinds.ErrSessionNotExist
+}
+func (stoppedMediaPlayer) Play() error            { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) Pause() error           { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) TogglePlayPause() error { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) Next() error            { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) Previous() error        { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) FastForward() error     { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) Rewind() error          { return errorkinds.ErrSessionNotExist }
+func (stoppedMediaPlayer) Stop() error            { return errorkinds.ErrSessionNotExist }
@@ -0,0 +1,504 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	ac "github.com/bluetuith-org/bluetooth-classic/api/appfeatures"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/config"
+	"github.com/bluetuith-org/bluetooth-classic/api/eventbus"
+	"github.com/bluetuith-org/bluetooth-classic/api/platforminfo"
+)
+
+// EventID is the ID of the "session" event, which is published on each change of the session state.
+const EventID uint = 101
+
+// EventName is the name of the "session" event.
+const EventName = "session"
+
+// The default supervision settings.
+const (
+	DefaultCheckInterval  = 5 * time.Second
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+)
+
+// State describes the state of the supervised session.
+type State string
+
+// The different session states.
+const (
+	StateUp       State = "up"
+	StateDown     State = "down"
+	StateStarting State = "starting"
+	StateStopped  State = "stopped"
+)
+
+// ErrSessionLost is returned when the session has been lost.
+var ErrSessionLost = errors.New("bluetooth session lost")
+
+// noAdaptersReason is the reason of the "down" state, while the session is running without any adapters.
+const noAdaptersReason = "no adapters available"
+
+// Config describes the supervision settings.
+type Config struct {
+	// CheckInterval holds the interval between each check of the session.
+	CheckInterval time.Duration
+
+	// InitialBackoff holds the wait time before the first restart attempt.
+	// It is doubled after each failed attempt, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Status describes the status of the supervised session.
+type Status struct {
+	Since      time.Time `doc:"The time at which the session changed to its current state." json:"since"`
+	State      State     `doc:"The state of the session." enum:"up,down,starting,stopped" json:"state"`
+	Reason     string    `doc:"The reason why the session is down, if it is down." json:"reason,omitempty"`
+	Attempt    int       `doc:"The number of failed restart attempts, if the session is down." json:"attempt,omitempty"`
+	RetryAfter int       `doc:"The number of seconds until the next restart attempt, if the session is down." json:"retry_after,omitempty"`
+
+	retryAt time.Time
+}
+
+// Supervisor implements the bluetooth.Session interface. It delegates each call to the
+// current underlying session, and restarts the underlying session if it is lost.
+type Supervisor struct {
+	cfg        Config
+	newSession func() bluetooth.Session
+	hub        *events.Hub
+
+	authorizer    bluetooth.SessionAuthorizer
+	sessionConfig config.Configuration
+
+	session   bluetooth.Session
+	features  ac.FeatureSet
+	platform  platforminfo.PlatformInfo
+	startedAt time.Time
+	status    Status
+	mu        sync.RWMutex
+
+	lost       chan string
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	supervised bool
+}
+
+// New returns a new supervisor, which creates each underlying session using the provided function.
+// All session events, including the "session" event, are published to the provided event hub.
+func New(newSession func() bluetooth.Session, hub *events.Hub, cfg Config) *Supervisor {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultCheckInterval
+	}
+
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.InitialBackoff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Supervisor{
+		cfg:        cfg,
+		newSession: newSession,
+		hub:        hub,
+		status:     Status{State: StateStarting, Since: time.Now()},
+		lost:       make(chan string, 1),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts the underlying session, and starts supervising it.
+// If the initial session cannot be started, an error is returned, and the session is not supervised.
+func (s *Supervisor) Start(authHandler bluetooth.SessionAuthorizer, cfg config.Configuration) (ac.FeatureSet, platforminfo.PlatformInfo, error) {
+	s.authorizer, s.sessionConfig = authHandler, cfg
+
+	if err := s.start(); err != nil {
+		return s.features, s.platform, err
+	}
+
+	s.supervised = true
+	go s.supervise()
+
+	return s.Features(), s.Platform(), nil
+}
+
+// Stop stops supervising the session, and stops the underlying session.
+// The calls on the session fail after it is stopped.
+func (s *Supervisor) Stop() error {
+	s.cancel()
+
+	if s.supervised {
+		<-s.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = Status{State: StateStopped, Since: time.Now()}
+	if s.session == nil {
+		return nil
+	}
+
+	err := s.session.Stop()
+	s.session = nil
+
+	return err
+}
+
+// Status returns the status of the supervised session.
+// The status, features, platform information and start time are empty for a nil supervisor.
+func (s *Supervisor) Status() Status {
+	if s == nil {
+		return Status{}
+	}
+
+	s.mu.RLock()
+	status := s.status
+	s.mu.RUnlock()
+
+	if status.State == StateDown {
+		status.RetryAfter = retryAfterSeconds(time.Until(status.retryAt))
+	}
+
+	return status
+}
+
+// Healthy returns whether the supervisor is running, that is, whether the session is up,
+// or is being restarted or waited for.
+func (s *Supervisor) Healthy() bool {
+	return s.Status().State != StateStopped
+}
+
+// RetryAfter returns the number of seconds to wait before the session is expected to be available again,
+// for use in the "Retry-After" header. If the session is up, or the supervisor is nil, zero is returned.
+func (s *Supervisor) RetryAfter() int {
+	if s == nil {
+		return 0
+	}
+
+	status := s.Status()
+
+	switch status.State {
+	case StateUp:
+		return 0
+
+	case StateDown:
+		return status.RetryAfter
+	}
+
+	return 1
+}
+
+// Features returns the features of the current underlying session.
+func (s *Supervisor) Features() ac.FeatureSet {
+	if s == nil {
+		return ac.NilFeatureSet()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.features
+}
+
+// Platform returns the platform information of the current underlying session.
+func (s *Supervisor) Platform() platforminfo.PlatformInfo {
+	if s == nil {
+		return platforminfo.PlatformInfo{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.platform
+}
+
+// StartedAt returns the time at which the current underlying session was started.
+func (s *Supervisor) StartedAt() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.startedAt
+}
+
+// Adapters returns a list of known adapters.
+func (s *Supervisor) Adapters() []bluetooth.AdapterData {
+	session := s.current()
+	if session == nil {
+		return nil
+	}
+
+	return session.Adapters()
+}
+
+// Adapter returns a function call interface to invoke adapter related functions.
+func (s *Supervisor) Adapter(adapterAddress bluetooth.MacAddress) bluetooth.Adapter {
+	session := s.current()
+	if session == nil {
+		return stoppedAdapter{}
+	}
+
+	return session.Adapter(adapterAddress)
+}
+
+// Device returns a function call interface to invoke device related functions.
+func (s *Supervisor) Device(deviceAddress bluetooth.MacAddress) bluetooth.Device {
+	session := s.current()
+	if session == nil {
+		return stoppedDevice{}
+	}
+
+	return session.Device(deviceAddress)
+}
+
+// Obex returns a function call interface to invoke obex related functions.
+func (s *Supervisor) Obex(deviceAddress bluetooth.MacAddress) bluetooth.Obex {
+	session := s.current()
+	if session == nil {
+		return stoppedObex{}
+	}
+
+	return session.Obex(deviceAddress)
+}
+
+// Network returns a function call interface to invoke network related functions.
+func (s *Supervisor) Network(deviceAddress bluetooth.MacAddress) bluetooth.Network {
+	session := s.current()
+	if session == nil {
+		return stoppedNetwork{}
+	}
+
+	return session.Network(deviceAddress)
+}
+
+// MediaPlayer returns a function call interface to invoke media player/control
+// related functions on a device.
+func (s *Supervisor) MediaPlayer(deviceAddress bluetooth.MacAddress) bluetooth.MediaPlayer {
+	session := s.current()
+	if session == nil {
+		return stoppedMediaPlayer{}
+	}
+
+	return session.MediaPlayer(deviceAddress)
+}
+
+// current returns the current underlying session.
+// While the session is down, the last underlying session is returned, and its calls will fail.
+// After the supervisor is stopped, nil is returned.
+func (s *Supervisor) current() bluetooth.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.session
+}
+
+// start creates and starts a new underlying session.
+func (s *Supervisor) start() error {
+	// The event handlers are registered each time, since a failed session may have replaced them.
+	eventbus.RegisterEventHandlers(s.hub, nil)
+
+	session := s.newSession()
+
+	features, platform, err := session.Start(s.authorizer, s.sessionConfig)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.session = session
+	s.features, s.platform = features, platform
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	s.setStatus(Status{State: StateUp})
+
+	return nil
+}
+
+// supervise restarts the session if it is lost, and checks the available adapters periodically
+// and on each adapter event.
+func (s *Supervisor) supervise() {
+	defer close(s.done)
+
+	stopWatch := watchService(s.markLost)
+	defer stopWatch()
+
+	adapterEvents := s.hub.Subscribe("adapter")
+	defer adapterEvents.Unsubscribe()
+
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	s.checkAdapters()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case reason := <-s.lost:
+			s.restart(reason)
+			s.checkAdapters()
+
+		case <-adapterEvents.C:
+			s.checkAdapters()
+
+		case <-ticker.C:
+			s.checkAdapters()
+		}
+	}
+}
+
+// checkAdapters marks the running session as down while it has no adapters, and as up again
+// once an adapter is available. The session itself is kept running, so that added adapters are detected.
+// If the properties of none of the available adapters can be fetched, the session is marked as lost.
+func (s *Supervisor) checkAdapters() {
+	adapters := s.Adapters()
+	available := len(adapters) > 0
+	status := s.Status()
+
+	if available && status.State == StateUp {
+		if err := s.probe(adapters); err != nil {
+			s.markLost("bluetooth session not responding: " + err.Error())
+
+			return
+		}
+	}
+
+	switch {
+	case !available && status.State == StateUp:
+		slog.Warn("No Bluetooth adapters available, waiting for an adapter")
+
+		s.setStatus(Status{
+			State:   StateDown,
+			Reason:  noAdaptersReason,
+			retryAt: time.Now().Add(s.cfg.CheckInterval),
+		})
+
+	case available && status.State == StateDown && status.Reason == noAdaptersReason:
+		slog.Info("Bluetooth adapter available")
+
+		s.setStatus(Status{State: StateUp})
+	}
+}
+
+// probe fetches the properties of each adapter, and returns an error if none of them can be fetched.
+// A single adapter may fail if it is removed while it is probed, so the session is only considered
+// unresponsive if all adapters fail.
+func (s *Supervisor) probe(adapters []bluetooth.AdapterData) error {
+	var err error
+
+	for _, adapter := range adapters {
+		if _, err = s.Adapter(adapter.Address).Properties(); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// markLost marks the session as lost, so that it is restarted.
+func (s *Supervisor) markLost(reason string) {
+	select {
+	case s.lost <- reason:
+	default:
+	}
+}
+
+// restart stops the lost session, and starts a new session with an exponential backoff,
+// until it succeeds, or the supervisor is stopped.
+func (s *Supervisor) restart(reason string) {
+	slog.Warn("Bluetooth session lost, restarting", "reason", reason)
+
+	s.mu.Lock()
+	session := s.session
+	s.mu.Unlock()
+
+	if session != nil {
+		if err := session.Stop(); err != nil {
+			slog.Debug("Cannot stop the lost Bluetooth session", "error", err)
+		}
+	}
+
+	backoff := s.cfg.InitialBackoff
+
+	for attempt := 0; ; attempt++ {
+		s.setStatus(Status{
+			State:   StateDown,
+			Reason:  reason,
+			Attempt: attempt,
+			retryAt: time.Now().Add(backoff),
+		})
+
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case <-time.After(backoff):
+		}
+
+		s.setStatus(Status{State: StateStarting, Reason: reason, Attempt: attempt})
+
+		err := s.start()
+		if err == nil {
+			slog.Info("Bluetooth session restarted", "attempts", attempt+1)
+
+			// Discard any loss that was detected while restarting.
+			select {
+			case <-s.lost:
+			default:
+			}
+
+			return
+		}
+
+		slog.Warn("Cannot restart Bluetooth session", "attempt", attempt+1, "error", err)
+
+		reason = err.Error()
+		backoff = min(backoff*2, s.cfg.MaxBackoff)
+	}
+}
+
+// setStatus sets the status of the session and publishes it as a "session" event,
+// if the status has changed.
+func (s *Supervisor) setStatus(status Status) {
+	status.Since = time.Now()
+
+	s.mu.Lock()
+	if s.status.State == StateStopped {
+		s.mu.Unlock()
+
+		return
+	}
+	s.status = status
+	s.mu.Unlock()
+
+	if status.State == StateDown {
+		status.RetryAfter = retryAfterSeconds(time.Until(status.retryAt))
+	}
+
+	s.hub.Publish(EventID, EventName, status)
+}
+
+// retryAfterSeconds rounds up the wait time to seconds, with a minimum of one second.
+func retryAfterSeconds(wait time.Duration) int {
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
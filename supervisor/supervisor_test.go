@@ -0,0 +1,260 @@
+package supervisor
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	ac "github.com/bluetuith-org/bluetooth-classic/api/appfeatures"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/config"
+	"github.com/bluetuith-org/bluetooth-classic/api/errorkinds"
+	"github.com/bluetuith-org/bluetooth-classic/api/platforminfo"
+)
+
+// testTimeout is the maximum time to wait for a state change.
+const testTimeout = 5 * time.Second
+
+// testBackend holds the state shared by the sessions created by a test.
+type testBackend struct {
+	adapters    []bluetooth.AdapterData
+	unreachable bool
+	startErrors int
+	started     int
+	stopped     int
+	mu          sync.Mutex
+}
+
+// fakeSession is a session of a testBackend. Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	backend *testBackend
+}
+
+// fakeAdapter is an adapter of a fakeSession, whose properties cannot be fetched while the backend is unreachable.
+type fakeAdapter struct {
+	bluetooth.Adapter
+
+	backend *testBackend
+}
+
+// newSession returns a new session of the backend.
+func (b *testBackend) newSession() bluetooth.Session {
+	return &fakeSession{backend: b}
+}
+
+// update modifies the backend's state.
+func (b *testBackend) update(fn func(b *testBackend)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fn(b)
+}
+
+// counts returns the number of started and stopped sessions.
+func (b *testBackend) counts() (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.started, b.stopped
+}
+
+func (s *fakeSession) Start(bluetooth.SessionAuthorizer, config.Configuration) (ac.FeatureSet, platforminfo.PlatformInfo, error) {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	if s.backend.startErrors > 0 {
+		s.backend.startErrors--
+
+		return ac.NilFeatureSet(), platforminfo.PlatformInfo{}, errors.New("service unavailable")
+	}
+
+	s.backend.started++
+	s.backend.unreachable = false
+
+	return ac.NilFeatureSet(), platforminfo.PlatformInfo{}, nil
+}
+
+func (s *fakeSession) Stop() error {
+	s.backend.update(func(b *testBackend) { b.stopped++ })
+
+	return nil
+}
+
+func (s *fakeSession) Adapters() []bluetooth.AdapterData {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	return append([]bluetooth.AdapterData(nil), s.backend.adapters...)
+}
+
+func (s *fakeSession) Adapter(bluetooth.MacAddress) bluetooth.Adapter {
+	return fakeAdapter{backend: s.backend}
+}
+
+func (a fakeAdapter) Properties() (bluetooth.AdapterData, error) {
+	a.backend.mu.Lock()
+	defer a.backend.mu.Unlock()
+
+	if a.backend.unreachable {
+		return bluetooth.AdapterData{}, errors.New("no reply")
+	}
+
+	return a.backend.adapters[0], nil
+}
+
+// newTestSupervisor starts a supervisor of the backend, which is stopped when the test finishes.
+func newTestSupervisor(t *testing.T, backend *testBackend) (*Supervisor, *events.Hub) {
+	t.Helper()
+
+	hub := events.NewHub()
+	s := New(backend.newSession, hub, Config{
+		CheckInterval:  10 * time.Millisecond,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+
+	if _, _, err := s.Start(nil, config.Configuration{}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { s.Stop() })
+
+	return s, hub
+}
+
+// waitState waits until the supervisor reports the state.
+func waitState(t *testing.T, s *Supervisor, state State) Status {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+
+	for {
+		status := s.Status()
+		if status.State == state {
+			return status
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", status.State, state)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// testAdapter returns the data of an adapter.
+func testAdapter() bluetooth.AdapterData {
+	address, _ := bluetooth.ParseMAC("00:1A:7D:DA:71:13")
+
+	return bluetooth.AdapterData{AdapterEventData: bluetooth.AdapterEventData{Address: address}}
+}
+
+func TestStartFailure(t *testing.T) {
+	backend := &testBackend{startErrors: 1}
+	s := New(backend.newSession, events.NewHub(), Config{})
+
+	if _, _, err := s.Start(nil, config.Configuration{}); err == nil {
+		t.Fatal("Start() succeeded, want an error")
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := s.Status().State; state != StateStopped {
+		t.Fatalf("state = %s, want %s", state, StateStopped)
+	}
+}
+
+func TestNoAdapters(t *testing.T) {
+	backend := &testBackend{}
+	s, _ := newTestSupervisor(t, backend)
+
+	if status := waitState(t, s, StateDown); status.Reason != noAdaptersReason || status.RetryAfter < 1 {
+		t.Fatalf("status = %+v", status)
+	}
+
+	backend.update(func(b *testBackend) { b.adapters = []bluetooth.AdapterData{testAdapter()} })
+	waitState(t, s, StateUp)
+
+	if started, stopped := backend.counts(); started != 1 || stopped != 0 {
+		t.Fatalf("%d sessions started and %d stopped, want the session to be kept running", started, stopped)
+	}
+}
+
+func TestUnresponsiveSession(t *testing.T) {
+	backend := &testBackend{adapters: []bluetooth.AdapterData{testAdapter()}}
+	s, hub := newTestSupervisor(t, backend)
+
+	sessionEvents := hub.Subscribe(EventName)
+	defer sessionEvents.Unsubscribe()
+
+	// The next session fails to start twice before it starts.
+	backend.update(func(b *testBackend) { b.unreachable, b.startErrors = true, 2 })
+
+	deadline := time.Now().Add(testTimeout)
+	for started, _ := backend.counts(); started != 2; started, _ = backend.counts() {
+		if time.Now().After(deadline) {
+			t.Fatal("the unresponsive session was not restarted")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	waitState(t, s, StateUp)
+
+	if _, stopped := backend.counts(); stopped != 1 {
+		t.Fatalf("%d sessions stopped, want the unresponsive session to be stopped", stopped)
+	}
+
+	var attempts []int
+
+	for len(attempts) == 0 || attempts[len(attempts)-1] != -1 {
+		select {
+		case ev := <-sessionEvents.C:
+			status := ev.Data.(Status)
+
+			switch status.State {
+			case StateDown:
+				attempts = append(attempts, status.Attempt)
+
+			case StateUp:
+				attempts = append(attempts, -1)
+			}
+
+		case <-time.After(testTimeout):
+			t.Fatalf("attempts = %v, want the session to be up", attempts)
+		}
+	}
+
+	// The up state is recorded as -1.
+	if want := []int{0, 1, 2, -1}; !slices.Equal(attempts, want) {
+		t.Fatalf("attempts = %v, want %v", attempts, want)
+	}
+}
+
+func TestStop(t *testing.T) {
+	backend := &testBackend{adapters: []bluetooth.AdapterData{testAdapter()}}
+	s, _ := newTestSupervisor(t, backend)
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Healthy() {
+		t.Fatal("Healthy() = true after Stop()")
+	}
+
+	if _, err := s.Adapter(testAdapter().Address).Properties(); !errors.Is(err, errorkinds.ErrSessionNotExist) {
+		t.Fatalf("Properties() = %v, want ErrSessionNotExist", err)
+	}
+
+	if s.Adapters() != nil {
+		t.Fatal("Adapters() returned adapters after Stop()")
+	}
+}
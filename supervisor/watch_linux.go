@@ -0,0 +1,59 @@
+package supervisor
+
+import (
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// bluezService is the DBus name of the Bluetooth service.
+const bluezService = "org.bluez"
+
+// watchService watches the Bluetooth service on the system bus, and calls lost when
+// the service stops or restarts. The returned function stops watching the service.
+func watchService(lost func(reason string)) func() {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		slog.Debug("Cannot watch the Bluetooth service", "error", err)
+
+		return func() {}
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, bluezService),
+	); err != nil {
+		slog.Debug("Cannot watch the Bluetooth service", "error", err)
+		conn.Close()
+
+		return func() {}
+	}
+
+	ch := make(chan *dbus.Signal, 8)
+	conn.Signal(ch)
+
+	go func() {
+		for signal := range ch {
+			if signal.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(signal.Body) < 3 {
+				continue
+			}
+
+			if name, _ := signal.Body[0].(string); name != bluezService {
+				continue
+			}
+
+			if owner, _ := signal.Body[2].(string); owner == "" {
+				lost("bluetooth service stopped")
+			} else {
+				lost("bluetooth service restarted")
+			}
+		}
+	}()
+
+	return func() {
+		conn.RemoveSignal(ch)
+		conn.Close()
+		close(ch)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package supervisor
+
+// watchService does nothing, since the Bluetooth service can only be watched on Linux.
+// The loss of the session is detected by the periodic checks instead, which probe the adapters of the session.
+func watchService(func(reason string)) func() {
+	return func() {}
+}
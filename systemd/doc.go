@@ -0,0 +1,5 @@
+/*
+Package systemd provides the systemd service integration of the daemon, which includes readiness and watchdog
+notifications, socket activation, and the generation of service and socket unit files.
+*/
+package systemd
@@ -0,0 +1,97 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The notification states sent to the service manager.
+const (
+	stateReady    = "READY=1"
+	stateStopping = "STOPPING=1"
+	stateWatchdog = "WATCHDOG=1"
+	stateStatus   = "STATUS="
+)
+
+// Notify sends a notification state to the service manager.
+// If the daemon is not run by a service manager which supports notifications, it does nothing and returns false.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// Abstract sockets are prefixed with '@'.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Ready notifies the service manager that the daemon has started.
+func Ready() (bool, error) {
+	return Notify(stateReady)
+}
+
+// Stopping notifies the service manager that the daemon is stopping.
+func Stopping() (bool, error) {
+	return Notify(stateStopping)
+}
+
+// Status sends a status message to the service manager, which is shown by "systemctl status".
+func Status(status string) (bool, error) {
+	return Notify(stateStatus + status)
+}
+
+// WatchdogInterval returns the watchdog timeout of the service, if the watchdog is enabled for the daemon.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// Watchdog sends watchdog notifications to the service manager at half the watchdog timeout,
+// as long as healthy returns true, until the context is cancelled.
+// If the watchdog is not enabled for the daemon, it returns immediately.
+func Watchdog(ctx context.Context, healthy func() bool) {
+	timeout, ok := WatchdogInterval()
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if healthy() {
+				Notify(stateWatchdog)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package systemd
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultWatchdogSec is the default watchdog timeout of the generated service unit.
+const DefaultWatchdogSec = 30 * time.Second
+
+// Unit describes the generated service and socket units of the daemon.
+type Unit struct {
+	// Name holds the name of the units, without the ".service" or ".socket" suffix.
+	Name string
+
+	// Description holds the description of the units.
+	Description string
+
+	// ExecStart holds the command line which starts the daemon.
+	ExecStart []string
+
+	// ListenStream holds the TCP address or UNIX socket path of the socket unit.
+	ListenStream string
+
+	// ReadWritePaths holds the paths which the daemon can write to, apart from the state directory.
+	ReadWritePaths []string
+
+	// WatchdogSec holds the watchdog timeout of the service.
+	WatchdogSec time.Duration
+
+	// StateDirectory specifies whether the state directory of the service is used as the data directory.
+	StateDirectory bool
+
+	// User specifies whether the units are run by the per-user service manager.
+	User bool
+}
+
+// serviceTemplate is the template of the service unit.
+// Most of the sandboxing options are only applied to system services,
+// since they are not available to the per-user service manager.
+var serviceTemplate = template.Must(template.New("service").Parse(`[Unit]
+Description={{.Description}}
+Documentation=https://github.com/bluetuith-org/bluerestd
+Requires={{.Name}}.socket
+After={{.Name}}.socket{{if not .User}} bluetooth.target{{end}}
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{.Exec}}
+Restart=on-failure
+RestartSec=5s
+WatchdogSec={{.Watchdog}}
+{{- if .StateDirectory}}
+StateDirectory={{.Name}}
+StateDirectoryMode=0700
+{{- end}}
+NoNewPrivileges=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+RestrictRealtime=yes
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6 AF_NETLINK
+SystemCallArchitectures=native
+{{- if not .User}}
+CapabilityBoundingSet=
+ProtectSystem=strict
+ProtectHome=read-only
+PrivateTmp=yes
+PrivateDevices=yes
+ProtectClock=yes
+ProtectHostname=yes
+ProtectKernelLogs=yes
+ProtectKernelModules=yes
+ProtectKernelTunables=yes
+ProtectControlGroups=yes
+RestrictNamespaces=yes
+RestrictSUIDSGID=yes
+{{- range .ReadWritePaths}}
+ReadWritePaths={{.}}
+{{- end}}
+{{- end}}
+
+[Install]
+WantedBy={{if .User}}default.target{{else}}multi-user.target{{end}}
+`))
+
+// socketTemplate is the template of the socket unit.
+var socketTemplate = template.Must(template.New("socket").Parse(`[Unit]
+Description={{.Description}} (socket)
+Documentation=https://github.com/bluetuith-org/bluerestd
+
+[Socket]
+ListenStream={{.ListenStream}}
+{{- if .UnixSocket}}
+SocketMode=0600
+{{- end}}
+
+[Install]
+WantedBy=sockets.target
+`))
+
+// Service returns the contents of the service unit.
+func (u Unit) Service() (string, error) {
+	var sb strings.Builder
+
+	args := make([]string, 0, len(u.ExecStart)+1)
+	for _, arg := range u.ExecStart {
+		args = append(args, quote(arg))
+	}
+
+	if u.StateDirectory {
+		args = append(args, "--data-dir=${STATE_DIRECTORY}")
+	}
+
+	err := serviceTemplate.Execute(&sb, struct {
+		Unit
+		Exec     string
+		Watchdog string
+	}{u, strings.Join(args, " "), u.WatchdogSec.String()})
+
+	return sb.String(), err
+}
+
+// Socket returns the contents of the socket unit.
+func (u Unit) Socket() (string, error) {
+	var sb strings.Builder
+
+	err := socketTemplate.Execute(&sb, struct {
+		Unit
+		UnixSocket bool
+	}{u, filepath.IsAbs(u.ListenStream)})
+
+	return sb.String(), err
+}
+
+// quote quotes and escapes an argument of a command line in a unit file.
+func quote(arg string) string {
+	escaped := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"%", "%%",
+		"$", "$$",
+	).Replace(arg)
+
+	if escaped == arg && arg != "" && !strings.ContainsAny(arg, " \t\n'") {
+		return arg
+	}
+
+	return `"` + escaped + `"`
+}
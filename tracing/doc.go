@@ -0,0 +1,4 @@
+/*
+Package tracing provides OpenTelemetry tracing for the API operations and session calls. Traces can be exported to an OTLP collector over HTTP, or to a file.
+*/
+package tracing
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/bluetuith-org/bluerestd/instrument"
+	"github.com/danielgtaylor/huma/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer used by the daemon.
+const instrumentationName = "github.com/bluetuith-org/bluerestd"
+
+// defaultURLPath is the default OTLP/HTTP path to export traces to.
+const defaultURLPath = "/v1/traces"
+
+// Config describes the trace exporters.
+type Config struct {
+	// Endpoint holds the URL of an OTLP/HTTP collector, for example "http://127.0.0.1:4318".
+	// If the URL has no path, the "/v1/traces" path is used.
+	Endpoint string
+
+	// File holds the path to a file to write the traces to, as JSON.
+	File string
+
+	// ServiceVersion holds the version of the daemon.
+	ServiceVersion string
+}
+
+// Setup registers the global tracer provider with the configured exporters.
+// The returned function must be called to flush and stop the exporters.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	var (
+		opts    []sdktrace.TracerProviderOption
+		closers []func() error
+	)
+
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid OTLP endpoint: %s", cfg.Endpoint)
+		}
+
+		if u.Path == "" || u.Path == "/" {
+			u.Path = defaultURLPath
+		}
+
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create OTLP exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	if cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open trace file: %w", err)
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+
+			return nil, fmt.Errorf("cannot create file exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+		closers = append(closers, file.Close)
+	}
+
+	provider := sdktrace.NewTracerProvider(append(opts,
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("bluerestd"),
+			semconv.ServiceVersion(cfg.ServiceVersion),
+		)),
+	)...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		for _, c := range closers {
+			err = errors.Join(err, c())
+		}
+
+		return err
+	}, nil
+}
+
+// Tracer returns the daemon's tracer from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware starts a server span for each API operation. The trace context
+// of the incoming request's headers is used as the parent of the span, if present.
+func Middleware(ctx huma.Context, next func(huma.Context)) {
+	op := ctx.Operation()
+
+	parent := otel.GetTextMapPropagator().Extract(ctx.Context(), headerCarrier{ctx})
+	spanCtx, span := Tracer().Start(parent, op.OperationID,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(op.Method),
+			semconv.HTTPRoute(op.Path),
+		),
+	)
+	defer span.End()
+
+	next(huma.WithContext(ctx, spanCtx))
+
+	status := ctx.Status()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+	if status >= 500 {
+		span.SetStatus(codes.Error, strconv.Itoa(status))
+	}
+}
+
+// Hook returns a session hook, which starts a child span for each session call.
+func Hook() instrument.Hook {
+	return func(ctx context.Context, call string) func(error) {
+		_, span := Tracer().Start(ctx, call,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("bluetooth.call", call)),
+		)
+
+		return func(err error) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			span.End()
+		}
+	}
+}
+
+// headerCarrier adapts the request headers of a huma.Context to a propagation.TextMapCarrier.
+type headerCarrier struct {
+	ctx huma.Context
+}
+
+// Get returns the value of the header.
+func (h headerCarrier) Get(key string) string {
+	return h.ctx.Header(key)
+}
+
+// Set is not supported for request headers.
+func (h headerCarrier) Set(string, string) {}
+
+// Keys returns the headers used by the configured propagators.
+func (h headerCarrier) Keys() []string {
+	return otel.GetTextMapPropagator().Fields()
+}
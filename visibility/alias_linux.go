@@ -0,0 +1,24 @@
+package visibility
+
+import (
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/godbus/dbus/v5"
+)
+
+// The DBus names of the Bluetooth service and the alias property of its adapters.
+const (
+	bluezService  = "org.bluez"
+	aliasProperty = "org.bluez.Adapter1.Alias"
+)
+
+// setAlias sets the alias of an adapter through its object on the system bus.
+func setAlias(adapter bluetooth.AdapterData, alias string) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+
+	path := dbus.ObjectPath("/org/bluez/" + adapter.UniqueName)
+
+	return conn.Object(bluezService, path).SetProperty(aliasProperty, dbus.MakeVariant(alias))
+}
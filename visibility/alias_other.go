@@ -0,0 +1,10 @@
+//go:build !linux
+
+package visibility
+
+import "github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+
+// setAlias returns ErrAliasUnsupported, since the alias of an adapter can only be set on Linux.
+func setAlias(bluetooth.AdapterData, string) error {
+	return ErrAliasUnsupported
+}
@@ -0,0 +1,7 @@
+/*
+Package visibility provides the discoverable and pairable timeouts of the adapters, which are enforced
+by the daemon itself: when an adapter becomes discoverable or pairable, the state is reverted after the
+configured timeout, regardless of whether the Bluetooth backend has a native timeout. On Linux, it also
+sets the alias of the adapters through BlueZ.
+*/
+package visibility
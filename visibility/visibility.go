@@ -0,0 +1,361 @@
+package visibility
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+)
+
+// timeoutsBucket is the store bucket of the visibility timeouts.
+const timeoutsBucket = "adapter_visibility"
+
+// ErrAliasUnsupported is returned when the alias of an adapter cannot be set on this system.
+var ErrAliasUnsupported = errors.New("setting the alias of an adapter is not supported on this system")
+
+// Timeouts describes the visibility timeouts of an adapter.
+type Timeouts struct {
+	UpdatedAt           time.Time            `doc:"The time at which the timeouts were last updated." json:"updated_at"`
+	Address             bluetooth.MacAddress `doc:"The address of the adapter." json:"address"`
+	DiscoverableTimeout uint32               `doc:"The number of seconds after which a discoverable adapter stops being discoverable. If it is zero, the adapter stays discoverable." json:"discoverable_timeout"`
+	PairableTimeout     uint32               `doc:"The number of seconds after which a pairable adapter stops being pairable. If it is zero, the adapter stays pairable." json:"pairable_timeout"`
+}
+
+// Visibility describes the visibility timeouts of an adapter, and the times at which its states are reverted.
+type Visibility struct {
+	Timeouts
+
+	DiscoverableUntil *time.Time `doc:"The time at which the adapter stops being discoverable, if it is discoverable with a timeout." json:"discoverable_until,omitempty"`
+	PairableUntil     *time.Time `doc:"The time at which the adapter stops being pairable, if it is pairable with a timeout." json:"pairable_until,omitempty"`
+}
+
+// Manager manages the visibility timeouts of all adapters.
+type Manager struct {
+	session bluetooth.Session
+	hub     *events.Hub
+	store   *store.Store
+
+	subscriber *events.Subscriber
+	done       chan struct{}
+
+	timeouts map[bluetooth.MacAddress]Timeouts
+	reverts  map[revertKey]*revert
+	mu       sync.Mutex
+}
+
+// state describes a visibility state of an adapter.
+type state string
+
+// The visibility states of an adapter.
+const (
+	discoverable state = "discoverable"
+	pairable     state = "pairable"
+)
+
+// revertKey identifies a pending revert of a visibility state of an adapter.
+type revertKey struct {
+	adapter bluetooth.MacAddress
+	state   state
+}
+
+// revert holds a pending revert of a visibility state.
+type revert struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+// New loads the visibility timeouts from the store, and returns a new visibility manager.
+// Use (*Manager).Start() to start enforcing the timeouts.
+func New(st *store.Store, hub *events.Hub) (*Manager, error) {
+	timeouts, err := store.List[Timeouts](st, timeoutsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load visibility timeouts: %w", err)
+	}
+
+	m := &Manager{
+		hub:      hub,
+		store:    st,
+		done:     make(chan struct{}),
+		timeouts: make(map[bluetooth.MacAddress]Timeouts, len(timeouts)),
+		reverts:  make(map[revertKey]*revert),
+	}
+
+	for _, t := range timeouts {
+		m.timeouts[t.Address] = t
+	}
+
+	return m, nil
+}
+
+// Start starts enforcing the visibility timeouts on the adapters of the session,
+// whenever an adapter becomes discoverable or pairable.
+func (m *Manager) Start(session bluetooth.Session) {
+	m.session = session
+	m.subscriber = m.hub.Subscribe("adapter")
+
+	go m.watch()
+}
+
+// Stop stops enforcing the visibility timeouts, and reverts the states which have a pending revert,
+// so that the adapters do not stay discoverable or pairable after the daemon exits.
+func (m *Manager) Stop() {
+	if m == nil || m.subscriber == nil {
+		return
+	}
+
+	m.subscriber.Unsubscribe()
+	<-m.done
+
+	m.mu.Lock()
+	pending := make([]revertKey, 0, len(m.reverts))
+	for key, r := range m.reverts {
+		r.timer.Stop()
+		pending = append(pending, key)
+	}
+	clear(m.reverts)
+	m.mu.Unlock()
+
+	for _, key := range pending {
+		m.disable(key)
+	}
+}
+
+// Visibility returns the visibility timeouts of an adapter, and the times at which its states are reverted.
+func (m *Manager) Visibility(address bluetooth.MacAddress) Visibility {
+	if m == nil {
+		return Visibility{Timeouts: Timeouts{Address: address}}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.visibility(address)
+}
+
+// SetTimeouts sets the provided visibility timeouts (in seconds) of an adapter, and stores them.
+// If the adapter is already discoverable or pairable, the state is reverted after the new timeout,
+// counted from now, and the state of the adapter is published.
+func (m *Manager) SetTimeouts(address bluetooth.MacAddress, discoverableTimeout, pairableTimeout *uint32) (Visibility, error) {
+	properties, err := m.session.Adapter(address).Properties()
+	if err != nil {
+		return Visibility{}, err
+	}
+
+	v, err := m.setTimeouts(address, properties, discoverableTimeout, pairableTimeout)
+	if err != nil {
+		return v, err
+	}
+
+	if properties.Discoverable || properties.Pairable {
+		m.publish(address)
+	}
+
+	return v, nil
+}
+
+// SetAlias sets the alias (friendly name) of an adapter, and returns the updated properties of the adapter.
+func (m *Manager) SetAlias(address bluetooth.MacAddress, alias string) (bluetooth.AdapterData, error) {
+	adapter := m.session.Adapter(address)
+
+	properties, err := adapter.Properties()
+	if err != nil {
+		return properties, err
+	}
+
+	if err := setAlias(properties, alias); err != nil {
+		return properties, err
+	}
+
+	slog.Info("Adapter alias set", "adapter", address.String(), "alias", alias)
+
+	return adapter.Properties()
+}
+
+// setTimeouts sets and stores the provided visibility timeouts of an adapter, and reschedules the reverts
+// of its visibility states.
+func (m *Manager) setTimeouts(
+	address bluetooth.MacAddress, properties bluetooth.AdapterData, discoverableTimeout, pairableTimeout *uint32,
+) (Visibility, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.timeouts[address]
+	t.Address = address
+	t.UpdatedAt = time.Now()
+
+	if discoverableTimeout != nil {
+		t.DiscoverableTimeout = *discoverableTimeout
+	}
+
+	if pairableTimeout != nil {
+		t.PairableTimeout = *pairableTimeout
+	}
+
+	if t.DiscoverableTimeout == 0 && t.PairableTimeout == 0 {
+		if err := m.store.Delete(timeoutsBucket, address.String()); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return Visibility{}, err
+		}
+
+		delete(m.timeouts, address)
+	} else {
+		if err := m.store.Put(timeoutsBucket, address.String(), t); err != nil {
+			return Visibility{}, err
+		}
+
+		m.timeouts[address] = t
+	}
+
+	if discoverableTimeout != nil {
+		m.schedule(revertKey{address, discoverable}, properties.Discoverable, seconds(t.DiscoverableTimeout))
+	}
+
+	if pairableTimeout != nil {
+		m.schedule(revertKey{address, pairable}, properties.Pairable, seconds(t.PairableTimeout))
+	}
+
+	return m.visibility(address), nil
+}
+
+// DiscoverableFor makes an adapter discoverable, and reverts it after the provided duration,
+// regardless of its discoverable timeout.
+func (m *Manager) DiscoverableFor(address bluetooth.MacAddress, duration time.Duration) (Visibility, error) {
+	if err := m.session.Adapter(address).SetDiscoverableState(true); err != nil {
+		return Visibility{}, err
+	}
+
+	m.mu.Lock()
+	m.schedule(revertKey{address, discoverable}, true, duration)
+	v := m.visibility(address)
+	m.mu.Unlock()
+
+	slog.Info("Adapter discoverable", "adapter", address.String(), "duration", duration)
+	m.publish(address)
+
+	return v, nil
+}
+
+// watch follows the visibility states of the adapters, until the manager is stopped.
+func (m *Manager) watch() {
+	defer close(m.done)
+
+	for ev := range m.subscriber.C {
+		if data, ok := ev.Data.(bluetooth.Event[bluetooth.AdapterEventData]); ok {
+			m.follow(data)
+		}
+	}
+}
+
+// follow schedules the revert of each visibility state of an adapter which has become enabled,
+// and cancels the revert of each state which has become disabled.
+func (m *Manager) follow(ev bluetooth.Event[bluetooth.AdapterEventData]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address := ev.Data.Address
+	removed := ev.Action == bluetooth.EventActionRemoved
+	t := m.timeouts[address]
+
+	m.track(revertKey{address, discoverable}, ev.Data.Discoverable && !removed, seconds(t.DiscoverableTimeout))
+	m.track(revertKey{address, pairable}, ev.Data.Pairable && !removed, seconds(t.PairableTimeout))
+}
+
+// track schedules the revert of an enabled visibility state after its timeout, if it has no pending revert,
+// and cancels the pending revert of a disabled state. The manager must be locked by the caller.
+func (m *Manager) track(key revertKey, enabled bool, timeout time.Duration) {
+	if _, pending := m.reverts[key]; enabled && pending {
+		return
+	}
+
+	m.schedule(key, enabled, timeout)
+}
+
+// schedule cancels the pending revert of a visibility state, and if the state is enabled,
+// schedules a new revert after the provided duration. A zero duration does not schedule a revert.
+// The manager must be locked by the caller.
+func (m *Manager) schedule(key revertKey, enabled bool, duration time.Duration) {
+	if r, ok := m.reverts[key]; ok {
+		r.timer.Stop()
+		delete(m.reverts, key)
+	}
+
+	if !enabled || duration <= 0 {
+		return
+	}
+
+	r := &revert{at: time.Now().Add(duration)}
+	r.timer = time.AfterFunc(duration, func() {
+		m.mu.Lock()
+		if m.reverts[key] != r {
+			m.mu.Unlock()
+
+			return
+		}
+
+		delete(m.reverts, key)
+		m.mu.Unlock()
+
+		m.disable(key)
+	})
+
+	m.reverts[key] = r
+}
+
+// disable disables a visibility state of an adapter, and publishes the updated state of the adapter.
+func (m *Manager) disable(key revertKey) {
+	adapter := m.session.Adapter(key.adapter)
+
+	var err error
+
+	switch key.state {
+	case discoverable:
+		err = adapter.SetDiscoverableState(false)
+
+	case pairable:
+		err = adapter.SetPairableState(false)
+	}
+
+	if err != nil {
+		slog.Warn("Cannot revert adapter state", "adapter", key.adapter.String(), "state", string(key.state), "error", err)
+
+		return
+	}
+
+	slog.Info("Adapter state reverted", "adapter", key.adapter.String(), "state", string(key.state))
+	m.publish(key.adapter)
+}
+
+// publish publishes an "adapter" event with the current state of an adapter.
+func (m *Manager) publish(address bluetooth.MacAddress) {
+	properties, err := m.session.Adapter(address).Properties()
+	if err != nil {
+		return
+	}
+
+	bluetooth.AdapterEvent(bluetooth.EventActionUpdated).PublishData(properties.AdapterEventData)
+}
+
+// visibility returns the visibility of an adapter. The manager must be locked by the caller.
+func (m *Manager) visibility(address bluetooth.MacAddress) Visibility {
+	v := Visibility{Timeouts: m.timeouts[address]}
+	v.Address = address
+
+	if r, ok := m.reverts[revertKey{address, discoverable}]; ok {
+		v.DiscoverableUntil = &r.at
+	}
+
+	if r, ok := m.reverts[revertKey{address, pairable}]; ok {
+		v.PairableUntil = &r.at
+	}
+
+	return v
+}
+
+// seconds converts a timeout in seconds to a duration.
+func seconds(timeout uint32) time.Duration {
+	return time.Duration(timeout) * time.Second
+}
@@ -0,0 +1,225 @@
+package visibility
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/bluetuith-org/bluetooth-classic/api/bluetooth"
+	"github.com/bluetuith-org/bluetooth-classic/api/eventbus"
+)
+
+// testTimeout is the maximum time to wait for a state to be reverted.
+const testTimeout = 5 * time.Second
+
+// testAddress is the address of the adapter in the tests.
+var testAddress, _ = bluetooth.ParseMAC("00:1A:7D:DA:71:13")
+
+// fakeSession is a session with a single adapter, which records the state changes of the adapter.
+// Calls which are not implemented panic.
+type fakeSession struct {
+	bluetooth.Session
+
+	adapter bluetooth.AdapterData
+	calls   []string
+	mu      sync.Mutex
+}
+
+// fakeAdapter is the adapter of a fakeSession.
+type fakeAdapter struct {
+	bluetooth.Adapter
+
+	session *fakeSession
+}
+
+func (s *fakeSession) Adapter(bluetooth.MacAddress) bluetooth.Adapter {
+	return fakeAdapter{session: s}
+}
+
+// recorded returns the recorded state changes.
+func (s *fakeSession) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.calls...)
+}
+
+// set sets a state of the adapter, and records the change.
+func (s *fakeSession) set(state *bool, name string, enable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*state = enable
+
+	if enable {
+		s.calls = append(s.calls, name+" on")
+	} else {
+		s.calls = append(s.calls, name+" off")
+	}
+}
+
+func (a fakeAdapter) Properties() (bluetooth.AdapterData, error) {
+	a.session.mu.Lock()
+	defer a.session.mu.Unlock()
+
+	return a.session.adapter, nil
+}
+
+func (a fakeAdapter) SetDiscoverableState(enable bool) error {
+	a.session.set(&a.session.adapter.Discoverable, "discoverable", enable)
+
+	return nil
+}
+
+func (a fakeAdapter) SetPairableState(enable bool) error {
+	a.session.set(&a.session.adapter.Pairable, "pairable", enable)
+
+	return nil
+}
+
+// newTestManager starts a manager of a new session with a store at the path, which is stopped when the test finishes.
+func newTestManager(t *testing.T, path string, adapter bluetooth.AdapterEventData) (*Manager, *fakeSession, *events.Hub) {
+	t.Helper()
+
+	st, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := events.NewHub()
+	eventbus.RegisterEventHandlers(hub, nil)
+
+	m, err := New(st, hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adapter.Address = testAddress
+	session := &fakeSession{adapter: bluetooth.AdapterData{AdapterEventData: adapter}}
+	m.Start(session)
+
+	t.Cleanup(func() {
+		m.Stop()
+		st.Close()
+	})
+
+	return m, session, hub
+}
+
+// eventually waits until the condition is true.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDiscoverableFor(t *testing.T) {
+	m, session, hub := newTestManager(t, filepath.Join(t.TempDir(), "store.db"), bluetooth.AdapterEventData{})
+
+	adapterEvents := hub.Subscribe("adapter")
+	defer adapterEvents.Unsubscribe()
+
+	v, err := m.DiscoverableFor(testAddress, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.DiscoverableUntil == nil || v.PairableUntil != nil {
+		t.Fatalf("DiscoverableFor() = %+v, want a discoverable revert", v)
+	}
+
+	eventually(t, func() bool { return slices.Equal(session.recorded(), []string{"discoverable on", "discoverable off"}) })
+
+	if v := m.Visibility(testAddress); v.DiscoverableUntil != nil {
+		t.Fatalf("Visibility() = %+v, want no pending revert", v)
+	}
+
+	// An adapter event is published when the adapter becomes discoverable, and when it is reverted.
+	for _, want := range []bool{true, false} {
+		select {
+		case ev := <-adapterEvents.C:
+			if data := ev.Data.(bluetooth.Event[bluetooth.AdapterEventData]); data.Data.Discoverable != want {
+				t.Fatalf("adapter event = %+v, want discoverable = %v", data.Data, want)
+			}
+
+		case <-time.After(testTimeout):
+			t.Fatal("no adapter event was published")
+		}
+	}
+}
+
+func TestSetTimeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	m, session, _ := newTestManager(t, path, bluetooth.AdapterEventData{Pairable: true})
+
+	timeout := uint32(1)
+
+	v, err := m.SetTimeouts(testAddress, nil, &timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.PairableTimeout != 1 || v.DiscoverableTimeout != 0 || v.PairableUntil == nil || v.DiscoverableUntil != nil {
+		t.Fatalf("SetTimeouts() = %+v, want the pairable state to be reverted", v)
+	}
+
+	eventually(t, func() bool { return slices.Equal(session.recorded(), []string{"pairable off"}) })
+
+	// The timeout is enforced whenever the adapter becomes pairable.
+	fakeAdapter{session: session}.SetPairableState(true)
+	m.follow(bluetooth.Event[bluetooth.AdapterEventData]{Action: bluetooth.EventActionUpdated, Data: session.adapter.AdapterEventData})
+
+	if v := m.Visibility(testAddress); v.PairableUntil == nil {
+		t.Fatalf("Visibility() = %+v, want a pending revert", v)
+	}
+
+	// A disabled state has no pending revert.
+	fakeAdapter{session: session}.SetPairableState(false)
+	m.follow(bluetooth.Event[bluetooth.AdapterEventData]{Action: bluetooth.EventActionUpdated, Data: session.adapter.AdapterEventData})
+
+	if v := m.Visibility(testAddress); v.PairableUntil != nil {
+		t.Fatalf("Visibility() = %+v, want no pending revert", v)
+	}
+
+	m.Stop()
+	m.store.Close()
+
+	reloaded, _, _ := newTestManager(t, path, bluetooth.AdapterEventData{})
+	if v := reloaded.Visibility(testAddress); v.PairableTimeout != 1 {
+		t.Fatalf("Visibility() after reloading = %+v, want the stored timeout", v)
+	}
+
+	zero := uint32(0)
+	if _, err := reloaded.SetTimeouts(testAddress, &zero, &zero); err != nil {
+		t.Fatal(err)
+	}
+
+	if timeouts, _ := store.List[Timeouts](reloaded.store, timeoutsBucket); len(timeouts) != 0 {
+		t.Fatalf("stored timeouts = %+v, want none", timeouts)
+	}
+}
+
+func TestStopReverts(t *testing.T) {
+	m, session, _ := newTestManager(t, filepath.Join(t.TempDir(), "store.db"), bluetooth.AdapterEventData{})
+
+	if _, err := m.DiscoverableFor(testAddress, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Stop()
+
+	if calls := session.recorded(); !slices.Equal(calls, []string{"discoverable on", "discoverable off"}) {
+		t.Fatalf("calls = %v, want the pending revert to be applied", calls)
+	}
+}
@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/google/uuid"
+)
+
+// The headers sent with each delivery.
+const (
+	headerEvent     = "X-Bluerestd-Event"
+	headerDelivery  = "X-Bluerestd-Delivery"
+	headerSignature = "X-Bluerestd-Signature"
+)
+
+// payload describes the body of a delivery.
+type payload struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Data       any       `json:"data"`
+	DeliveryID string    `json:"delivery_id"`
+	Event      string    `json:"event"`
+}
+
+// deliver delivers an event to the subscription, and retries with an exponential backoff
+// if the delivery fails. If all attempts fail, the event is moved to the dead-letter queue.
+func (m *Manager) deliver(sub Subscription, ev events.Event) {
+	id, body, err := newPayload(ev)
+	if err != nil {
+		return
+	}
+
+	var attempts int
+
+	backoff := m.cfg.InitialBackoff
+
+Retry:
+	for attempts < m.cfg.MaxAttempts {
+		attempts++
+
+		if err = m.post(m.ctx, sub, ev.Name, id, body); err == nil {
+			return
+		}
+
+		if attempts == m.cfg.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-m.ctx.Done():
+			break Retry
+
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, m.cfg.MaxBackoff)
+	}
+
+	slog.Warn("Webhook delivery failed",
+		"webhook_id", sub.ID, "delivery_id", id, "event", ev.Name, "attempts", attempts, "error", err,
+	)
+
+	m.deadLetter(sub, ev.Name, id, body, attempts, err)
+}
+
+// drop moves an event which does not fit into the delivery queue of the subscription
+// to the dead-letter queue, without attempting to deliver it.
+func (m *Manager) drop(sub Subscription, ev events.Event) {
+	id, body, err := newPayload(ev)
+	if err != nil {
+		return
+	}
+
+	slog.Warn("Webhook delivery queue is full",
+		"webhook_id", sub.ID, "delivery_id", id, "event", ev.Name,
+	)
+
+	m.deadLetter(sub, ev.Name, id, body, 0, ErrQueueFull)
+}
+
+// deadLetter stores a delivery in the dead-letter queue.
+// The dead letter is stored even if the daemon is being stopped, so that it can be replayed later.
+func (m *Manager) deadLetter(sub Subscription, event, id string, body []byte, attempts int, err error) {
+	_ = m.store.Put(deadLettersBucket, id, DeadLetter{
+		FailedAt:       time.Now().UTC(),
+		ID:             id,
+		SubscriptionID: sub.ID,
+		URL:            sub.URL,
+		Event:          event,
+		LastError:      err.Error(),
+		Payload:        body,
+		Attempts:       attempts,
+	})
+}
+
+// newPayload returns a new delivery ID, and the delivery payload of the event.
+func newPayload(ev events.Event) (string, []byte, error) {
+	id := uuid.NewString()
+
+	body, err := json.Marshal(payload{
+		Timestamp:  time.Now().UTC(),
+		Data:       ev.Data,
+		DeliveryID: id,
+		Event:      ev.Name,
+	})
+
+	return id, body, err
+}
+
+// post sends a single signed delivery to the subscription's URL.
+func (m *Manager) post(ctx context.Context, sub Subscription, event, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerEvent, event)
+	req.Header.Set(headerDelivery, id)
+
+	if sub.Secret != "" {
+		req.Header.Set(headerSignature, "sha256="+Sign(sub.Secret, body))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status '%s'", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the body, using the provided secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
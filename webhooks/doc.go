@@ -0,0 +1,4 @@
+/*
+Package webhooks provides outgoing webhook subscriptions for session events. Each delivery is signed with HMAC-SHA256, retried with an exponential backoff, and moved to a persistent dead-letter queue if it keeps failing.
+*/
+package webhooks
@@ -0,0 +1,326 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+	"github.com/google/uuid"
+)
+
+// The store buckets of the subscriptions and dead letters.
+const (
+	subscriptionsBucket = "webhook_subscriptions"
+	deadLettersBucket   = "webhook_dead_letters"
+)
+
+// The default delivery settings.
+const (
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultTimeout        = 10 * time.Second
+	DefaultQueueSize      = 256
+)
+
+// The different webhook errors.
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeadLetterNotFound   = errors.New("dead letter not found")
+	ErrDeliveryFailed       = errors.New("webhook delivery failed")
+	ErrQueueFull            = errors.New("webhook delivery queue is full")
+)
+
+// Config describes the delivery settings of the webhooks.
+type Config struct {
+	// MaxAttempts holds the maximum number of attempts to deliver an event,
+	// before it is moved to the dead-letter queue.
+	MaxAttempts int
+
+	// InitialBackoff holds the wait time before the first retry. It is doubled after each retry,
+	// up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Timeout holds the timeout of each delivery request.
+	Timeout time.Duration
+
+	// QueueSize holds the maximum number of events that are waiting to be delivered to
+	// a subscription. Events are delivered to each subscription in order, and events which
+	// do not fit into the queue are moved to the dead-letter queue without being delivered.
+	QueueSize int
+}
+
+// Subscription describes a webhook subscription.
+type Subscription struct {
+	CreatedAt time.Time `doc:"The time at which the subscription was created." json:"created_at"`
+	ID        string    `doc:"The ID of the subscription." json:"webhook_id"`
+	URL       string    `doc:"The URL to deliver the events to." json:"url"`
+	Secret    string    `doc:"The secret used to sign the deliveries with HMAC-SHA256." json:"secret,omitempty"`
+	Events    []string  `doc:"The names of the delivered events. If empty, all events are delivered." json:"events,omitempty"`
+}
+
+// DeadLetter describes an event which could not be delivered.
+type DeadLetter struct {
+	FailedAt       time.Time       `doc:"The time of the last delivery attempt." json:"failed_at"`
+	ID             string          `doc:"The ID of the dead letter, which is also the delivery ID." json:"dead_letter_id"`
+	SubscriptionID string          `doc:"The ID of the webhook subscription." json:"webhook_id"`
+	URL            string          `doc:"The URL which the event was delivered to." json:"url"`
+	Event          string          `doc:"The name of the event." json:"event"`
+	LastError      string          `doc:"The error of the last delivery attempt." json:"last_error"`
+	Payload        json.RawMessage `doc:"The delivery payload, as sent to the webhook." json:"payload"`
+	Attempts       int             `doc:"The number of delivery attempts." json:"attempts"`
+}
+
+// Manager delivers the subscribed events to each webhook subscription.
+type Manager struct {
+	cfg    Config
+	store  *store.Store
+	hub    *events.Hub
+	client *http.Client
+
+	subscriptions map[string]*subscription
+	mu            sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// subscription holds a webhook subscription, its event hub subscriber and its delivery queue.
+type subscription struct {
+	Subscription
+
+	subscriber *events.Subscriber
+	queue      chan events.Event
+}
+
+// New returns a new webhook manager. Use (*Manager).Start() to start delivering events.
+func New(cfg Config, st *store.Store, hub *events.Hub) *Manager {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.InitialBackoff)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		cfg:           cfg,
+		store:         st,
+		hub:           hub,
+		client:        &http.Client{Timeout: cfg.Timeout},
+		subscriptions: make(map[string]*subscription),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Start loads the stored subscriptions and starts delivering events to them.
+func (m *Manager) Start() error {
+	subscriptions, err := store.List[Subscription](m.store, subscriptionsBucket)
+	if err != nil {
+		return fmt.Errorf("cannot load webhook subscriptions: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range subscriptions {
+		m.watch(s)
+	}
+
+	return nil
+}
+
+// Stop stops delivering events. Deliveries that are still being retried are moved
+// to the dead-letter queue.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	for id, s := range m.subscriptions {
+		s.subscriber.Unsubscribe()
+		delete(m.subscriptions, id)
+	}
+	m.mu.Unlock()
+
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Subscriptions returns all webhook subscriptions, without their secrets.
+func (m *Manager) Subscriptions() []Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0, len(m.subscriptions))
+	for _, s := range m.subscriptions {
+		sub := s.Subscription
+		sub.Secret = ""
+
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions
+}
+
+// Subscribe validates, stores and starts a new webhook subscription.
+func (m *Manager) Subscribe(sub Subscription) (Subscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("invalid webhook URL: %s", sub.URL)
+	}
+
+	sub.ID = uuid.NewString()
+	sub.CreatedAt = time.Now().UTC()
+
+	if err := m.store.Put(subscriptionsBucket, sub.ID, sub); err != nil {
+		return Subscription{}, fmt.Errorf("cannot store webhook subscription: %w", err)
+	}
+
+	m.mu.Lock()
+	m.watch(sub)
+	m.mu.Unlock()
+
+	sub.Secret = ""
+
+	return sub, nil
+}
+
+// Unsubscribe stops and removes a webhook subscription.
+func (m *Manager) Unsubscribe(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subscriptions[id]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+
+	s.subscriber.Unsubscribe()
+	delete(m.subscriptions, id)
+
+	return m.store.Delete(subscriptionsBucket, id)
+}
+
+// DeadLetters returns all dead letters. If a subscription ID is provided,
+// only the dead letters of the subscription are returned.
+func (m *Manager) DeadLetters(subscriptionID string) ([]DeadLetter, error) {
+	letters, err := store.List[DeadLetter](m.store, deadLettersBucket)
+	if err != nil || subscriptionID == "" {
+		return letters, err
+	}
+
+	filtered := make([]DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		if letter.SubscriptionID == subscriptionID {
+			filtered = append(filtered, letter)
+		}
+	}
+
+	return filtered, nil
+}
+
+// Replay attempts to deliver a dead letter once more. If the delivery succeeds,
+// the dead letter is removed from the queue.
+func (m *Manager) Replay(ctx context.Context, id string) (DeadLetter, error) {
+	var letter DeadLetter
+
+	if err := m.store.Get(deadLettersBucket, id, &letter); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return letter, ErrDeadLetterNotFound
+		}
+
+		return letter, err
+	}
+
+	m.mu.Lock()
+	s, ok := m.subscriptions[letter.SubscriptionID]
+	m.mu.Unlock()
+
+	if !ok {
+		return letter, ErrSubscriptionNotFound
+	}
+
+	letter.Attempts++
+	letter.FailedAt = time.Now().UTC()
+
+	if err := m.post(ctx, s.Subscription, letter.Event, letter.ID, letter.Payload); err != nil {
+		letter.LastError = err.Error()
+
+		return letter, errors.Join(
+			fmt.Errorf("%w: %w", ErrDeliveryFailed, err),
+			m.store.Put(deadLettersBucket, letter.ID, letter),
+		)
+	}
+
+	return letter, m.store.Delete(deadLettersBucket, id)
+}
+
+// DeleteDeadLetter removes a dead letter from the queue.
+func (m *Manager) DeleteDeadLetter(id string) error {
+	if err := m.store.Delete(deadLettersBucket, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrDeadLetterNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// watch subscribes to the events of a webhook subscription, and queues them to be delivered
+// by a single worker. If the queue is full, the event is moved to the dead-letter queue.
+// The manager's lock must be held while calling this function.
+func (m *Manager) watch(sub Subscription) {
+	s := &subscription{
+		Subscription: sub,
+		subscriber:   m.hub.Subscribe(sub.Events...),
+		queue:        make(chan events.Event, m.cfg.QueueSize),
+	}
+	m.subscriptions[sub.ID] = s
+
+	m.wg.Add(2)
+
+	go func() {
+		defer m.wg.Done()
+		defer close(s.queue)
+
+		for ev := range s.subscriber.C {
+			select {
+			case s.queue <- ev:
+			default:
+				m.drop(s.Subscription, ev)
+			}
+		}
+	}()
+
+	go func() {
+		defer m.wg.Done()
+
+		for ev := range s.queue {
+			m.deliver(s.Subscription, ev)
+		}
+	}()
+}
@@ -0,0 +1,293 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluetuith-org/bluerestd/events"
+	"github.com/bluetuith-org/bluerestd/store"
+)
+
+// testTimeout is the maximum time to wait for a delivery.
+const testTimeout = 5 * time.Second
+
+// delivery describes a request received by a testReceiver.
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook receiver, which fails the first requests.
+type testReceiver struct {
+	server *httptest.Server
+
+	failures   int
+	deliveries []delivery
+	mu         sync.Mutex
+}
+
+// newTestReceiver starts a new receiver, which fails the provided number of requests
+// with a 500 status before accepting them.
+func newTestReceiver(t *testing.T, failures int) *testReceiver {
+	t.Helper()
+
+	r := &testReceiver{failures: failures}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.deliveries = append(r.deliveries, delivery{req.Header.Clone(), body})
+
+		if r.failures != 0 {
+			r.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+// received returns the received requests.
+func (r *testReceiver) received() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]delivery(nil), r.deliveries...)
+}
+
+// setFailures sets the number of requests that fail.
+func (r *testReceiver) setFailures(failures int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = failures
+}
+
+// newTestManager starts a new webhook manager with a temporary store, which is stopped when the test finishes.
+func newTestManager(t *testing.T, cfg Config) (*Manager, *events.Hub) {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := events.NewHub()
+	m := New(cfg, st, hub)
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		m.Stop()
+		st.Close()
+	})
+
+	return m, hub
+}
+
+// eventually waits until the condition is true.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// The HMAC-SHA256 test case 2 of RFC 4231.
+	got := Sign("Jefe", []byte("what do ya want for nothing?"))
+	if want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m, _ := newTestManager(t, Config{})
+
+	for _, url := range []string{"", "ftp://example.com", "http://", "://"} {
+		if _, err := m.Subscribe(Subscription{URL: url}); err == nil {
+			t.Fatalf("Subscribe(%q) succeeded, want an error", url)
+		}
+	}
+
+	sub, err := m.Subscribe(Subscription{URL: "http://127.0.0.1:1/hook", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sub.ID == "" || sub.Secret != "" {
+		t.Fatalf("Subscribe() = %+v, want an ID and no secret", sub)
+	}
+
+	if subs := m.Subscriptions(); len(subs) != 1 || subs[0].ID != sub.ID || subs[0].Secret != "" {
+		t.Fatalf("Subscriptions() = %+v", subs)
+	}
+
+	if err := m.Unsubscribe(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Unsubscribe(sub.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Fatalf("Unsubscribe() = %v, want ErrSubscriptionNotFound", err)
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	receiver := newTestReceiver(t, 2)
+	m, hub := newTestManager(t, Config{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	if _, err := m.Subscribe(Subscription{URL: receiver.server.URL, Secret: "secret", Events: []string{"device"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Publish(0, "adapter", "ignored")
+	hub.Publish(0, "device", map[string]string{"name": "headphones"})
+
+	eventually(t, func() bool { return len(receiver.received()) == 3 })
+
+	deliveries := receiver.received()
+	for _, d := range deliveries {
+		if d.header.Get(headerDelivery) != deliveries[0].header.Get(headerDelivery) {
+			t.Fatal("the retries have different delivery IDs")
+		}
+	}
+
+	last := deliveries[len(deliveries)-1]
+	if got := last.header.Get(headerEvent); got != "device" {
+		t.Fatalf("event header = %q, want device", got)
+	}
+
+	if got, want := last.header.Get(headerSignature), "sha256="+Sign("secret", last.body); got != want {
+		t.Fatalf("signature header = %q, want %q", got, want)
+	}
+
+	var p struct {
+		Data       map[string]string `json:"data"`
+		DeliveryID string            `json:"delivery_id"`
+		Event      string            `json:"event"`
+	}
+	if err := json.Unmarshal(last.body, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Event != "device" || p.Data["name"] != "headphones" || p.DeliveryID != last.header.Get(headerDelivery) {
+		t.Fatalf("payload = %+v", p)
+	}
+
+	if letters, err := m.DeadLetters(""); err != nil || len(letters) != 0 {
+		t.Fatalf("DeadLetters() = %v, %v, want none", letters, err)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	receiver := newTestReceiver(t, -1)
+	m, hub := newTestManager(t, Config{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	sub, err := m.Subscribe(Subscription{URL: receiver.server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Publish(0, "device", "data")
+
+	var letters []DeadLetter
+
+	eventually(t, func() bool {
+		letters, _ = m.DeadLetters(sub.ID)
+		return len(letters) == 1
+	})
+
+	letter := letters[0]
+	if letter.Attempts != 2 || letter.Event != "device" || letter.SubscriptionID != sub.ID || !strings.Contains(letter.LastError, "500") {
+		t.Fatalf("dead letter = %+v", letter)
+	}
+
+	if other, _ := m.DeadLetters("other"); len(other) != 0 {
+		t.Fatalf("DeadLetters() of another subscription = %v, want none", other)
+	}
+
+	if _, err := m.Replay(context.Background(), letter.ID); !errors.Is(err, ErrDeliveryFailed) {
+		t.Fatalf("Replay() = %v, want ErrDeliveryFailed", err)
+	}
+
+	if letters, _ = m.DeadLetters(sub.ID); len(letters) != 1 || letters[0].Attempts != 3 {
+		t.Fatalf("dead letters after a failed replay = %+v", letters)
+	}
+
+	receiver.setFailures(0)
+
+	if _, err := m.Replay(context.Background(), letter.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := receiver.received()
+	if body := replayed[len(replayed)-1].body; string(body) != string(letter.Payload) {
+		t.Fatalf("replayed payload = %s, want %s", body, letter.Payload)
+	}
+
+	if letters, _ = m.DeadLetters(""); len(letters) != 0 {
+		t.Fatalf("dead letters after a replay = %+v, want none", letters)
+	}
+
+	if _, err := m.Replay(context.Background(), letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("Replay() of a removed dead letter = %v, want ErrDeadLetterNotFound", err)
+	}
+
+	if err := m.DeleteDeadLetter(letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("DeleteDeadLetter() = %v, want ErrDeadLetterNotFound", err)
+	}
+}
+
+func TestDeliveryQueueFull(t *testing.T) {
+	received, release := make(chan struct{}, 8), make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	m, hub := newTestManager(t, Config{QueueSize: 1})
+
+	if _, err := m.Subscribe(Subscription{URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Publish(0, "device", 1)
+	<-received
+
+	hub.Publish(0, "device", 2)
+	hub.Publish(0, "device", 3)
+
+	eventually(t, func() bool {
+		letters, _ := m.DeadLetters("")
+		return len(letters) == 1 && letters[0].LastError == ErrQueueFull.Error() && letters[0].Attempts == 0
+	})
+}